@@ -6,39 +6,53 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime/pprof"
+	"strings"
 
 	"github.com/rhomel/golox/pkg/args"
 	ast "github.com/rhomel/golox/pkg/ast/gen"
+	"github.com/rhomel/golox/pkg/golden"
+	"github.com/rhomel/golox/pkg/golox"
 	"github.com/rhomel/golox/pkg/interpreter"
-	"github.com/rhomel/golox/pkg/parser"
-	"github.com/rhomel/golox/pkg/resolver"
 	"github.com/rhomel/golox/pkg/scanner"
 	"github.com/rhomel/golox/pkg/util/ast/printer"
 	"github.com/rhomel/golox/pkg/util/exit"
+	"github.com/rhomel/golox/pkg/util/runner"
 	"github.com/rhomel/golox/pkg/vm"
 )
 
 func main() {
-	implementation := flag.String("implementation", "treewalk", "interpreter implementation to use")
+	implementation := flag.String("implementation", "treewalk", "interpreter implementation to use: treewalk, closure or vm")
 	disassembler := flag.Bool("disassembler", false, "enable vm stack disassembler")
 	cpuProfileFile := flag.String("cpu-profile", "", "file to output cpu profile")
+	maxCallDepth := flag.Int("max-call-depth", interpreter.DefaultMaxCallDepth, "calls in progress before a \"Stack overflow.\" error (treewalk and closure)")
+	modulePath := flag.String("module-path", "", "directories imports look in after the importing file's directory, separated by the OS path list separator")
 	flag.Parse()
 	args := args.New()
+	if args.Len() > 0 && args.Get()[0] == "test" {
+		testMain(*implementation, args.Get()[1:])
+		return
+	}
+	dirs := filepath.SplitList(*modulePath)
 	switch *implementation {
 	case "treewalk":
-		treewalkMain(args, *cpuProfileFile)
+		treewalkMain(golox.Options{Engine: golox.TreeWalk, MaxCallDepth: *maxCallDepth, ModulePath: dirs}, args, *cpuProfileFile)
+	case "closure":
+		treewalkMain(golox.Options{Engine: golox.Closure, MaxCallDepth: *maxCallDepth, ModulePath: dirs}, args, *cpuProfileFile)
 	case "vm":
-		vm.Main(*disassembler, args)
+		vm.Main(*disassembler, dirs, args)
 	default:
 		exit.Exitf(exit.ExitCodeUsageError, fmt.Sprintf("%s is not a valid implementation flag value", *implementation))
 	}
 }
 
-func treewalkMain(args *args.Args, cpuProfileFile string) {
-	lox := NewLox()
+func treewalkMain(opts golox.Options, args *args.Args, cpuProfileFile string) {
+	lox, err := golox.New(opts)
+	if err != nil {
+		exit.Exitf(exit.ExitCodeUsageError, "%v", err)
+	}
 	l := args.Len()
 	switch {
 	case l > 1:
@@ -48,9 +62,50 @@ func treewalkMain(args *args.Args, cpuProfileFile string) {
 			cleanup := profile(cpuProfileFile)
 			defer cleanup()
 		}
-		lox.runFile(args.Get()[0])
+		runFile(lox, args.Get()[0])
 	default:
-		lox.runPrompt()
+		runPrompt(lox)
+	}
+}
+
+// testMain runs the golden tests found in paths (see pkg/golden) by running
+// each file with this binary and the chosen implementation.
+func testMain(implementation string, paths []string) {
+	if len(paths) == 0 {
+		exit.Exitf(exit.ExitCodeUsageError, "usage: golox <flags> test <dir or file>...")
+	}
+	binary, err := os.Executable()
+	if err != nil {
+		exit.Exitf(exit.ExitIOError, "unable to locate golox executable: %v", err)
+	}
+	var passed, failed, skipped int
+	for _, path := range paths {
+		files, err := golden.Files(path)
+		if err != nil {
+			exit.Exitf(exit.ExitIOError, "error reading '%s': %v", path, err)
+		}
+		for _, file := range files {
+			report, err := golden.RunFile(binary, implementation, file, runner.DefaultTimeout)
+			if err != nil {
+				exit.Exitf(exit.ExitIOError, "error running '%s': %v", file, err)
+			}
+			switch {
+			case report.Skipped:
+				skipped++
+			case report.Passed():
+				passed++
+			default:
+				failed++
+				fmt.Printf("FAIL %s\n", file)
+				for _, failure := range report.Failures {
+					fmt.Printf("    %s\n", strings.ReplaceAll(failure, "\n", "\n    "))
+				}
+			}
+		}
+	}
+	fmt.Printf("%d passed, %d failed, %d skipped (no expectations)\n", passed, failed, skipped)
+	if failed > 0 {
+		exit.Exitf(exit.ExitTestFailure, "")
 	}
 }
 
@@ -63,34 +118,22 @@ func profile(file string) func() {
 	return pprof.StopCPUProfile
 }
 
-type Lox struct {
-	hadError        bool
-	hadRuntimeError bool
-
-	interpreter interpreter.Interpreter
-}
-
-func NewLox() *Lox {
-	lox := &Lox{}
-	lox.interpreter = interpreter.NewTreeWalkInterpreter(lox)
-	return lox
-}
-
-func (l *Lox) runFile(file string) {
-	b, err := ioutil.ReadFile(file)
-	if err != nil {
-		exit.Exitf(exit.ExitIOError, "error reading file '%s': %v", file, err)
-	}
-	l.run(string(b))
-	if l.hadError {
+func runFile(lox *golox.Lox, file string) {
+	err := lox.RunFile(file)
+	switch err.(type) {
+	case nil:
+	case *golox.CompileError:
+		reportError(err)
 		exit.Exitf(exit.ExitSyntaxError, "")
-	}
-	if l.hadRuntimeError {
+	case *golox.RuntimeError:
+		reportError(err)
 		exit.Exitf(exit.ExitRuntimeError, "")
+	default:
+		exit.Exitf(exit.ExitIOError, "error reading file '%s': %v", file, err)
 	}
 }
 
-func (l *Lox) runPrompt() {
+func runPrompt(lox *golox.Lox) {
 	fmt.Println("Welcome to golox REPL. Use ctrl+d to exit.")
 	reader := bufio.NewReader(os.Stdin)
 	for {
@@ -102,27 +145,23 @@ func (l *Lox) runPrompt() {
 		if err != nil {
 			exit.Exitf(exit.ExitIOError, "error reading from stdin: %v", err)
 		}
-		l.run(line)
-		l.hadError = false // don't exit the repl on syntax errors, just ignore the input
+		// don't exit the repl on errors, just report them
+		reportError(lox.Run(line))
 	}
 }
 
-func (l *Lox) run(line string) {
-	scanner := scanner.NewScanner(line, l)
-	tokens := scanner.ScanTokens()
-	//printTokens(tokens) // TODO: make a flag to enable printing scanned tokens
-	parser := parser.NewParser(tokens, l)
-	statements := parser.Parse()
-	//printAst(expr) // TODO: make a flag to enable printing the parsed ast
-	if l.hadError {
-		return
-	}
-	resolver := resolver.NewResolver(l.interpreter, l)
-	resolver.ResolveStmts(statements)
-	if l.hadError {
-		return
+// reportError prints err to stderr in the format used by the
+// craftinginterpreters test suite.
+func reportError(err error) {
+	switch err := err.(type) {
+	case nil:
+	case *golox.CompileError:
+		fmt.Fprintln(os.Stderr, err)
+	case *golox.RuntimeError:
+		fmt.Fprintf(os.Stderr, "%s\n%s", err.Message, err.StackTrace())
+	default:
+		fmt.Fprintln(os.Stderr, err)
 	}
-	l.interpreter.Interpret(statements)
 }
 
 func printAst(expr ast.Expr) {
@@ -135,29 +174,3 @@ func printTokens(tokens []*scanner.Token) {
 		fmt.Fprintf(os.Stderr, "line: %d, token: %s\n", token.Line, token.String())
 	}
 }
-
-func (l *Lox) Error(line int, message string) {
-	l.report(line, "", message)
-}
-
-func (l *Lox) report(line int, where, message string) {
-	fmt.Fprintf(os.Stderr, "[line %d] Error%s: %s\n", line, where, message)
-	l.hadError = true
-}
-
-func (l *Lox) ParseError(token scanner.Token, message string) {
-	if token.Typ == scanner.EOF {
-		l.report(token.Line, " at end", message)
-	} else {
-		l.report(token.Line, " at '"+token.Lexeme+"'", message)
-	}
-}
-
-func (l *Lox) ResolveError(token scanner.Token, message string) {
-	l.ParseError(token, message)
-}
-
-func (l *Lox) RuntimeError(token scanner.Token, message string) {
-	fmt.Fprintf(os.Stderr, "%s\n[line %d]\n", message, token.Line)
-	l.hadRuntimeError = true
-}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"go/token"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -30,29 +31,44 @@ func main() {
 	}
 	outputDirectory := args[0]
 	defineAST(outputDirectory, "Expr", map[string]string{
-		"Binary":   "Left Expr, Operator scanner.Token, Right Expr",
-		"Call":     "Callee Expr, Paren scanner.Token, Arguments []Expr",
-		"Get":      "Object Expr, Name scanner.Token",
-		"Grouping": "Expression Expr",
-		"Literal":  "Value interface{}",
-		"Logical":  "Left Expr, Operator scanner.Token, Right Expr",
-		"Set":      "Object Expr, Name scanner.Token, Value Expr",
-		"Super":    "Keyword scanner.Token, Method scanner.Token",
-		"This":     "Keyword scanner.Token",
-		"Unary":    "Operator scanner.Token, Right Expr",
-		"Variable": "Name scanner.Token",
-		"Assign":   "Name scanner.Token, Value Expr",
+		"Binary":      "Left Expr, Operator scanner.Token, Right Expr",
+		"Call":        "Callee Expr, Paren scanner.Token, Arguments []Expr",
+		"Comma":       "Left Expr, Right Expr",
+		"Compound":    "Target Expr, Operator scanner.Token, Value Expr, Postfix bool",
+		"Conditional": "Condition Expr, Then Expr, Else Expr",
+		"Get":         "Object Expr, Name scanner.Token",
+		"GetIndex":    "Object Expr, Bracket scanner.Token, Index Expr",
+		"Grouping":    "Expression Expr",
+		"Lambda":      "Function *Function",
+		"List":        "Bracket scanner.Token, Elements []Expr",
+		"Literal":     "Value interface{}",
+		"Logical":     "Left Expr, Operator scanner.Token, Right Expr",
+		"Map":         "Brace scanner.Token, Keys []Expr, Values []Expr",
+		"Set":         "Object Expr, Name scanner.Token, Value Expr",
+		"SetIndex":    "Object Expr, Bracket scanner.Token, Index Expr, Value Expr",
+		"Stringify":   "Expression Expr",
+		"Super":       "Keyword scanner.Token, Method scanner.Token",
+		"This":        "Keyword scanner.Token",
+		"Unary":       "Operator scanner.Token, Right Expr",
+		"Variable":    "Name scanner.Token",
+		"Assign":      "Name scanner.Token, Value Expr",
 	}, "import \"github.com/rhomel/golox/pkg/scanner\"")
 	defineAST(outputDirectory, "Stmt", map[string]string{
-		"Block":      "Statements []Stmt",
-		"Class":      "Name scanner.Token, Superclass *Variable, Methods []*Function",
-		"Expression": "Expression Expr",
-		"Function":   "Name scanner.Token, Params []scanner.Token, Body []Stmt",
-		"IfStmt":     "Condition Expr, ThenBranch Stmt, ElseBranch Stmt",
-		"Print":      "Expression Expr",
-		"ReturnStmt": "Keyword scanner.Token, Value Expr",
-		"VarStmt":    "Name scanner.Token, Initializer Expr",
-		"While":      "Condition Expr, Body Stmt",
+		"Block":        "Statements []Stmt",
+		"BreakStmt":    "Keyword scanner.Token",
+		"Catch":        "Name scanner.Token, Body []Stmt",
+		"Class":        "Name scanner.Token, Superclass *Variable, Methods []*Function",
+		"ContinueStmt": "Keyword scanner.Token",
+		"Expression":   "Expression Expr",
+		"Function":     "Name scanner.Token, Params []scanner.Token, Body []Stmt",
+		"IfStmt":       "Condition Expr, ThenBranch Stmt, ElseBranch Stmt",
+		"ImportStmt":   "Keyword scanner.Token, Path scanner.Token, Name scanner.Token",
+		"Print":        "Expression Expr",
+		"ReturnStmt":   "Keyword scanner.Token, Value Expr",
+		"Throw":        "Keyword scanner.Token, Value Expr",
+		"Try":          "Body Stmt, Catch *Catch, Finally Stmt",
+		"VarStmt":      "Name scanner.Token, Initializer Expr",
+		"While":        "Keyword scanner.Token, Condition Expr, Body Stmt, Increment Expr",
 	}, "import \"github.com/rhomel/golox/pkg/scanner\"")
 }
 
@@ -88,7 +104,7 @@ func writeFile(file *os.File, baseName string, types map[string]string, imports
 		generated := template
 		generated = strings.ReplaceAll(generated, "<baseName>", baseName)
 		generated = strings.ReplaceAll(generated, "<Name>", name)
-		generated = strings.ReplaceAll(generated, "<lowercaseName>", strings.ToLower(name))
+		generated = strings.ReplaceAll(generated, "<lowercaseName>", receiverName(name))
 		generated = strings.ReplaceAll(generated, "<Fields>", defineFields(fields))
 		if _, err := fmt.Fprintf(file, generated); err != nil {
 			return err
@@ -97,6 +113,16 @@ func writeFile(file *os.File, baseName string, types map[string]string, imports
 	return nil
 }
 
+// receiverName is the lowercase name of the type unless that is a Go keyword,
+// like map.
+func receiverName(name string) string {
+	receiver := strings.ToLower(name)
+	if token.IsKeyword(receiver) {
+		return receiver[:1]
+	}
+	return receiver
+}
+
 func defineFields(fieldString string) string {
 	var fields string
 	for i, field := range strings.Split(fieldString, ",") {
@@ -109,6 +109,108 @@ func (call *Call) Accept(visitor CallVisitor) interface{} {
 	return visitor.VisitCallExpr(call)
 }
 
+var _ Expr = (*Comma)(nil)
+
+type Comma struct {
+	Left  Expr
+	Right Expr
+}
+
+func (*Comma) isExpr() {}
+
+type CommaStringVisitor interface {
+	VisitCommaExprString(*Comma) string
+}
+
+func (comma *Comma) AcceptString(visitor CommaStringVisitor) string {
+	return visitor.VisitCommaExprString(comma)
+}
+
+type CommaVoidVisitor interface {
+	VisitCommaExprVoid(*Comma)
+}
+
+func (comma *Comma) AcceptVoid(visitor CommaVoidVisitor) {
+	visitor.VisitCommaExprVoid(comma)
+}
+
+type CommaVisitor interface {
+	VisitCommaExpr(*Comma) interface{}
+}
+
+func (comma *Comma) Accept(visitor CommaVisitor) interface{} {
+	return visitor.VisitCommaExpr(comma)
+}
+
+var _ Expr = (*Compound)(nil)
+
+type Compound struct {
+	Target   Expr
+	Operator scanner.Token
+	Value    Expr
+	Postfix  bool
+}
+
+func (*Compound) isExpr() {}
+
+type CompoundStringVisitor interface {
+	VisitCompoundExprString(*Compound) string
+}
+
+func (compound *Compound) AcceptString(visitor CompoundStringVisitor) string {
+	return visitor.VisitCompoundExprString(compound)
+}
+
+type CompoundVoidVisitor interface {
+	VisitCompoundExprVoid(*Compound)
+}
+
+func (compound *Compound) AcceptVoid(visitor CompoundVoidVisitor) {
+	visitor.VisitCompoundExprVoid(compound)
+}
+
+type CompoundVisitor interface {
+	VisitCompoundExpr(*Compound) interface{}
+}
+
+func (compound *Compound) Accept(visitor CompoundVisitor) interface{} {
+	return visitor.VisitCompoundExpr(compound)
+}
+
+var _ Expr = (*Conditional)(nil)
+
+type Conditional struct {
+	Condition Expr
+	Then      Expr
+	Else      Expr
+}
+
+func (*Conditional) isExpr() {}
+
+type ConditionalStringVisitor interface {
+	VisitConditionalExprString(*Conditional) string
+}
+
+func (conditional *Conditional) AcceptString(visitor ConditionalStringVisitor) string {
+	return visitor.VisitConditionalExprString(conditional)
+}
+
+type ConditionalVoidVisitor interface {
+	VisitConditionalExprVoid(*Conditional)
+}
+
+func (conditional *Conditional) AcceptVoid(visitor ConditionalVoidVisitor) {
+	visitor.VisitConditionalExprVoid(conditional)
+}
+
+type ConditionalVisitor interface {
+	VisitConditionalExpr(*Conditional) interface{}
+}
+
+func (conditional *Conditional) Accept(visitor ConditionalVisitor) interface{} {
+	return visitor.VisitConditionalExpr(conditional)
+}
+
 var _ Expr = (*Get)(nil)
 
 type Get struct {
@@ -142,6 +244,40 @@ func (get *Get) Accept(visitor GetVisitor) interface{} {
 	return visitor.VisitGetExpr(get)
 }
 
+var _ Expr = (*GetIndex)(nil)
+
+type GetIndex struct {
+	Object  Expr
+	Bracket scanner.Token
+	Index   Expr
+}
+
+func (*GetIndex) isExpr() {}
+
+type GetIndexStringVisitor interface {
+	VisitGetIndexExprString(*GetIndex) string
+}
+
+func (getindex *GetIndex) AcceptString(visitor GetIndexStringVisitor) string {
+	return visitor.VisitGetIndexExprString(getindex)
+}
+
+type GetIndexVoidVisitor interface {
+	VisitGetIndexExprVoid(*GetIndex)
+}
+
+func (getindex *GetIndex) AcceptVoid(visitor GetIndexVoidVisitor) {
+	visitor.VisitGetIndexExprVoid(getindex)
+}
+
+type GetIndexVisitor interface {
+	VisitGetIndexExpr(*GetIndex) interface{}
+}
+
+func (getindex *GetIndex) Accept(visitor GetIndexVisitor) interface{} {
+	return visitor.VisitGetIndexExpr(getindex)
+}
+
 var _ Expr = (*Grouping)(nil)
 
 type Grouping struct {
@@ -174,6 +310,71 @@ func (grouping *Grouping) Accept(visitor GroupingVisitor) interface{} {
 	return visitor.VisitGroupingExpr(grouping)
 }
 
+var _ Expr = (*Lambda)(nil)
+
+type Lambda struct {
+	Function *Function
+}
+
+func (*Lambda) isExpr() {}
+
+type LambdaStringVisitor interface {
+	VisitLambdaExprString(*Lambda) string
+}
+
+func (lambda *Lambda) AcceptString(visitor LambdaStringVisitor) string {
+	return visitor.VisitLambdaExprString(lambda)
+}
+
+type LambdaVoidVisitor interface {
+	VisitLambdaExprVoid(*Lambda)
+}
+
+func (lambda *Lambda) AcceptVoid(visitor LambdaVoidVisitor) {
+	visitor.VisitLambdaExprVoid(lambda)
+}
+
+type LambdaVisitor interface {
+	VisitLambdaExpr(*Lambda) interface{}
+}
+
+func (lambda *Lambda) Accept(visitor LambdaVisitor) interface{} {
+	return visitor.VisitLambdaExpr(lambda)
+}
+
+var _ Expr = (*List)(nil)
+
+type List struct {
+	Bracket  scanner.Token
+	Elements []Expr
+}
+
+func (*List) isExpr() {}
+
+type ListStringVisitor interface {
+	VisitListExprString(*List) string
+}
+
+func (list *List) AcceptString(visitor ListStringVisitor) string {
+	return visitor.VisitListExprString(list)
+}
+
+type ListVoidVisitor interface {
+	VisitListExprVoid(*List)
+}
+
+func (list *List) AcceptVoid(visitor ListVoidVisitor) {
+	visitor.VisitListExprVoid(list)
+}
+
+type ListVisitor interface {
+	VisitListExpr(*List) interface{}
+}
+
+func (list *List) Accept(visitor ListVisitor) interface{} {
+	return visitor.VisitListExpr(list)
+}
+
 var _ Expr = (*Literal)(nil)
 
 type Literal struct {
@@ -240,6 +441,40 @@ func (logical *Logical) Accept(visitor LogicalVisitor) interface{} {
 	return visitor.VisitLogicalExpr(logical)
 }
 
+var _ Expr = (*Map)(nil)
+
+type Map struct {
+	Brace  scanner.Token
+	Keys   []Expr
+	Values []Expr
+}
+
+func (*Map) isExpr() {}
+
+type MapStringVisitor interface {
+	VisitMapExprString(*Map) string
+}
+
+func (m *Map) AcceptString(visitor MapStringVisitor) string {
+	return visitor.VisitMapExprString(m)
+}
+
+type MapVoidVisitor interface {
+	VisitMapExprVoid(*Map)
+}
+
+func (m *Map) AcceptVoid(visitor MapVoidVisitor) {
+	visitor.VisitMapExprVoid(m)
+}
+
+type MapVisitor interface {
+	VisitMapExpr(*Map) interface{}
+}
+
+func (m *Map) Accept(visitor MapVisitor) interface{} {
+	return visitor.VisitMapExpr(m)
+}
+
 var _ Expr = (*Set)(nil)
 
 type Set struct {
@@ -274,6 +509,73 @@ func (set *Set) Accept(visitor SetVisitor) interface{} {
 	return visitor.VisitSetExpr(set)
 }
 
+var _ Expr = (*SetIndex)(nil)
+
+type SetIndex struct {
+	Object  Expr
+	Bracket scanner.Token
+	Index   Expr
+	Value   Expr
+}
+
+func (*SetIndex) isExpr() {}
+
+type SetIndexStringVisitor interface {
+	VisitSetIndexExprString(*SetIndex) string
+}
+
+func (setindex *SetIndex) AcceptString(visitor SetIndexStringVisitor) string {
+	return visitor.VisitSetIndexExprString(setindex)
+}
+
+type SetIndexVoidVisitor interface {
+	VisitSetIndexExprVoid(*SetIndex)
+}
+
+func (setindex *SetIndex) AcceptVoid(visitor SetIndexVoidVisitor) {
+	visitor.VisitSetIndexExprVoid(setindex)
+}
+
+type SetIndexVisitor interface {
+	VisitSetIndexExpr(*SetIndex) interface{}
+}
+
+func (setindex *SetIndex) Accept(visitor SetIndexVisitor) interface{} {
+	return visitor.VisitSetIndexExpr(setindex)
+}
+
+var _ Expr = (*Stringify)(nil)
+
+type Stringify struct {
+	Expression Expr
+}
+
+func (*Stringify) isExpr() {}
+
+type StringifyStringVisitor interface {
+	VisitStringifyExprString(*Stringify) string
+}
+
+func (stringify *Stringify) AcceptString(visitor StringifyStringVisitor) string {
+	return visitor.VisitStringifyExprString(stringify)
+}
+
+type StringifyVoidVisitor interface {
+	VisitStringifyExprVoid(*Stringify)
+}
+
+func (stringify *Stringify) AcceptVoid(visitor StringifyVoidVisitor) {
+	visitor.VisitStringifyExprVoid(stringify)
+}
+
+type StringifyVisitor interface {
+	VisitStringifyExpr(*Stringify) interface{}
+}
+
+func (stringify *Stringify) Accept(visitor StringifyVisitor) interface{} {
+	return visitor.VisitStringifyExpr(stringify)
+}
+
 var _ Expr = (*Super)(nil)
 
 type Super struct {
@@ -40,6 +40,71 @@ func (block *Block) Accept(visitor BlockVisitor) interface{} {
 	return visitor.VisitBlockStmt(block)
 }
 
+var _ Stmt = (*BreakStmt)(nil)
+
+type BreakStmt struct {
+	Keyword scanner.Token
+}
+
+func (*BreakStmt) isStmt() {}
+
+type BreakStmtStringVisitor interface {
+	VisitBreakStmtStmtString(*BreakStmt) string
+}
+
+func (breakstmt *BreakStmt) AcceptString(visitor BreakStmtStringVisitor) string {
+	return visitor.VisitBreakStmtStmtString(breakstmt)
+}
+
+type BreakStmtVoidVisitor interface {
+	VisitBreakStmtStmtVoid(*BreakStmt)
+}
+
+func (breakstmt *BreakStmt) AcceptVoid(visitor BreakStmtVoidVisitor) {
+	visitor.VisitBreakStmtStmtVoid(breakstmt)
+}
+
+type BreakStmtVisitor interface {
+	VisitBreakStmtStmt(*BreakStmt) interface{}
+}
+
+func (breakstmt *BreakStmt) Accept(visitor BreakStmtVisitor) interface{} {
+	return visitor.VisitBreakStmtStmt(breakstmt)
+}
+
+var _ Stmt = (*Catch)(nil)
+
+type Catch struct {
+	Name scanner.Token
+	Body []Stmt
+}
+
+func (*Catch) isStmt() {}
+
+type CatchStringVisitor interface {
+	VisitCatchStmtString(*Catch) string
+}
+
+func (catch *Catch) AcceptString(visitor CatchStringVisitor) string {
+	return visitor.VisitCatchStmtString(catch)
+}
+
+type CatchVoidVisitor interface {
+	VisitCatchStmtVoid(*Catch)
+}
+
+func (catch *Catch) AcceptVoid(visitor CatchVoidVisitor) {
+	visitor.VisitCatchStmtVoid(catch)
+}
+
+type CatchVisitor interface {
+	VisitCatchStmt(*Catch) interface{}
+}
+
+func (catch *Catch) Accept(visitor CatchVisitor) interface{} {
+	return visitor.VisitCatchStmt(catch)
+}
+
 var _ Stmt = (*Class)(nil)
 
 type Class struct {
@@ -74,6 +139,38 @@ func (class *Class) Accept(visitor ClassVisitor) interface{} {
 	return visitor.VisitClassStmt(class)
 }
 
+var _ Stmt = (*ContinueStmt)(nil)
+
+type ContinueStmt struct {
+	Keyword scanner.Token
+}
+
+func (*ContinueStmt) isStmt() {}
+
+type ContinueStmtStringVisitor interface {
+	VisitContinueStmtStmtString(*ContinueStmt) string
+}
+
+func (continuestmt *ContinueStmt) AcceptString(visitor ContinueStmtStringVisitor) string {
+	return visitor.VisitContinueStmtStmtString(continuestmt)
+}
+
+type ContinueStmtVoidVisitor interface {
+	VisitContinueStmtStmtVoid(*ContinueStmt)
+}
+
+func (continuestmt *ContinueStmt) AcceptVoid(visitor ContinueStmtVoidVisitor) {
+	visitor.VisitContinueStmtStmtVoid(continuestmt)
+}
+
+type ContinueStmtVisitor interface {
+	VisitContinueStmtStmt(*ContinueStmt) interface{}
+}
+
+func (continuestmt *ContinueStmt) Accept(visitor ContinueStmtVisitor) interface{} {
+	return visitor.VisitContinueStmtStmt(continuestmt)
+}
+
 var _ Stmt = (*Expression)(nil)
 
 type Expression struct {
@@ -174,6 +271,40 @@ func (ifstmt *IfStmt) Accept(visitor IfStmtVisitor) interface{} {
 	return visitor.VisitIfStmtStmt(ifstmt)
 }
 
+var _ Stmt = (*ImportStmt)(nil)
+
+type ImportStmt struct {
+	Keyword scanner.Token
+	Path    scanner.Token
+	Name    scanner.Token
+}
+
+func (*ImportStmt) isStmt() {}
+
+type ImportStmtStringVisitor interface {
+	VisitImportStmtStmtString(*ImportStmt) string
+}
+
+func (importstmt *ImportStmt) AcceptString(visitor ImportStmtStringVisitor) string {
+	return visitor.VisitImportStmtStmtString(importstmt)
+}
+
+type ImportStmtVoidVisitor interface {
+	VisitImportStmtStmtVoid(*ImportStmt)
+}
+
+func (importstmt *ImportStmt) AcceptVoid(visitor ImportStmtVoidVisitor) {
+	visitor.VisitImportStmtStmtVoid(importstmt)
+}
+
+type ImportStmtVisitor interface {
+	VisitImportStmtStmt(*ImportStmt) interface{}
+}
+
+func (importstmt *ImportStmt) Accept(visitor ImportStmtVisitor) interface{} {
+	return visitor.VisitImportStmtStmt(importstmt)
+}
+
 var _ Stmt = (*Print)(nil)
 
 type Print struct {
@@ -239,6 +370,73 @@ func (returnstmt *ReturnStmt) Accept(visitor ReturnStmtVisitor) interface{} {
 	return visitor.VisitReturnStmtStmt(returnstmt)
 }
 
+var _ Stmt = (*Throw)(nil)
+
+type Throw struct {
+	Keyword scanner.Token
+	Value   Expr
+}
+
+func (*Throw) isStmt() {}
+
+type ThrowStringVisitor interface {
+	VisitThrowStmtString(*Throw) string
+}
+
+func (throw *Throw) AcceptString(visitor ThrowStringVisitor) string {
+	return visitor.VisitThrowStmtString(throw)
+}
+
+type ThrowVoidVisitor interface {
+	VisitThrowStmtVoid(*Throw)
+}
+
+func (throw *Throw) AcceptVoid(visitor ThrowVoidVisitor) {
+	visitor.VisitThrowStmtVoid(throw)
+}
+
+type ThrowVisitor interface {
+	VisitThrowStmt(*Throw) interface{}
+}
+
+func (throw *Throw) Accept(visitor ThrowVisitor) interface{} {
+	return visitor.VisitThrowStmt(throw)
+}
+
+var _ Stmt = (*Try)(nil)
+
+type Try struct {
+	Body    Stmt
+	Catch   *Catch
+	Finally Stmt
+}
+
+func (*Try) isStmt() {}
+
+type TryStringVisitor interface {
+	VisitTryStmtString(*Try) string
+}
+
+func (try *Try) AcceptString(visitor TryStringVisitor) string {
+	return visitor.VisitTryStmtString(try)
+}
+
+type TryVoidVisitor interface {
+	VisitTryStmtVoid(*Try)
+}
+
+func (try *Try) AcceptVoid(visitor TryVoidVisitor) {
+	visitor.VisitTryStmtVoid(try)
+}
+
+type TryVisitor interface {
+	VisitTryStmt(*Try) interface{}
+}
+
+func (try *Try) Accept(visitor TryVisitor) interface{} {
+	return visitor.VisitTryStmt(try)
+}
+
 var _ Stmt = (*VarStmt)(nil)
 
 type VarStmt struct {
@@ -275,8 +473,10 @@ func (varstmt *VarStmt) Accept(visitor VarStmtVisitor) interface{} {
 var _ Stmt = (*While)(nil)
 
 type While struct {
+	Keyword   scanner.Token
 	Condition Expr
 	Body      Stmt
+	Increment Expr
 }
 
 func (*While) isStmt() {}
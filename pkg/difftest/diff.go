@@ -0,0 +1,84 @@
+package difftest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rhomel/golox/pkg/util/runner"
+)
+
+// Compare returns a human readable report of every difference between the
+// results of two implementations. An empty string means the results match.
+func Compare(leftName string, left *runner.Result, rightName string, right *runner.Result) string {
+	var report strings.Builder
+	if left.TimedOut != right.TimedOut {
+		fmt.Fprintf(&report, "timed out: %s=%v %s=%v\n", leftName, left.TimedOut, rightName, right.TimedOut)
+	}
+	if left.ExitCode != right.ExitCode {
+		fmt.Fprintf(&report, "exit code: %s=%d %s=%d\n", leftName, left.ExitCode, rightName, right.ExitCode)
+	}
+	if left.Stdout != right.Stdout {
+		fmt.Fprintf(&report, "stdout:\n--- %s\n+++ %s\n", leftName, rightName)
+		report.WriteString(Diff(left.Stdout, right.Stdout))
+	}
+	if left.Stderr != right.Stderr {
+		fmt.Fprintf(&report, "stderr:\n--- %s\n+++ %s\n", leftName, rightName)
+		report.WriteString(Diff(left.Stderr, right.Stderr))
+	}
+	return report.String()
+}
+
+// Diff returns a line based diff of a and b. Lines only in a are prefixed
+// with '-', lines only in b with '+' and common lines with ' '.
+func Diff(a, b string) string {
+	as := splitLines(a)
+	bs := splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of as[i:] and bs[j:]
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			if as[i] == bs[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(as) && j < len(bs) {
+		switch {
+		case as[i] == bs[j]:
+			fmt.Fprintf(&out, " %s\n", as[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			fmt.Fprintf(&out, "-%s\n", as[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+%s\n", bs[j])
+			j++
+		}
+	}
+	for ; i < len(as); i++ {
+		fmt.Fprintf(&out, "-%s\n", as[i])
+	}
+	for ; j < len(bs); j++ {
+		fmt.Fprintf(&out, "+%s\n", bs[j])
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
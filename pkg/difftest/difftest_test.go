@@ -0,0 +1,136 @@
+package difftest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rhomel/golox/pkg/util/runner"
+)
+
+// allowlist contains samples that are expected to diverge between the
+// tree-walk interpreter and the vm along with the reason why. Samples that use
+// features the vm doesn't have yet are listed in runner.Unsupported instead.
+var allowlist = map[string]string{
+	"4.7-identifiers.lox":   "parser error recovery differs after the first syntax error",
+	"invalid-character.lox": "vm scans lazily so scanner and parser errors interleave differently",
+}
+
+var golox string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "golox-difftest")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	golox, err = runner.Build(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func compare(t *testing.T, file string) {
+	t.Helper()
+	compareImplementations(t, file, runner.TreeWalk, runner.VM)
+}
+
+func compareImplementations(t *testing.T, file, left, right string) {
+	t.Helper()
+	leftResult, err := runner.Run(golox, left, file, runner.DefaultTimeout)
+	if err != nil {
+		t.Fatalf("running %s: %v", left, err)
+	}
+	rightResult, err := runner.Run(golox, right, file, runner.DefaultTimeout)
+	if err != nil {
+		t.Fatalf("running %s: %v", right, err)
+	}
+	if report := Compare(left, leftResult, right, rightResult); report != "" {
+		t.Errorf("implementations diverge on %s:\n%s", file, report)
+	}
+}
+
+func TestSamples(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "samples", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no samples found")
+	}
+	for _, file := range files {
+		file := file
+		name := filepath.Base(file)
+		t.Run(name, func(t *testing.T) {
+			if reason, ok := runner.Unsupported[runner.VM][name]; ok {
+				t.Skip("vm: " + reason)
+			}
+			if reason, ok := allowlist[name]; ok {
+				t.Skip(reason)
+			}
+			compare(t, file)
+		})
+	}
+}
+
+// slow lists samples that run for too long to compare implementations that
+// both support them.
+var slow = map[string]bool{
+	"14-fib-bench.lox":       true,
+	"fib-tail-recursion.lox": true,
+}
+
+// TestClosureSamples checks that the closure compiler matches the tree-walker
+// on every sample since both implement the whole language.
+func TestClosureSamples(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "samples", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		file := file
+		name := filepath.Base(file)
+		t.Run(name, func(t *testing.T) {
+			if slow[name] {
+				t.Skip("runs for too long")
+			}
+			compareImplementations(t, file, runner.TreeWalk, runner.Closure)
+		})
+	}
+}
+
+func TestGeneratedPrograms(t *testing.T) {
+	programs := 500
+	if testing.Short() {
+		programs = 50
+	}
+	dir := t.TempDir()
+	for seed := 0; seed < programs; seed++ {
+		source := NewGenerator(int64(seed)).Program()
+		file := filepath.Join(dir, fmt.Sprintf("generated-%d.lox", seed))
+		if err := os.WriteFile(file, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Run(fmt.Sprintf("seed-%d", seed), func(t *testing.T) {
+			compare(t, file)
+			compareImplementations(t, file, runner.TreeWalk, runner.Closure)
+			if t.Failed() {
+				t.Logf("program:\n%s", source)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := Diff("a\nb\nc\n", "a\nc\nd\n")
+	want := " a\n-b\n c\n+d\n"
+	if got != want {
+		t.Errorf("want diff:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,289 @@
+package difftest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Generator produces random, well-typed Lox programs that stay inside the
+// subset of the language implemented by both the tree-walk interpreter and the
+// vm: global and local variables, arithmetic, string concatenation,
+// comparisons, logical operators, print, blocks, if/else, while and for.
+//
+// Every generated expression is type correct so a program never raises a
+// runtime error, and every loop has a bounded counter so a program always
+// terminates.
+type Generator struct {
+	rand      *rand.Rand
+	out       strings.Builder
+	indent    int
+	scopes    [][]*variable
+	nextName  int
+	constants int
+
+	// literalStrings disables string variable references while generating
+	// an assignment so a loop can't grow a string exponentially
+	literalStrings bool
+}
+
+type valueType int
+
+const (
+	typeNumber valueType = iota
+	typeString
+	typeBool
+)
+
+type variable struct {
+	name     string
+	typ      valueType
+	global   bool
+	readOnly bool // loop counters can be read but not assigned
+}
+
+const (
+	maxStatements = 12
+	maxDepth      = 3
+	maxExprDepth  = 3
+	// the vm stores every literal and global name reference in a single chunk
+	// constant table of at most 256 entries, leave some headroom
+	maxConstants = 150
+)
+
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Program returns the source of a new random program.
+func (g *Generator) Program() string {
+	g.out.Reset()
+	g.indent = 0
+	g.scopes = [][]*variable{nil}
+	g.nextName = 0
+	g.constants = 0
+	count := 1 + g.rand.Intn(maxStatements)
+	for i := 0; i < count && g.constants < maxConstants; i++ {
+		g.statement(0)
+	}
+	return g.out.String()
+}
+
+func (g *Generator) line(format string, args ...interface{}) {
+	g.out.WriteString(strings.Repeat("  ", g.indent))
+	fmt.Fprintf(&g.out, format, args...)
+	g.out.WriteString("\n")
+}
+
+func (g *Generator) isGlobalScope() bool {
+	return len(g.scopes) == 1
+}
+
+func (g *Generator) beginScope() {
+	g.scopes = append(g.scopes, nil)
+}
+
+func (g *Generator) endScope() {
+	g.scopes = g.scopes[:len(g.scopes)-1]
+}
+
+func (g *Generator) declare(typ valueType, readOnly bool) *variable {
+	v := &variable{
+		name:     fmt.Sprintf("v%d", g.nextName),
+		typ:      typ,
+		global:   g.isGlobalScope(),
+		readOnly: readOnly,
+	}
+	g.nextName++
+	last := len(g.scopes) - 1
+	g.scopes[last] = append(g.scopes[last], v)
+	return v
+}
+
+// visible returns every variable in scope matching typ.
+func (g *Generator) visible(typ valueType, assignable bool) []*variable {
+	var vars []*variable
+	for _, scope := range g.scopes {
+		for _, v := range scope {
+			if v.typ == typ && !(assignable && v.readOnly) {
+				vars = append(vars, v)
+			}
+		}
+	}
+	return vars
+}
+
+func (g *Generator) reference(v *variable) string {
+	if v.global {
+		g.constants++
+	}
+	return v.name
+}
+
+func (g *Generator) randomType() valueType {
+	return valueType(g.rand.Intn(3))
+}
+
+func (g *Generator) statement(depth int) {
+	choice := g.rand.Intn(10)
+	if depth >= maxDepth {
+		choice = g.rand.Intn(4)
+	}
+	switch choice {
+	case 0, 1:
+		typ := g.randomType()
+		init := g.expression(typ, 0)
+		v := g.declare(typ, false)
+		g.line("var %s = %s;", g.reference(v), init)
+	case 2:
+		g.line("print %s;", g.expression(g.randomType(), 0))
+	case 3:
+		typ := g.randomType()
+		vars := g.visible(typ, true)
+		if len(vars) == 0 {
+			g.line("print %s;", g.expression(typ, 0))
+			return
+		}
+		v := vars[g.rand.Intn(len(vars))]
+		g.literalStrings = true
+		value := g.expression(typ, 0)
+		g.literalStrings = false
+		g.line("%s = %s;", g.reference(v), value)
+	case 4, 5:
+		g.line("if (%s) {", g.expression(typeBool, 0))
+		g.block(depth)
+		if g.rand.Intn(2) == 0 {
+			g.line("} else {")
+			g.block(depth)
+		}
+		g.line("}")
+	case 6:
+		g.line("{")
+		g.block(depth)
+		g.line("}")
+	case 7:
+		// while loops need a counter declared in an enclosing block
+		g.line("{")
+		g.indent++
+		g.beginScope()
+		counter := g.declare(typeNumber, true)
+		g.constants++
+		g.line("var %s = 0;", counter.name)
+		g.line("while (%s < %d) {", counter.name, 1+g.rand.Intn(4))
+		g.block(depth)
+		g.indent++
+		g.constants++
+		g.line("%s = %s + 1;", counter.name, counter.name)
+		g.indent--
+		g.line("}")
+		g.endScope()
+		g.indent--
+		g.line("}")
+	default:
+		g.beginScope()
+		counter := g.declare(typeNumber, true)
+		g.constants += 3
+		g.line("for (var %s = 0; %s < %d; %s = %s + 1) {", counter.name, counter.name, 1+g.rand.Intn(4), counter.name, counter.name)
+		g.block(depth)
+		g.line("}")
+		g.endScope()
+	}
+}
+
+func (g *Generator) block(depth int) {
+	g.indent++
+	g.beginScope()
+	count := 1 + g.rand.Intn(3)
+	for i := 0; i < count && g.constants < maxConstants; i++ {
+		g.statement(depth + 1)
+	}
+	g.endScope()
+	g.indent--
+}
+
+func (g *Generator) expression(typ valueType, depth int) string {
+	switch typ {
+	case typeNumber:
+		return g.number(depth)
+	case typeString:
+		return g.string(depth)
+	default:
+		return g.boolean(depth)
+	}
+}
+
+func (g *Generator) leaf(typ valueType) string {
+	if vars := g.visible(typ, false); len(vars) > 0 && g.rand.Intn(2) == 0 && !(typ == typeString && g.literalStrings) {
+		return g.reference(vars[g.rand.Intn(len(vars))])
+	}
+	g.constants++
+	switch typ {
+	case typeNumber:
+		if g.rand.Intn(4) == 0 {
+			return fmt.Sprintf("%d.5", g.rand.Intn(100))
+		}
+		return fmt.Sprintf("%d", g.rand.Intn(100))
+	case typeString:
+		words := []string{"a", "lox", "golox", "vm", "tree", ""}
+		return fmt.Sprintf("%q", words[g.rand.Intn(len(words))])
+	default:
+		g.constants-- // true and false have dedicated opcodes
+		if g.rand.Intn(2) == 0 {
+			return "true"
+		}
+		return "false"
+	}
+}
+
+func (g *Generator) number(depth int) string {
+	if depth >= maxExprDepth {
+		return g.leaf(typeNumber)
+	}
+	switch g.rand.Intn(6) {
+	case 0:
+		return g.leaf(typeNumber)
+	case 1:
+		return "-(" + g.number(depth+1) + ")"
+	case 2:
+		return "(" + g.number(depth+1) + ")"
+	case 3:
+		return "(" + g.boolean(depth+1) + " ? " + g.number(depth+1) + " : " + g.number(depth+1) + ")"
+	default:
+		operators := []string{"+", "-", "*", "/", "%", "~/", "**"}
+		operator := operators[g.rand.Intn(len(operators))]
+		return g.number(depth+1) + " " + operator + " " + g.number(depth+1)
+	}
+}
+
+func (g *Generator) string(depth int) string {
+	if depth >= maxExprDepth || g.rand.Intn(2) == 0 {
+		return g.leaf(typeString)
+	}
+	return g.string(depth+1) + " + " + g.string(depth+1)
+}
+
+func (g *Generator) boolean(depth int) string {
+	if depth >= maxExprDepth {
+		return g.leaf(typeBool)
+	}
+	switch g.rand.Intn(6) {
+	case 0:
+		return g.leaf(typeBool)
+	case 1:
+		return "!(" + g.boolean(depth+1) + ")"
+	case 2:
+		operators := []string{"<", "<=", ">", ">="}
+		operator := operators[g.rand.Intn(len(operators))]
+		return g.number(depth+1) + " " + operator + " " + g.number(depth+1)
+	case 3:
+		operators := []string{"==", "!="}
+		operator := operators[g.rand.Intn(len(operators))]
+		return "(" + g.expression(g.randomType(), depth+1) + " " + operator + " " + g.expression(g.randomType(), depth+1) + ")"
+	default:
+		operators := []string{"and", "or"}
+		operator := operators[g.rand.Intn(len(operators))]
+		return "(" + g.boolean(depth+1) + " " + operator + " " + g.boolean(depth+1) + ")"
+	}
+}
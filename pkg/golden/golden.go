@@ -0,0 +1,244 @@
+package golden
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rhomel/golox/pkg/util/exit"
+	"github.com/rhomel/golox/pkg/util/runner"
+)
+
+// Golden tests are .lox files annotated with comments describing the expected
+// result of running them. The annotations follow the format of the
+// craftinginterpreters test suite:
+//
+//   print 1 + 2; // expect: 3
+//   print nil + 1; // expect runtime error: Operands must be numbers.
+//   var a = ; // Error at ';': Expect expression.
+//   // [line 3] Error at 'a': Already a variable with this name in this scope.
+//
+// Errors with an explicit line may be restricted to one implementation with
+// `// [java line N]` (treewalk and closure) or `// [c line N]` (vm).
+//
+// ref:
+//   https://github.com/munificent/craftinginterpreters/blob/master/tool/bin/test.dart
+
+var (
+	expectedOutputPattern       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectedErrorPattern        = regexp.MustCompile(`// (Error.*)`)
+	errorLinePattern            = regexp.MustCompile(`// \[((java|c) )?line (\d+)\] (Error.*)`)
+	expectedRuntimeErrorPattern = regexp.MustCompile(`// expect runtime error: (.+)`)
+	syntaxErrorPattern          = regexp.MustCompile(`\[.*line (\d+)\] (Error.+)`)
+	stackTracePattern           = regexp.MustCompile(`\[line (\d+)\]`)
+)
+
+// the language names used by the craftinginterpreters suite for each implementation
+var errorLineLanguages = map[string]string{
+	runner.TreeWalk: "java",
+	runner.Closure:  "java",
+	runner.VM:       "c",
+}
+
+type Output struct {
+	Line int
+	Text string
+}
+
+type Expectations struct {
+	Output        []Output
+	CompileErrors map[string]bool // formatted as "[line N] Error..."
+	RuntimeError  *Output
+	ExitCode      int
+}
+
+// Parse collects the expectations for running source with implementation.
+func Parse(source, implementation string) *Expectations {
+	expectations := &Expectations{
+		CompileErrors: make(map[string]bool),
+	}
+	for i, line := range strings.Split(source, "\n") {
+		lineNumber := i + 1
+		if match := expectedOutputPattern.FindStringSubmatch(line); match != nil {
+			expectations.Output = append(expectations.Output, Output{lineNumber, match[1]})
+			continue
+		}
+		if match := expectedErrorPattern.FindStringSubmatch(line); match != nil {
+			expectations.CompileErrors[fmt.Sprintf("[line %d] %s", lineNumber, match[1])] = true
+			expectations.ExitCode = exit.ExitSyntaxError
+			continue
+		}
+		if match := errorLinePattern.FindStringSubmatch(line); match != nil {
+			language := match[2]
+			if language == "" || language == errorLineLanguages[implementation] {
+				expectations.CompileErrors[fmt.Sprintf("[line %s] %s", match[3], match[4])] = true
+				expectations.ExitCode = exit.ExitSyntaxError
+			}
+			continue
+		}
+		if match := expectedRuntimeErrorPattern.FindStringSubmatch(line); match != nil {
+			expectations.RuntimeError = &Output{lineNumber, match[1]}
+			expectations.ExitCode = exit.ExitRuntimeError
+		}
+	}
+	return expectations
+}
+
+// IsEmpty is true when the source had no annotations at all.
+func (e *Expectations) IsEmpty() bool {
+	return len(e.Output) == 0 && len(e.CompileErrors) == 0 && e.RuntimeError == nil
+}
+
+// Check returns a description of every way result fails to meet the
+// expectations. An empty slice means the test passed.
+func (e *Expectations) Check(result *runner.Result) []string {
+	var failures []string
+	fail := func(format string, args ...interface{}) {
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}
+	if result.TimedOut {
+		fail("Timed out.")
+		return failures
+	}
+
+	errorLines := splitLines(result.Stderr)
+	switch {
+	case e.RuntimeError != nil:
+		failures = append(failures, e.checkRuntimeError(errorLines)...)
+	case len(e.CompileErrors) > 0:
+		failures = append(failures, e.checkCompileErrors(errorLines)...)
+	default:
+		for _, line := range errorLines {
+			if line != "" {
+				fail("Unexpected output on stderr:\n%s", line)
+				break
+			}
+		}
+	}
+
+	if result.ExitCode != e.ExitCode {
+		fail("Expected return code %d and got %d.", e.ExitCode, result.ExitCode)
+	}
+
+	outputLines := splitLines(result.Stdout)
+	for i, line := range outputLines {
+		if i >= len(e.Output) {
+			fail("Got output '%s' when none was expected.", line)
+			continue
+		}
+		expected := e.Output[i]
+		if expected.Text != line {
+			fail("Expected output '%s' on line %d and got '%s'.", expected.Text, expected.Line, line)
+		}
+	}
+	for i := len(outputLines); i < len(e.Output); i++ {
+		fail("Missing expected output '%s' on line %d.", e.Output[i].Text, e.Output[i].Line)
+	}
+	return failures
+}
+
+func (e *Expectations) checkRuntimeError(errorLines []string) []string {
+	if len(errorLines) < 2 {
+		return []string{fmt.Sprintf("Expected runtime error '%s' and got:\n%s", e.RuntimeError.Text, strings.Join(errorLines, "\n"))}
+	}
+	if errorLines[0] != e.RuntimeError.Text {
+		return []string{fmt.Sprintf("Expected runtime error '%s' and got:\n%s", e.RuntimeError.Text, errorLines[0])}
+	}
+	for _, line := range errorLines[1:] {
+		if match := stackTracePattern.FindStringSubmatch(line); match != nil {
+			if line, _ := strconv.Atoi(match[1]); line != e.RuntimeError.Line {
+				return []string{fmt.Sprintf("Expected runtime error on line %d but was on line %d.", e.RuntimeError.Line, line)}
+			}
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("Expected stack trace and got:\n%s", strings.Join(errorLines[1:], "\n"))}
+}
+
+func (e *Expectations) checkCompileErrors(errorLines []string) []string {
+	var failures []string
+	found := make(map[string]bool)
+	for _, line := range errorLines {
+		if match := syntaxErrorPattern.FindStringSubmatch(line); match != nil {
+			message := fmt.Sprintf("[line %s] %s", match[1], match[2])
+			if e.CompileErrors[message] {
+				found[message] = true
+			} else {
+				failures = append(failures, fmt.Sprintf("Unexpected error:\n%s", line))
+			}
+		} else if line != "" {
+			failures = append(failures, fmt.Sprintf("Unexpected output on stderr:\n%s", line))
+		}
+	}
+	var missing []string
+	for message := range e.CompileErrors {
+		if !found[message] {
+			missing = append(missing, message)
+		}
+	}
+	sort.Strings(missing)
+	for _, message := range missing {
+		failures = append(failures, fmt.Sprintf("Missing expected error: %s", message))
+	}
+	return failures
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+type Report struct {
+	File     string
+	Skipped  bool
+	Failures []string
+}
+
+func (r *Report) Passed() bool {
+	return !r.Skipped && len(r.Failures) == 0
+}
+
+// RunFile runs a single golden test file with the golox binary. Files without
+// any annotations are skipped.
+func RunFile(binary, implementation, file string, timeout time.Duration) (*Report, error) {
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{File: file}
+	expectations := Parse(string(source), implementation)
+	if expectations.IsEmpty() {
+		report.Skipped = true
+		return report, nil
+	}
+	result, err := runner.Run(binary, implementation, file, timeout)
+	if err != nil {
+		return nil, err
+	}
+	report.Failures = expectations.Check(result)
+	return report, nil
+}
+
+// Files returns every .lox file under path in lexical order. path may also be
+// a single file.
+func Files(path string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(file) == ".lox" {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,85 @@
+package golden
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rhomel/golox/pkg/util/runner"
+)
+
+var golox string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "golox-golden")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	golox, err = runner.Build(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestSamples(t *testing.T) {
+	files, err := Files(filepath.Join("..", "..", "samples"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, implementation := range runner.Implementations {
+		implementation := implementation
+		t.Run(implementation, func(t *testing.T) {
+			for _, file := range files {
+				file := file
+				name := filepath.Base(file)
+				t.Run(name, func(t *testing.T) {
+					if reason, ok := runner.Unsupported[implementation][name]; ok {
+						t.Skip(reason)
+					}
+					report, err := RunFile(golox, implementation, file, runner.DefaultTimeout)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if report.Skipped {
+						t.Skip("no expectations")
+					}
+					for _, failure := range report.Failures {
+						t.Error(failure)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	source := strings.Join([]string{
+		`print 1; // expect: 1`,
+		`print "a" + 1; // expect runtime error: Operands must be two numbers or two strings.`,
+	}, "\n")
+	expectations := Parse(source, runner.TreeWalk)
+	pass := &runner.Result{
+		Stdout:   "1\n",
+		Stderr:   "Operands must be two numbers or two strings.\n[line 2]\n",
+		ExitCode: 70,
+	}
+	if failures := expectations.Check(pass); len(failures) != 0 {
+		t.Errorf("want no failures, got: %v", failures)
+	}
+	fail := &runner.Result{
+		Stdout:   "2\n",
+		Stderr:   "Operands must be two numbers or two strings.\n[line 1]\n",
+		ExitCode: 0,
+	}
+	if want, got := 3, len(expectations.Check(fail)); want != got {
+		t.Errorf("want %d failures, got: %d", want, got)
+	}
+}
@@ -0,0 +1,118 @@
+package golox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyntaxError is a single error found while scanning, parsing or resolving.
+type SyntaxError struct {
+	Line int
+	// Where is " at 'lexeme'", " at end" or empty for scanner errors.
+	Where   string
+	Message string
+}
+
+// Error formats the error the same way the golox command does.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("[line %d] Error%s: %s", e.Line, e.Where, e.Message)
+}
+
+// CompileError is returned when source has one or more syntax errors.
+type CompileError struct {
+	Errors []*SyntaxError
+}
+
+func (e *CompileError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// RuntimeError is returned when a program fails while running.
+type RuntimeError struct {
+	Line    int
+	Message string
+	// Trace lists the calls in progress when the error occurred, starting
+	// with the innermost one.
+	Trace []StackFrame
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("[line %d] %s", e.Line, e.Message)
+}
+
+// maxTraceFrames is the most calls StackTrace prints. Runaway recursion has
+// thousands of calls in progress.
+const maxTraceFrames = 20
+
+// StackTrace formats Trace the same way the golox command does, one call per
+// line:
+//
+//	[line 2] in inner()
+//	[line 5] in outer()
+//	[line 7] in script
+//
+// Only the innermost and outermost calls of a long trace are included.
+func (e *RuntimeError) StackTrace() string {
+	var trace strings.Builder
+	frames := e.Trace
+	if omitted := len(frames) - maxTraceFrames; omitted > 0 {
+		for _, frame := range frames[:maxTraceFrames/2] {
+			fmt.Fprintf(&trace, "%s\n", frame)
+		}
+		fmt.Fprintf(&trace, "... %d more calls\n", omitted)
+		frames = frames[len(frames)-maxTraceFrames/2:]
+	}
+	for _, frame := range frames {
+		fmt.Fprintf(&trace, "%s\n", frame)
+	}
+	return trace.String()
+}
+
+// StackFrame is a call in progress when a runtime error occurred.
+type StackFrame struct {
+	// Function is empty for top level code.
+	Function string
+	// Line is the line being executed in the function.
+	Line int
+}
+
+func (f StackFrame) String() string {
+	if f.Function == "" {
+		return fmt.Sprintf("[line %d] in script", f.Line)
+	}
+	return fmt.Sprintf("[line %d] in %s()", f.Line, f.Function)
+}
+
+// errorCollector collects the errors reported while running source so they
+// can be returned as a single error.
+type errorCollector struct {
+	syntaxErrors []*SyntaxError
+	runtimeError *RuntimeError
+}
+
+func (c *errorCollector) reset() {
+	c.syntaxErrors = nil
+	c.runtimeError = nil
+}
+
+func (c *errorCollector) syntaxError(line int, where, message string) {
+	c.syntaxErrors = append(c.syntaxErrors, &SyntaxError{line, where, message})
+}
+
+func (c *errorCollector) hadSyntaxError() bool {
+	return len(c.syntaxErrors) > 0
+}
+
+func (c *errorCollector) err() error {
+	if c.hadSyntaxError() {
+		return &CompileError{c.syntaxErrors}
+	}
+	if c.runtimeError != nil {
+		return c.runtimeError
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Package golox runs Lox programs from Go.
+//
+//	lox, err := golox.New(golox.Options{Stdout: &out})
+//	if err != nil { ... }
+//	if err := lox.Run(`fun add(a, b) { return a + b; }`); err != nil { ... }
+//	sum, err := lox.Call("add", 1, 2) // 3.0
+//
+// Lox values are returned as a Value, which has a Kind and accessors for each
+// kind. Value.Interface converts it to an ordinary Go value: nil, bool,
+// float64 or string, an opaque value for functions, classes and instances,
+// and the Go struct or map itself for objects exposed to Lox.
+//
+// Go values passed to Lox may be a Value or an ordinary Go value: nil, bool,
+// string, or any Go integer or float type for a number.
+package golox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/rhomel/golox/pkg/interpreter"
+)
+
+// Value is a Lox value.
+type Value = interpreter.Value
+
+type Engine string
+
+const (
+	// TreeWalk is the tree-walk interpreter from part II of Crafting
+	// Interpreters. It implements the whole language.
+	TreeWalk Engine = "treewalk"
+	// Closure compiles the same tree into nested Go closures before running
+	// it. It implements the whole language and runs faster than TreeWalk.
+	Closure Engine = "closure"
+	// VM is the bytecode vm from part III. It does not support functions or
+	// classes yet.
+	VM Engine = "vm"
+)
+
+type Options struct {
+	// Engine defaults to TreeWalk.
+	Engine Engine
+	// Stdout receives the output of print statements. It defaults to
+	// os.Stdout.
+	Stdout io.Writer
+	// MaxCallDepth is how many calls can be in progress before a call fails
+	// with a "Stack overflow." runtime error. It defaults to
+	// interpreter.DefaultMaxCallDepth. The VM engine has its own fixed limit.
+	MaxCallDepth int
+	// ModulePath lists the directories an import statement looks in when the
+	// module isn't found next to the importing file.
+	ModulePath []string
+}
+
+// engine is implemented by each interpreter. Values passed to an engine have
+// already been normalized by toLox.
+type engine interface {
+	// run runs source read from file, which is empty if it isn't a file.
+	run(file, source string) error
+	eval(expression string) (Value, error)
+	call(name string, args []interface{}) (Value, error)
+	setGlobal(name string, value interface{}) error
+	getGlobal(name string) (Value, bool)
+}
+
+// Lox is a Lox interpreter with its own global variables. Globals persist
+// between calls so a program can be loaded with Run and then used with Eval
+// and Call. A Lox must not be used concurrently.
+type Lox struct {
+	engine engine
+}
+
+func New(opts Options) (*Lox, error) {
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.MaxCallDepth < 0 {
+		return nil, fmt.Errorf("invalid max call depth %d", opts.MaxCallDepth)
+	}
+	if opts.MaxCallDepth == 0 {
+		opts.MaxCallDepth = interpreter.DefaultMaxCallDepth
+	}
+	switch opts.Engine {
+	case TreeWalk, "":
+		return &Lox{newTreeWalk(opts, false)}, nil
+	case Closure:
+		return &Lox{newTreeWalk(opts, true)}, nil
+	case VM:
+		return &Lox{newVM(opts)}, nil
+	}
+	return nil, fmt.Errorf("unknown engine '%s'", opts.Engine)
+}
+
+// Run runs a program. It returns a *CompileError if the program has syntax
+// errors, in which case nothing is run, or a *RuntimeError if the program
+// fails.
+func (l *Lox) Run(source string) error {
+	return l.engine.run("", source)
+}
+
+// RunFile runs the program in file like Run. Modules it imports are found
+// relative to file; Run finds them relative to the current directory.
+func (l *Lox) RunFile(file string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return l.engine.run(file, string(b))
+}
+
+// Eval returns the value of a single expression. Errors are returned as in Run.
+func (l *Lox) Eval(expression string) (Value, error) {
+	return l.engine.eval(expression)
+}
+
+// Call calls the global function or class name with args and returns the
+// result. Errors raised by the function are returned as a *RuntimeError.
+func (l *Lox) Call(name string, args ...interface{}) (Value, error) {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		value, err := toLox(arg)
+		if err != nil {
+			return interpreter.Nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		values[i] = value
+	}
+	return l.engine.call(name, values)
+}
+
+// SetGlobal defines or redefines a global variable. With the TreeWalk and
+// Closure engines value may also be:
+//
+//   - an ordinary Go function, which is bound as a native Lox function with
+//     its arguments and results converted automatically (see
+//     interpreter.NativeFunction). An error returned by the function or a
+//     panic becomes a Lox runtime error.
+//   - a struct, a pointer to a struct or a map with string keys, which Lox
+//     sees as an object with fields and methods (see interpreter.GoObject).
+//     Use Hide to make some members inaccessible.
+func (l *Lox) SetGlobal(name string, value interface{}) error {
+	v, err := toLox(value)
+	if err != nil {
+		return err
+	}
+	return l.engine.setGlobal(name, v)
+}
+
+type hiddenMembers struct {
+	value   interface{}
+	members []string
+}
+
+// Hide wraps a struct or map for SetGlobal so Lox can't access the named
+// fields, map entries or methods. They are also hidden in any object reached
+// through value.
+func Hide(value interface{}, members ...string) interface{} {
+	return &hiddenMembers{value, members}
+}
+
+// GetGlobal returns the value of a global variable. ok is false if the
+// variable is not defined.
+func (l *Lox) GetGlobal(name string) (value Value, ok bool) {
+	return l.engine.getGlobal(name)
+}
+
+// toLox converts numbers to a Number Value and other values that have a Value
+// to it. Other values are passed through unchanged for the engine to convert.
+func toLox(value interface{}) (interface{}, error) {
+	if v, ok := interpreter.ValueOf(value); ok {
+		return v, nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return interpreter.Number(float64(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return interpreter.Number(float64(rv.Uint())), nil
+	case reflect.Float32:
+		return interpreter.Number(rv.Float()), nil
+	}
+	return value, nil
+}
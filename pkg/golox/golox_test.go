@@ -0,0 +1,598 @@
+package golox
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newLox(t *testing.T, engine Engine) (*Lox, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	lox, err := New(Options{Engine: engine, Stdout: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lox, &out
+}
+
+func forEachEngine(t *testing.T, test func(t *testing.T, engine Engine)) {
+	for _, engine := range []Engine{TreeWalk, Closure, VM} {
+		engine := engine
+		t.Run(string(engine), func(t *testing.T) {
+			test(t, engine)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine Engine) {
+		lox, out := newLox(t, engine)
+		if err := lox.Run(`var a = 1; print a + 2; print "lox";`); err != nil {
+			t.Fatal(err)
+		}
+		if want, got := "3\nlox\n", out.String(); want != got {
+			t.Errorf("want output %q, got %q", want, got)
+		}
+		// globals persist between runs
+		if err := lox.Run(`print a;`); err != nil {
+			t.Fatal(err)
+		}
+		if want, got := "3\nlox\n1\n", out.String(); want != got {
+			t.Errorf("want output %q, got %q", want, got)
+		}
+	})
+}
+
+func TestRunCompileError(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine Engine) {
+		lox, out := newLox(t, engine)
+		err := lox.Run("print 1;\nvar = 2;")
+		var compileErr *CompileError
+		if !errors.As(err, &compileErr) {
+			t.Fatalf("want *CompileError, got %T: %v", err, err)
+		}
+		want := SyntaxError{2, " at '='", "Expect variable name."}
+		if len(compileErr.Errors) != 1 || *compileErr.Errors[0] != want {
+			t.Errorf("want %v, got %v", want, compileErr.Errors)
+		}
+		if out.Len() != 0 {
+			t.Errorf("nothing should run when there is a syntax error, got %q", out.String())
+		}
+	})
+}
+
+func TestRunRuntimeError(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine Engine) {
+		lox, _ := newLox(t, engine)
+		err := lox.Run("print 1;\nprint -\"a\";")
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Fatalf("want *RuntimeError, got %T: %v", err, err)
+		}
+		want := &RuntimeError{2, "Operand must be a number.", []StackFrame{{Line: 2}}}
+		if !reflect.DeepEqual(want, runtimeErr) {
+			t.Errorf("want %v, got %v", want, runtimeErr)
+		}
+		// the next run starts from a clean state
+		if err := lox.Run("print 1;"); err != nil {
+			t.Errorf("want no error after a runtime error, got %v", err)
+		}
+	})
+}
+
+func TestRuntimeErrorStackTrace(t *testing.T) {
+	for _, engine := range []Engine{TreeWalk, Closure} {
+		engine := engine
+		t.Run(string(engine), func(t *testing.T) {
+			lox, _ := newLox(t, engine)
+			err := lox.Run(`fun inner(x) {
+  return -x;
+}
+class Outer {
+  init() {
+    inner("a");
+  }
+}
+fun outer() {
+  Outer();
+}
+outer();`)
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) {
+				t.Fatalf("want *RuntimeError, got %T: %v", err, err)
+			}
+			want := "[line 2] in inner()\n[line 6] in init()\n[line 10] in outer()\n[line 12] in script\n"
+			if got := runtimeErr.StackTrace(); want != got {
+				t.Errorf("want trace:\n%s\ngot:\n%s", want, got)
+			}
+			// functions called from Go don't have a call site in Lox
+			_, err = lox.Call("inner", "a")
+			if !errors.As(err, &runtimeErr) {
+				t.Fatalf("want *RuntimeError, got %T: %v", err, err)
+			}
+			if want := []StackFrame{{"inner", 2}}; !reflect.DeepEqual(want, runtimeErr.Trace) {
+				t.Errorf("want trace %v, got %v", want, runtimeErr.Trace)
+			}
+		})
+	}
+}
+
+func TestUncaughtThrowStackTrace(t *testing.T) {
+	for _, engine := range []Engine{TreeWalk, Closure} {
+		engine := engine
+		t.Run(string(engine), func(t *testing.T) {
+			lox, out := newLox(t, engine)
+			err := lox.Run(`fun inner() {
+  throw "oops";
+}
+fun outer() {
+  try {
+    inner();
+  } finally {
+    print "cleanup";
+  }
+}
+outer();`)
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) {
+				t.Fatalf("want *RuntimeError, got %T: %v", err, err)
+			}
+			// the trace is where the value was thrown, not the finally clause
+			want := &RuntimeError{2, "oops", []StackFrame{{"inner", 2}, {"outer", 6}, {Line: 11}}}
+			if !reflect.DeepEqual(want, runtimeErr) {
+				t.Errorf("want %v, got %v", want, runtimeErr)
+			}
+			if want, got := "cleanup\n", out.String(); want != got {
+				t.Errorf("want output %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	for _, engine := range []Engine{TreeWalk, Closure} {
+		engine := engine
+		t.Run(string(engine), func(t *testing.T) {
+			lox, err := New(Options{Engine: engine, Stdout: &bytes.Buffer{}, MaxCallDepth: 100})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := lox.Run(`fun count(n) { if (n == 0) return 0; return 1 + count(n - 1); }`); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := lox.Eval("count(99)"); err != nil || got.Interface() != 99.0 {
+				t.Errorf("want 99, got %v (%v)", got, err)
+			}
+			_, err = lox.Eval("count(100)")
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) || runtimeErr.Message != "Stack overflow." {
+				t.Fatalf("want a stack overflow, got %v", err)
+			}
+			if want, got := 101, len(runtimeErr.Trace); want != got {
+				t.Errorf("want %d frames, got %d", want, got)
+			}
+			if want, got := maxTraceFrames+1, strings.Count(runtimeErr.StackTrace(), "\n"); want != got {
+				t.Errorf("want %d lines in the stack trace, got %d", want, got)
+			}
+			// the interpreter is still usable after a stack overflow
+			if got, err := lox.Eval("count(10)"); err != nil || got.Interface() != 10.0 {
+				t.Errorf("want 10, got %v (%v)", got, err)
+			}
+		})
+	}
+	if _, err := New(Options{MaxCallDepth: -1}); err == nil {
+		t.Error("want an error for a negative max call depth")
+	}
+}
+
+func TestRunFileImports(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	files := map[string]string{
+		"main.lox":           `import "local.lox" as local; import "shared.lox" as shared; print local.name + " " + shared.name;`,
+		"local.lox":          `print "loading local"; var name = "local";`,
+		"lib/shared.lox":     `var name = "shared";`,
+		"lib/cycle-a.lox":    `import "cycle-b.lox" as b;`,
+		"lib/cycle-b.lox":    `import "cycle-a.lox" as a;`,
+		"lib/has-errors.lox": `var = 1;`,
+	}
+	for name, source := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	forEachEngine(t, func(t *testing.T, engine Engine) {
+		var out bytes.Buffer
+		lox, err := New(Options{Engine: engine, Stdout: &out, ModulePath: []string{lib}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := lox.RunFile(filepath.Join(dir, "main.lox")); err != nil {
+			t.Fatal(err)
+		}
+		// a module runs only the first time it is imported
+		if err := lox.RunFile(filepath.Join(dir, "main.lox")); err != nil {
+			t.Fatal(err)
+		}
+		if want, got := "loading local\nlocal shared\nlocal shared\n", out.String(); want != got {
+			t.Errorf("want output %q, got %q", want, got)
+		}
+		for source, want := range map[string]string{
+			`import "missing.lox" as m;`:    "Module 'missing.lox' not found.",
+			`import "cycle-a.lox" as a;`:    "Import cycle: cycle-a.lox -> cycle-b.lox -> cycle-a.lox.",
+			`import "has-errors.lox" as m;`: "Error in module 'has-errors.lox': [line 1] Error at '=': Expect variable name.",
+		} {
+			var runtimeErr *RuntimeError
+			if err := lox.Run(source); !errors.As(err, &runtimeErr) || runtimeErr.Message != want {
+				t.Errorf("%s: want runtime error %q, got %v", source, want, err)
+			}
+		}
+	})
+}
+
+func TestEval(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine Engine) {
+		lox, _ := newLox(t, engine)
+		if err := lox.Run(`var a = 2; var s = "go";`); err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			expression string
+			want       interface{}
+		}{
+			{"a * 3", 6.0},
+			{`s + "lox"`, "golox"},
+			{"a > 1", true},
+			{"nil", nil},
+		}
+		for _, test := range tests {
+			got, err := lox.Eval(test.expression)
+			if err != nil {
+				t.Errorf("%s: %v", test.expression, err)
+				continue
+			}
+			if got.Interface() != test.want {
+				t.Errorf("%s: want %v, got %v", test.expression, test.want, got)
+			}
+		}
+
+		if _, err := lox.Eval("a +"); !errors.As(err, new(*CompileError)) {
+			t.Errorf("want *CompileError, got %v", err)
+		}
+		if _, err := lox.Eval("a; b"); !errors.As(err, new(*CompileError)) {
+			t.Errorf("want *CompileError for trailing tokens, got %v", err)
+		}
+		if _, err := lox.Eval("a + s"); !errors.As(err, new(*RuntimeError)) {
+			t.Errorf("want *RuntimeError, got %v", err)
+		}
+	})
+}
+
+func TestEvalList(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine Engine) {
+		lox, _ := newLox(t, engine)
+		got, err := lox.Eval(`[1, "a", [true]]`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `[1, "a", [true]]`; got.String() != want {
+			t.Errorf("want %s, got %s", want, got)
+		}
+		if err := lox.Run(`var l = [1]; l[0] = l;`); err != nil {
+			t.Fatal(err)
+		}
+		list, _ := lox.GetGlobal("l")
+		if want := "[[...]]"; list.String() != want {
+			t.Errorf("want %s, got %s", want, list)
+		}
+		if elements := list.AsList().Elements(); elements[0].AsList() != list.AsList() {
+			t.Error("want the list to contain itself")
+		}
+	})
+}
+
+func TestGlobals(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine Engine) {
+		lox, out := newLox(t, engine)
+		for name, value := range map[string]interface{}{"n": 42, "s": "lox", "b": true, "z": nil} {
+			if err := lox.SetGlobal(name, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := lox.Run(`print n + 1; print s; print b; print z; var out = n * 2;`); err != nil {
+			t.Fatal(err)
+		}
+		if want, got := "43\nlox\ntrue\nnil\n", out.String(); want != got {
+			t.Errorf("want output %q, got %q", want, got)
+		}
+		if value, ok := lox.GetGlobal("out"); !ok || value.Interface() != 84.0 {
+			t.Errorf("want 84, got %v (defined: %v)", value, ok)
+		}
+		if _, ok := lox.GetGlobal("undefined"); ok {
+			t.Error("want undefined global to not be found")
+		}
+		if err := lox.SetGlobal("bad", []int{}); err == nil {
+			t.Error("want an error for an unsupported value")
+		}
+	})
+}
+
+func TestCall(t *testing.T) {
+	for _, engine := range []Engine{TreeWalk, Closure} {
+		engine := engine
+		t.Run(string(engine), func(t *testing.T) {
+			lox, out := newLox(t, engine)
+			err := lox.Run(`
+fun add(a, b) { return a + b; }
+fun fail() { return -"a"; }
+class Point {
+  init(x) { this.x = x; }
+}
+`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := lox.Call("add", 1, 2.5); err != nil || got.Interface() != 3.5 {
+				t.Errorf("want 3.5, got %v (%v)", got, err)
+			}
+			if got, err := lox.Call("add", "go", "lox"); err != nil || got.Interface() != "golox" {
+				t.Errorf("want golox, got %v (%v)", got, err)
+			}
+			if _, err := lox.Call("add", 1); err == nil {
+				t.Error("want an arity error")
+			}
+			if _, err := lox.Call("missing"); err == nil {
+				t.Error("want an error for an undefined function")
+			}
+			if _, err := lox.Call("fail"); !errors.As(err, new(*RuntimeError)) {
+				t.Errorf("want *RuntimeError, got %v", err)
+			}
+			point, err := lox.Call("Point", 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// opaque values can be passed back in
+			if err := lox.SetGlobal("p", point); err != nil {
+				t.Fatal(err)
+			}
+			if err := lox.Run("print p.x;"); err != nil {
+				t.Fatal(err)
+			}
+			if want, got := "3\n", out.String(); want != got {
+				t.Errorf("want output %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestNewUnknownEngine(t *testing.T) {
+	if _, err := New(Options{Engine: "jit"}); err == nil {
+		t.Error("want an error for an unknown engine")
+	}
+}
+
+func TestBindGoFunction(t *testing.T) {
+	lox, out := newLox(t, TreeWalk)
+	greet := func(name string, times int) (string, error) {
+		if times < 1 {
+			return "", errors.New("Times must be positive.")
+		}
+		return strings.Repeat("hi "+name+" ", times), nil
+	}
+	sum := func(xs ...float64) float64 {
+		total := 0.0
+		for _, x := range xs {
+			total += x
+		}
+		return total
+	}
+	for name, fn := range map[string]interface{}{"greet": greet, "sum": sum} {
+		if err := lox.SetGlobal(name, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := lox.Run(`print greet("lox", 2); print sum(); print sum(1, 2, 3); print greet;`); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "hi lox hi lox \n0\n6\n<native fn>\n", out.String(); want != got {
+		t.Errorf("want output %q, got %q", want, got)
+	}
+
+	err := lox.Run("print 1;\ngreet(\"lox\", 0);")
+	if want := (&RuntimeError{2, "Times must be positive.", []StackFrame{{Line: 2}}}); !reflect.DeepEqual(err, want) {
+		t.Errorf("want %v, got %v", want, err)
+	}
+	err = lox.Run(`greet(1, 2);`)
+	if want := (&RuntimeError{1, "Argument 1 to greet must be a string.", []StackFrame{{Line: 1}}}); !reflect.DeepEqual(err, want) {
+		t.Errorf("want %v, got %v", want, err)
+	}
+	if got, err := lox.Call("sum", 1, 2); err != nil || got.Interface() != 3.0 {
+		t.Errorf("want 3, got %v (%v)", got, err)
+	}
+	var runtimeErr *RuntimeError
+	if _, err := lox.Call("greet", "lox", -1); !errors.As(err, &runtimeErr) || runtimeErr.Message != "Times must be positive." {
+		t.Errorf("want runtime error, got %v", err)
+	}
+	if err := lox.SetGlobal("boom", func() { panic("x") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := lox.Run("boom();"); !errors.As(err, &runtimeErr) || runtimeErr.Message != "boom panicked: x." {
+		t.Errorf("want runtime error for a panic, got %v", err)
+	}
+	if _, err := lox.Call("boom"); !errors.As(err, &runtimeErr) || runtimeErr.Message != "boom panicked: x." {
+		t.Errorf("want runtime error for a panic, got %v", err)
+	}
+
+	if err := lox.SetGlobal("bad", func(chan int) {}); err == nil {
+		t.Error("want an error for an unsupported parameter type")
+	}
+	vm, _ := newLox(t, VM)
+	if err := vm.SetGlobal("sum", sum); err == nil {
+		t.Error("want an error binding a function with the vm")
+	}
+}
+
+type address struct {
+	City string
+}
+
+type inner struct {
+	X float64
+}
+
+// outer promotes the fields of inner, which can be nil.
+type outer struct {
+	*inner
+	Y float64
+}
+
+type config struct {
+	Name     string
+	Retries  int
+	Debug    bool
+	Address  address
+	Password string
+	Internal string `lox:"-"`
+	secret   string
+}
+
+func (c *config) Greeting(greeting string) string {
+	return greeting + " " + c.Name
+}
+
+func (c *config) Self() *config {
+	return c
+}
+
+func (c *config) Fail() error {
+	return errors.New("Failed.")
+}
+
+func (c config) String() string {
+	return "config " + c.Name
+}
+
+func TestGoObject(t *testing.T) {
+	lox, out := newLox(t, TreeWalk)
+	cfg := &config{Name: "lox", Retries: 3, Address: address{"Tokyo"}, Password: "hunter2", Internal: "x", secret: "y"}
+	if err := lox.SetGlobal("cfg", Hide(cfg, "Password")); err != nil {
+		t.Fatal(err)
+	}
+	settings := map[string]interface{}{"level": 1}
+	if err := lox.SetGlobal("settings", settings); err != nil {
+		t.Fatal(err)
+	}
+	err := lox.Run(`
+print cfg.Name;
+print cfg.Retries + 1;
+print cfg.Address.City;
+print cfg.Greeting("hello");
+print cfg;
+cfg.Retries = 5;
+cfg.Debug = true;
+cfg.Address.City = "Osaka";
+print settings.level;
+settings.level = "high";
+settings.added = nil;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "lox\n4\nTokyo\nhello lox\nconfig lox\n1\n", out.String(); want != got {
+		t.Errorf("want output %q, got %q", want, got)
+	}
+	if cfg.Retries != 5 || !cfg.Debug || cfg.Address.City != "Osaka" {
+		t.Errorf("writes from Lox were not applied: %+v", cfg)
+	}
+	if settings["level"] != "high" {
+		t.Errorf("want map entry to be written, got %v", settings)
+	}
+	if value, ok := settings["added"]; !ok || value != nil {
+		t.Errorf("want map entry to be added, got %v", settings)
+	}
+	if value, _ := lox.GetGlobal("cfg"); value.Interface() != cfg {
+		t.Errorf("want GetGlobal to return the Go value, got %v", value)
+	}
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"print cfg.Password;", "Undefined property 'Password'."},
+		{"print cfg.Self().Password;", "Undefined property 'Password'."},
+		{"cfg.Password = 1;", "Undefined property 'Password'."},
+		{"print cfg.Internal;", "Undefined property 'Internal'."},
+		{"print cfg.secret;", "Undefined property 'secret'."},
+		{"print cfg.missing;", "Undefined property 'missing'."},
+		{"print settings.missing;", "Undefined property 'missing'."},
+		{`cfg.Retries = "x";`, "Property 'Retries' must be a number."},
+		{"cfg.Retries = 1.5;", "Property 'Retries' must be an integer that fits in int."},
+		{"cfg.Address = nil;", "Property 'Address' must be a golox.address."},
+		{"cfg.Fail();", "Failed."},
+	}
+	for _, test := range tests {
+		var runtimeErr *RuntimeError
+		if err := lox.Run(test.source); !errors.As(err, &runtimeErr) || runtimeErr.Message != test.want {
+			t.Errorf("%s: want runtime error %q, got %v", test.source, test.want, err)
+		}
+	}
+
+	// a struct value can be read but not written
+	if err := lox.SetGlobal("copy", config{Name: "copy"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lox.Run(`copy.Name = "x";`); err == nil {
+		t.Error("want an error writing a field of a struct value")
+	}
+	if err := lox.SetGlobal("list", []string{}); err == nil {
+		t.Error("want an error for an unsupported value type")
+	}
+
+	// a field promoted from a nil embedded pointer can't be read or written
+	if err := lox.SetGlobal("o", &outer{Y: 1}); err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range []string{"print o.X;", "o.X = 2;"} {
+		var runtimeErr *RuntimeError
+		if err := lox.Run(source); !errors.As(err, &runtimeErr) || runtimeErr.Message != "Property 'X' is nil." {
+			t.Errorf("%s: want runtime error %q, got %v", source, "Property 'X' is nil.", err)
+		}
+	}
+}
+
+func TestGoObjectArguments(t *testing.T) {
+	lox, _ := newLox(t, TreeWalk)
+	cfg := &config{Name: "lox"}
+	rename := func(c *config, name string) *config {
+		c.Name = name
+		return c
+	}
+	if err := lox.SetGlobal("rename", rename); err != nil {
+		t.Fatal(err)
+	}
+	got, err := lox.Call("rename", cfg, "golox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Interface() != cfg || cfg.Name != "golox" {
+		t.Errorf("want the same config renamed, got %v", got)
+	}
+	if err := lox.SetGlobal("cfg", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := lox.Run(`rename(cfg, "again"); rename(1, "x");`); err == nil || err.Error() != "[line 1] Argument 1 to rename must be a *golox.config." {
+		t.Errorf("want argument error, got %v", err)
+	}
+	if cfg.Name != "again" {
+		t.Errorf("want config renamed from Lox, got %v", cfg.Name)
+	}
+}
@@ -0,0 +1,211 @@
+package golox
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	ast "github.com/rhomel/golox/pkg/ast/gen"
+	"github.com/rhomel/golox/pkg/interpreter"
+	"github.com/rhomel/golox/pkg/module"
+	"github.com/rhomel/golox/pkg/parser"
+	"github.com/rhomel/golox/pkg/resolver"
+	"github.com/rhomel/golox/pkg/scanner"
+)
+
+type treeWalk struct {
+	errors      errorCollector
+	interpreter *interpreter.TreeWalkInterpreter
+	modulePath  []string
+}
+
+var _ engine = (*treeWalk)(nil)
+var _ interpreter.ModuleLoader = (*treeWalk)(nil)
+
+// newTreeWalk returns the TreeWalk engine or, if compile is set, the Closure
+// engine.
+func newTreeWalk(opts Options, compile bool) *treeWalk {
+	t := &treeWalk{modulePath: opts.ModulePath}
+	if compile {
+		t.interpreter = interpreter.NewClosureInterpreter(t)
+	} else {
+		t.interpreter = interpreter.NewTreeWalkInterpreter(t)
+	}
+	t.interpreter.SetStdout(opts.Stdout)
+	t.interpreter.SetMaxCallDepth(opts.MaxCallDepth)
+	t.interpreter.SetModuleLoader(t)
+	return t
+}
+
+func (t *treeWalk) run(file, source string) error {
+	t.errors.reset()
+	t.interpreter.SetFile(file)
+	tokens := scanner.NewScanner(source, t).ScanTokens()
+	statements := parser.NewParser(tokens, t).Parse()
+	if t.errors.hadSyntaxError() {
+		return t.errors.err()
+	}
+	resolver.NewResolver(t.interpreter, t).ResolveStmts(statements)
+	if t.errors.hadSyntaxError() {
+		return t.errors.err()
+	}
+	t.interpreter.Interpret(statements)
+	return t.errors.err()
+}
+
+func (t *treeWalk) eval(expression string) (Value, error) {
+	t.errors.reset()
+	tokens := scanner.NewScanner(expression, t).ScanTokens()
+	expr := parser.NewParser(tokens, t).ParseExpression()
+	if t.errors.hadSyntaxError() {
+		return interpreter.Nil, t.errors.err()
+	}
+	resolver.NewResolver(t.interpreter, t).ResolveExpr(expr)
+	if t.errors.hadSyntaxError() {
+		return interpreter.Nil, t.errors.err()
+	}
+	value := t.interpreter.Evaluate(expr)
+	if err := t.errors.err(); err != nil {
+		return interpreter.Nil, err
+	}
+	return value, nil
+}
+
+func (t *treeWalk) call(name string, args []interface{}) (Value, error) {
+	t.errors.reset()
+	values := make([]Value, len(args))
+	for i, arg := range args {
+		value, err := toInterpreter(name, arg)
+		if err != nil {
+			return interpreter.Nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		values[i] = value
+	}
+	callee, ok := t.interpreter.GetGlobal(name)
+	if !ok {
+		return interpreter.Nil, fmt.Errorf("undefined function '%s'", name)
+	}
+	function := callee.AsCallable()
+	if function == nil {
+		return interpreter.Nil, fmt.Errorf("'%s' is not a function or class", name)
+	}
+	if err := interpreter.CheckArity(function, len(values)); err != nil {
+		return interpreter.Nil, err
+	}
+	result := t.interpreter.CallFunction(function, values)
+	if err := t.errors.err(); err != nil {
+		return interpreter.Nil, err
+	}
+	return result, nil
+}
+
+func (t *treeWalk) setGlobal(name string, value interface{}) error {
+	v, err := toInterpreter(name, value)
+	if err != nil {
+		return err
+	}
+	t.interpreter.DefineGlobal(name, v)
+	return nil
+}
+
+func (t *treeWalk) getGlobal(name string) (Value, bool) {
+	return t.interpreter.GetGlobal(name)
+}
+
+// toInterpreter binds Go functions and wraps structs and maps. Values
+// normalized by toLox are passed through.
+func toInterpreter(name string, value interface{}) (Value, error) {
+	switch v := value.(type) {
+	case Value:
+		return v, nil
+	case *hiddenMembers:
+		object, err := interpreter.NewGoObject(v.value, v.members...)
+		if err != nil {
+			return interpreter.Nil, err
+		}
+		return interpreter.Object(object), nil
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Func:
+		native, err := interpreter.NewNativeFunction(name, value)
+		if err != nil {
+			return interpreter.Nil, err
+		}
+		return interpreter.Callable(native), nil
+	case reflect.Struct, reflect.Ptr, reflect.Map:
+		object, err := interpreter.NewGoObject(value)
+		if err != nil {
+			return interpreter.Nil, err
+		}
+		return interpreter.Object(object), nil
+	}
+	return interpreter.Nil, fmt.Errorf("unsupported value type %T", value)
+}
+
+func (t *treeWalk) FindModule(from, path string) (string, bool) {
+	return module.Find(from, path, t.modulePath)
+}
+
+func (t *treeWalk) LoadModule(file string) ([]ast.Stmt, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	errors := &moduleErrors{}
+	tokens := scanner.NewScanner(string(b), errors).ScanTokens()
+	statements := parser.NewParser(tokens, errors).Parse()
+	if errors.hadSyntaxError() {
+		return nil, errors.syntaxErrors[0]
+	}
+	resolver.NewResolver(t.interpreter, errors).ResolveStmts(statements)
+	if errors.hadSyntaxError() {
+		return nil, errors.syntaxErrors[0]
+	}
+	return statements, nil
+}
+
+// moduleErrors collects the syntax errors of a module separately from those of
+// the program importing it.
+type moduleErrors struct {
+	errorCollector
+}
+
+func (m *moduleErrors) Error(line int, message string) {
+	m.syntaxError(line, "", message)
+}
+
+func (m *moduleErrors) ParseError(token scanner.Token, message string) {
+	m.syntaxError(token.Line, where(token), message)
+}
+
+func (m *moduleErrors) ResolveError(token scanner.Token, message string) {
+	m.ParseError(token, message)
+}
+
+func (t *treeWalk) Error(line int, message string) {
+	t.errors.syntaxError(line, "", message)
+}
+
+func (t *treeWalk) ParseError(token scanner.Token, message string) {
+	t.errors.syntaxError(token.Line, where(token), message)
+}
+
+// where describes where a syntax error is in the format of SyntaxError.Where.
+func where(token scanner.Token) string {
+	if token.Typ == scanner.EOF {
+		return " at end"
+	}
+	return " at '" + token.Lexeme + "'"
+}
+
+func (t *treeWalk) ResolveError(token scanner.Token, message string) {
+	t.ParseError(token, message)
+}
+
+func (t *treeWalk) RuntimeError(token scanner.Token, message string, trace []interpreter.StackFrame) {
+	frames := make([]StackFrame, len(trace))
+	for i, frame := range trace {
+		frames[i] = StackFrame(frame)
+	}
+	t.errors.runtimeError = &RuntimeError{token.Line, message, frames}
+}
@@ -0,0 +1,95 @@
+package golox
+
+import (
+	"fmt"
+
+	"github.com/rhomel/golox/pkg/interpreter"
+	"github.com/rhomel/golox/pkg/vm"
+)
+
+type bytecodeVM struct {
+	errors  errorCollector
+	session *vm.Session
+}
+
+var _ engine = (*bytecodeVM)(nil)
+
+func newVM(opts Options) *bytecodeVM {
+	b := &bytecodeVM{}
+	b.session = vm.NewSession(opts.Stdout, b)
+	b.session.SetModulePath(opts.ModulePath)
+	return b
+}
+
+func (b *bytecodeVM) run(file, source string) error {
+	b.errors.reset()
+	b.session.InterpretFile(file, source)
+	return b.errors.err()
+}
+
+func (b *bytecodeVM) eval(expression string) (Value, error) {
+	b.errors.reset()
+	value, _ := b.session.Evaluate(expression)
+	if err := b.errors.err(); err != nil {
+		return interpreter.Nil, err
+	}
+	return fromVM(value), nil
+}
+
+func (b *bytecodeVM) call(name string, args []interface{}) (Value, error) {
+	if _, ok := b.session.GetGlobal(name); !ok {
+		return interpreter.Nil, fmt.Errorf("undefined function '%s'", name)
+	}
+	// the vm can't define functions yet so no global is callable
+	return interpreter.Nil, fmt.Errorf("'%s' is not a function or class", name)
+}
+
+func (b *bytecodeVM) setGlobal(name string, value interface{}) error {
+	if v, ok := value.(Value); ok {
+		value = v.Interface()
+	}
+	return b.session.SetGlobal(name, value)
+}
+
+func (b *bytecodeVM) getGlobal(name string) (Value, bool) {
+	value, ok := b.session.GetGlobal(name)
+	return fromVM(value), ok
+}
+
+// fromVM converts the nil, booleans, numbers, strings and lists returned by
+// the vm.
+func fromVM(value interface{}) Value {
+	return fromVMWithLists(value, make(map[*interface{}]*interpreter.LoxList))
+}
+
+// fromVMWithLists is fromVM for a value that may be in one of the lists being
+// converted. The vm converts a list that contains itself to a slice that
+// contains itself so lists are keyed by their first element.
+func fromVMWithLists(value interface{}, lists map[*interface{}]*interpreter.LoxList) Value {
+	elements, ok := value.([]interface{})
+	if !ok {
+		v, _ := interpreter.ValueOf(value)
+		return v
+	}
+	if len(elements) == 0 {
+		return interpreter.List(interpreter.NewLoxList(nil))
+	}
+	if list, ok := lists[&elements[0]]; ok {
+		return interpreter.List(list)
+	}
+	list := interpreter.NewLoxList(make([]Value, len(elements)))
+	lists[&elements[0]] = list
+	for i, element := range elements {
+		list.Elements()[i] = fromVMWithLists(element, lists)
+	}
+	return interpreter.List(list)
+}
+
+func (b *bytecodeVM) CompileError(line int, where, message string) {
+	b.errors.syntaxError(line, where, message)
+}
+
+func (b *bytecodeVM) RuntimeError(line int, message string) {
+	// the vm doesn't have functions so errors are always in top level code
+	b.errors.runtimeError = &RuntimeError{line, message, []StackFrame{{Line: line}}}
+}
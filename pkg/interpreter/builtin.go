@@ -0,0 +1,71 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/rhomel/golox/pkg/scanner"
+)
+
+// builtinMethod is a method of a built-in type like a list.
+type builtinMethod struct {
+	arity int
+	call  func(this Value, arguments []Value) (Value, error)
+}
+
+// method returns the method called name bound to this.
+func method(methods map[string]builtinMethod, this Value, name scanner.Token) (Value, error) {
+	method, ok := methods[name.Lexeme]
+	if !ok {
+		return Nil, &RuntimeError{name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme)}
+	}
+	return Callable(&boundBuiltin{this, name.Lexeme, method}), nil
+}
+
+// boundBuiltin is a method of a built-in type bound to a value.
+type boundBuiltin struct {
+	this Value
+	name string
+	builtinMethod
+}
+
+var _ LoxCallable = (*boundBuiltin)(nil)
+
+func (m *boundBuiltin) Arity() int {
+	return m.arity
+}
+
+func (m *boundBuiltin) Call(in *TreeWalkInterpreter, arguments []Value) (Value, error) {
+	return m.call(m.this, arguments)
+}
+
+func (*boundBuiltin) String() string {
+	return "<native fn>"
+}
+
+// nativeLen is the len builtin. It returns the number of elements in a list,
+// entries in a map or characters in a string.
+type nativeLen struct{}
+
+var _ LoxCallable = (*nativeLen)(nil)
+
+func (*nativeLen) Arity() int {
+	return 1
+}
+
+func (*nativeLen) Call(in *TreeWalkInterpreter, arguments []Value) (Value, error) {
+	switch value := arguments[0]; value.kind {
+	case KindList:
+		return Number(float64(len(value.AsList().elements))), nil
+	case KindMap:
+		return Number(float64(len(value.AsMap().keys))), nil
+	case KindString:
+		return Number(float64(utf8.RuneCountInString(value.AsString()))), nil
+	}
+	return Nil, errors.New("Can only take the length of a list, map or string.")
+}
+
+func (*nativeLen) String() string {
+	return "<native fn>"
+}
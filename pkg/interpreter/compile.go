@@ -0,0 +1,857 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+
+	ast "github.com/rhomel/golox/pkg/ast/gen"
+	"github.com/rhomel/golox/pkg/scanner"
+	"github.com/rhomel/golox/pkg/util/check"
+)
+
+// The closure compiler converts the resolved tree into nested Go closures
+// once, before running it. Each closure does the work of one node and calls
+// the closures compiled for its children directly, so running a program no
+// longer dispatches on the node type or looks up resolved locals in a map.
+// Compiled code uses the same values, environments and runtime helpers as the
+// tree-walker.
+
+type (
+	evalFn func(in *TreeWalkInterpreter) (Value, error)
+	execFn func(in *TreeWalkInterpreter) (completion, error)
+)
+
+// NewClosureInterpreter returns an interpreter that compiles statements into
+// closures before running them. Otherwise it behaves exactly like the
+// tree-walker.
+func NewClosureInterpreter(reporter RuntimeErrorReporter) *TreeWalkInterpreter {
+	in := NewTreeWalkInterpreter(reporter)
+	in.compile = true
+	return in
+}
+
+type compiler struct {
+	locals map[ast.Expr]local
+	// scopeDepth is the number of enclosing blocks and functions. Variables
+	// declared outside of any are globals.
+	scopeDepth int
+}
+
+func newCompiler(in *TreeWalkInterpreter) *compiler {
+	return &compiler{locals: in.locals}
+}
+
+// statements compiles a list of statements that run in the current
+// environment until one of them completes abruptly.
+func (c *compiler) statements(stmts []ast.Stmt) execFn {
+	compiled := make([]execFn, len(stmts))
+	for i, stmt := range stmts {
+		compiled[i] = c.statement(stmt)
+	}
+	return func(in *TreeWalkInterpreter) (completion, error) {
+		for _, stmt := range compiled {
+			result, err := stmt(in)
+			if err != nil || result.kind != completeNormal {
+				return result, err
+			}
+		}
+		return normal, nil
+	}
+}
+
+func (c *compiler) statement(stmt ast.Stmt) execFn {
+	switch v := stmt.(type) {
+	case *ast.Class:
+		return c.class(v)
+	case *ast.IfStmt:
+		return c.ifStmt(v)
+	case *ast.ImportStmt:
+		define := c.define(v.Name)
+		return func(in *TreeWalkInterpreter) (completion, error) {
+			module, err := in.importModule(v.Path)
+			if err != nil {
+				return normal, err
+			}
+			define(in, Module(module))
+			return normal, nil
+		}
+	case *ast.Block:
+		return c.block(v)
+	case *ast.BreakStmt:
+		return func(in *TreeWalkInterpreter) (completion, error) {
+			return breakLoop, nil
+		}
+	case *ast.ContinueStmt:
+		return func(in *TreeWalkInterpreter) (completion, error) {
+			return continueLoop, nil
+		}
+	case *ast.Expression:
+		expr := c.expression(v.Expression)
+		return func(in *TreeWalkInterpreter) (completion, error) {
+			_, err := expr(in)
+			return normal, err
+		}
+	case *ast.Function:
+		return c.function(v)
+	case *ast.Print:
+		expr := c.expression(v.Expression)
+		return func(in *TreeWalkInterpreter) (completion, error) {
+			value, err := expr(in)
+			if err != nil {
+				return normal, err
+			}
+			fmt.Fprintln(in.stdout, value.String())
+			return normal, nil
+		}
+	case *ast.ReturnStmt:
+		return c.returnStmt(v)
+	case *ast.Throw:
+		value := c.expression(v.Value)
+		return func(in *TreeWalkInterpreter) (completion, error) {
+			thrownValue, err := value(in)
+			if err != nil {
+				return normal, err
+			}
+			return normal, &thrown{v.Keyword, thrownValue, in.errorClass}
+		}
+	case *ast.Try:
+		return c.try(v)
+	case *ast.VarStmt:
+		return c.varStmt(v)
+	case *ast.While:
+		return c.while(v)
+	default:
+		panic(fmt.Sprintf("unsupported statement: %s", check.TypeOf(stmt)))
+	}
+}
+
+func (c *compiler) block(block *ast.Block) execFn {
+	c.scopeDepth++
+	body := c.statements(block.Statements)
+	c.scopeDepth--
+	return func(in *TreeWalkInterpreter) (completion, error) {
+		previous := in.environment
+		in.environment = NewEnvironment(previous)
+		result, err := body(in)
+		in.environment = previous
+		return result, err
+	}
+}
+
+// body compiles the body of a function. LoxFunction.Call runs it in the
+// environment holding the parameters.
+func (c *compiler) body(function *ast.Function) execFn {
+	c.scopeDepth++
+	body := c.statements(function.Body)
+	c.scopeDepth--
+	return body
+}
+
+func (c *compiler) function(stmt *ast.Function) execFn {
+	body := c.body(stmt)
+	define := c.define(stmt.Name)
+	return func(in *TreeWalkInterpreter) (completion, error) {
+		function := in.newFunction(stmt, false)
+		function.body = body
+		define(in, Callable(function))
+		return normal, nil
+	}
+}
+
+func (c *compiler) class(class *ast.Class) execFn {
+	var superclass evalFn
+	if class.Superclass != nil {
+		superclass = c.expression(class.Superclass)
+	}
+	bodies := make(map[*ast.Function]execFn)
+	for _, method := range class.Methods {
+		bodies[method] = c.body(method)
+	}
+	return func(in *TreeWalkInterpreter) (completion, error) {
+		var value Value
+		if superclass != nil {
+			var err error
+			if value, err = superclass(in); err != nil {
+				return normal, err
+			}
+		}
+		return normal, in.defineClass(class, value, bodies)
+	}
+}
+
+// define returns a function that defines a variable called name in the
+// current scope.
+func (c *compiler) define(name scanner.Token) func(in *TreeWalkInterpreter, value Value) {
+	if c.scopeDepth == 0 {
+		return func(in *TreeWalkInterpreter, value Value) {
+			in.globals.Define(name.Lexeme, value)
+		}
+	}
+	return func(in *TreeWalkInterpreter, value Value) {
+		in.environment.Define(value)
+	}
+}
+
+func (c *compiler) varStmt(stmt *ast.VarStmt) execFn {
+	define := c.define(stmt.Name)
+	if stmt.Initializer == nil {
+		return func(in *TreeWalkInterpreter) (completion, error) {
+			define(in, Nil)
+			return normal, nil
+		}
+	}
+	initializer := c.expression(stmt.Initializer)
+	return func(in *TreeWalkInterpreter) (completion, error) {
+		value, err := initializer(in)
+		if err != nil {
+			return normal, err
+		}
+		define(in, value)
+		return normal, nil
+	}
+}
+
+func (c *compiler) ifStmt(stmt *ast.IfStmt) execFn {
+	condition := c.expression(stmt.Condition)
+	thenBranch := c.statement(stmt.ThenBranch)
+	elseBranch := func(in *TreeWalkInterpreter) (completion, error) {
+		return normal, nil
+	}
+	if stmt.ElseBranch != nil {
+		elseBranch = c.statement(stmt.ElseBranch)
+	}
+	return func(in *TreeWalkInterpreter) (completion, error) {
+		value, err := condition(in)
+		if err != nil {
+			return normal, err
+		}
+		if value.Truthy() {
+			return thenBranch(in)
+		}
+		return elseBranch(in)
+	}
+}
+
+func (c *compiler) returnStmt(stmt *ast.ReturnStmt) execFn {
+	if stmt.Value == nil {
+		return func(in *TreeWalkInterpreter) (completion, error) {
+			return completion{kind: completeReturn}, nil
+		}
+	}
+	value := c.expression(stmt.Value)
+	return func(in *TreeWalkInterpreter) (completion, error) {
+		result, err := value(in)
+		if err != nil {
+			return normal, err
+		}
+		return completion{completeReturn, result}, nil
+	}
+}
+
+func (c *compiler) try(stmt *ast.Try) execFn {
+	body := c.statement(stmt.Body)
+	var catch, finally execFn
+	if stmt.Catch != nil {
+		c.scopeDepth++
+		catch = c.statements(stmt.Catch.Body)
+		c.scopeDepth--
+	}
+	if stmt.Finally != nil {
+		finally = c.statement(stmt.Finally)
+	}
+	return func(in *TreeWalkInterpreter) (completion, error) {
+		environment := in.environment
+		result, err := body(in)
+		if err != nil && catch != nil {
+			if value, ok := in.caught(err); ok {
+				in.environment = NewEnvironment(environment)
+				in.environment.Define(value)
+				result, err = catch(in)
+				in.environment = environment
+			}
+		}
+		if finally == nil {
+			return result, err
+		}
+		in.environment = environment
+		return in.finish(func() (completion, error) {
+			return finally(in)
+		}, result, err)
+	}
+}
+
+func (c *compiler) while(while *ast.While) execFn {
+	condition := c.expression(while.Condition)
+	body := c.statement(while.Body)
+	increment := func(in *TreeWalkInterpreter) (Value, error) {
+		return Nil, nil
+	}
+	if while.Increment != nil {
+		increment = c.expression(while.Increment)
+	}
+	return func(in *TreeWalkInterpreter) (completion, error) {
+		for {
+			value, err := condition(in)
+			if err != nil {
+				return normal, err
+			}
+			if !value.Truthy() {
+				return normal, nil
+			}
+			result, err := body(in)
+			if err != nil {
+				return normal, err
+			}
+			switch result.kind {
+			case completeBreak:
+				return normal, nil
+			case completeReturn:
+				return result, nil
+			}
+			if _, err := increment(in); err != nil {
+				return normal, err
+			}
+			if err := in.checkInterrupt(while.Keyword); err != nil {
+				return normal, err
+			}
+		}
+	}
+}
+
+func (c *compiler) expression(expr ast.Expr) evalFn {
+	switch v := expr.(type) {
+	case *ast.Binary:
+		return c.binary(v)
+	case *ast.Call:
+		return c.call(v)
+	case *ast.Comma:
+		left := c.expression(v.Left)
+		right := c.expression(v.Right)
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			if _, err := left(in); err != nil {
+				return Nil, err
+			}
+			return right(in)
+		}
+	case *ast.Compound:
+		return c.compound(v)
+	case *ast.Conditional:
+		return c.conditional(v)
+	case *ast.Get:
+		return c.get(v)
+	case *ast.GetIndex:
+		return c.getIndex(v)
+	case *ast.Grouping:
+		return c.expression(v.Expression)
+	case *ast.Lambda:
+		body := c.body(v.Function)
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			function := in.newFunction(v.Function, false)
+			function.body = body
+			return Callable(function), nil
+		}
+	case *ast.List:
+		elements := c.arguments(v.Elements)
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			values, err := elements(in)
+			if err != nil {
+				return Nil, err
+			}
+			return List(NewLoxList(values)), nil
+		}
+	case *ast.Literal:
+		value := literal(v.Value)
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			return value, nil
+		}
+	case *ast.Logical:
+		return c.logical(v)
+	case *ast.Map:
+		return c.mapLiteral(v)
+	case *ast.Set:
+		return c.set(v)
+	case *ast.SetIndex:
+		return c.setIndex(v)
+	case *ast.Stringify:
+		expr := c.expression(v.Expression)
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			value, err := expr(in)
+			if err != nil {
+				return Nil, err
+			}
+			return String(value.String()), nil
+		}
+	case *ast.Super:
+		local, ok := c.locals[v]
+		if !ok {
+			return func(in *TreeWalkInterpreter) (Value, error) {
+				return Nil, &RuntimeError{v.Keyword, "no resolved local"}
+			}
+		}
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			object, method, err := in.superMethod(v, local)
+			if err != nil {
+				return Nil, err
+			}
+			return Callable(method.Bind(object)), nil
+		}
+	case *ast.This:
+		return c.variable(v.Keyword, v)
+	case *ast.Unary:
+		return c.unary(v)
+	case *ast.Variable:
+		return c.variable(v.Name, v)
+	case *ast.Assign:
+		return c.assign(v)
+	default:
+		panic(fmt.Sprintf("unsupported expression type: %s", check.TypeOf(expr)))
+	}
+}
+
+func (c *compiler) variable(name scanner.Token, expr ast.Expr) evalFn {
+	local, ok := c.locals[expr]
+	if !ok {
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			return in.globals.Get(name)
+		}
+	}
+	if local.depth == 0 {
+		slot := local.slot
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			return in.environment.values[slot], nil
+		}
+	}
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		return in.environment.GetAt(local.depth, local.slot), nil
+	}
+}
+
+func (c *compiler) assign(assign *ast.Assign) evalFn {
+	value := c.expression(assign.Value)
+	local, ok := c.locals[assign]
+	if !ok {
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			result, err := value(in)
+			if err != nil {
+				return Nil, err
+			}
+			return result, in.globals.Assign(assign.Name, result)
+		}
+	}
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		result, err := value(in)
+		if err != nil {
+			return Nil, err
+		}
+		in.environment.AssignAt(local.depth, local.slot, result)
+		return result, nil
+	}
+}
+
+func (c *compiler) binary(binary *ast.Binary) evalFn {
+	left := c.expression(binary.Left)
+	right := c.expression(binary.Right)
+	operator := binary.Operator
+	operands := func(in *TreeWalkInterpreter) (Value, Value, error) {
+		a, err := left(in)
+		if err != nil {
+			return Nil, Nil, err
+		}
+		b, err := right(in)
+		return a, b, err
+	}
+	numbers := func(in *TreeWalkInterpreter) (float64, float64, error) {
+		a, b, err := operands(in)
+		if err != nil {
+			return 0, 0, err
+		}
+		return numberOperands(operator, a, b)
+	}
+
+	switch operator.Typ {
+	case scanner.BANG_EQUAL:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := operands(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Bool(!a.Equal(b)), nil
+		}
+	case scanner.EQUAL_EQUAL:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := operands(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Bool(a.Equal(b)), nil
+		}
+	case scanner.PLUS:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := operands(in)
+			if err != nil {
+				return Nil, err
+			}
+			return add(operator, a, b)
+		}
+	case scanner.GREATER:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Bool(a > b), nil
+		}
+	case scanner.GREATER_EQUAL:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Bool(a >= b), nil
+		}
+	case scanner.LESS:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Bool(a < b), nil
+		}
+	case scanner.LESS_EQUAL:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Bool(a <= b), nil
+		}
+	case scanner.MINUS:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Number(a - b), nil
+		}
+	case scanner.SLASH:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Number(a / b), nil
+		}
+	case scanner.STAR:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Number(a * b), nil
+		}
+	case scanner.PERCENT:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Number(math.Mod(a, b)), nil
+		}
+	case scanner.STAR_STAR:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Number(math.Pow(a, b)), nil
+		}
+	case scanner.TILDE_SLASH:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Number(math.Trunc(a / b)), nil
+		}
+	case scanner.AMPERSAND, scanner.PIPE, scanner.CARET, scanner.LESS_LESS, scanner.GREATER_GREATER:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			a, b, err := numbers(in)
+			if err != nil {
+				return Nil, err
+			}
+			return bitwise(operator, a, b)
+		}
+	}
+	panic(fmt.Sprintf("unsupported binary operator: %s", operator.Typ))
+}
+
+func (c *compiler) unary(unary *ast.Unary) evalFn {
+	right := c.expression(unary.Right)
+	switch unary.Operator.Typ {
+	case scanner.MINUS:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			value, err := right(in)
+			if err != nil {
+				return Nil, err
+			}
+			if value.kind != KindNumber {
+				return Nil, &RuntimeError{unary.Operator, "Operand must be a number."}
+			}
+			return Number(-value.num), nil
+		}
+	case scanner.TILDE:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			value, err := right(in)
+			if err != nil {
+				return Nil, err
+			}
+			return complement(unary.Operator, value)
+		}
+	case scanner.BANG:
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			value, err := right(in)
+			if err != nil {
+				return Nil, err
+			}
+			return Bool(!value.Truthy()), nil
+		}
+	}
+	panic(fmt.Sprintf("unsupported unary operator: %s", unary.Operator.Typ))
+}
+
+func (c *compiler) logical(logical *ast.Logical) evalFn {
+	left := c.expression(logical.Left)
+	right := c.expression(logical.Right)
+	var isOr bool
+	switch logical.Operator.Typ {
+	case scanner.OR:
+		isOr = true
+	case scanner.AND:
+	default:
+		panic(fmt.Sprintf("unsupported logical operator: %s", logical.Operator.Typ))
+	}
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		value, err := left(in)
+		if err != nil {
+			return Nil, err
+		}
+		if value.Truthy() == isOr {
+			return value, nil
+		}
+		return right(in)
+	}
+}
+
+func (c *compiler) conditional(conditional *ast.Conditional) evalFn {
+	condition := c.expression(conditional.Condition)
+	then := c.expression(conditional.Then)
+	otherwise := c.expression(conditional.Else)
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		value, err := condition(in)
+		if err != nil {
+			return Nil, err
+		}
+		if value.Truthy() {
+			return then(in)
+		}
+		return otherwise(in)
+	}
+}
+
+func (c *compiler) get(get *ast.Get) evalFn {
+	object := c.expression(get.Object)
+	var cache propertyCache
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		value, err := object(in)
+		if err != nil {
+			return Nil, err
+		}
+		instance := value.AsInstance()
+		if instance == nil {
+			return getProperty(get.Name, value)
+		}
+		field, method, err := cache.property(instance, get.Name)
+		if method != nil {
+			return Callable(method.Bind(instance)), nil
+		}
+		return field, err
+	}
+}
+
+func (c *compiler) set(set *ast.Set) evalFn {
+	object := c.expression(set.Object)
+	value := c.expression(set.Value)
+	var cache propertyCache
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		target, err := object(in)
+		if err != nil {
+			return Nil, err
+		}
+		if err := checkFields(set.Name, target); err != nil {
+			return Nil, err
+		}
+		result, err := value(in)
+		if err != nil {
+			return Nil, err
+		}
+		if instance := target.AsInstance(); instance != nil {
+			cache.set(instance, set.Name, result)
+			return result, nil
+		}
+		return result, setProperty(set.Name, target, result)
+	}
+}
+
+func (c *compiler) mapLiteral(expr *ast.Map) evalFn {
+	keys := make([]evalFn, len(expr.Keys))
+	values := make([]evalFn, len(expr.Values))
+	for i := range expr.Keys {
+		keys[i] = c.expression(expr.Keys[i])
+		values[i] = c.expression(expr.Values[i])
+	}
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		m := NewLoxMap()
+		for i, key := range keys {
+			k, err := key(in)
+			if err != nil {
+				return Nil, err
+			}
+			v, err := values[i](in)
+			if err != nil {
+				return Nil, err
+			}
+			if err := m.Set(k, v); err != nil {
+				return Nil, &RuntimeError{expr.Brace, err.Error()}
+			}
+		}
+		return Map(m), nil
+	}
+}
+
+func (c *compiler) getIndex(index *ast.GetIndex) evalFn {
+	object := c.expression(index.Object)
+	i := c.expression(index.Index)
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		list, err := object(in)
+		if err != nil {
+			return Nil, err
+		}
+		position, err := i(in)
+		if err != nil {
+			return Nil, err
+		}
+		return getIndex(index.Bracket, list, position)
+	}
+}
+
+func (c *compiler) setIndex(index *ast.SetIndex) evalFn {
+	object := c.expression(index.Object)
+	i := c.expression(index.Index)
+	value := c.expression(index.Value)
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		list, err := object(in)
+		if err != nil {
+			return Nil, err
+		}
+		position, err := i(in)
+		if err != nil {
+			return Nil, err
+		}
+		result, err := value(in)
+		if err != nil {
+			return Nil, err
+		}
+		return result, setIndex(index.Bracket, list, position, result)
+	}
+}
+
+func (c *compiler) call(call *ast.Call) evalFn {
+	arguments := c.arguments(call.Arguments)
+	switch callee := call.Callee.(type) {
+	case *ast.Get:
+		return c.methodCall(call, callee, arguments)
+	case *ast.Super:
+		return c.superCall(call, callee, arguments)
+	}
+	callee := c.expression(call.Callee)
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		function, err := callee(in)
+		if err != nil {
+			return Nil, err
+		}
+		values, err := arguments(in)
+		if err != nil {
+			return Nil, err
+		}
+		return in.call(call.Paren, function, values)
+	}
+}
+
+// methodCall calls a method of an instance without binding it first, like
+// visitMethodCallExpr.
+func (c *compiler) methodCall(call *ast.Call, get *ast.Get, arguments func(*TreeWalkInterpreter) ([]Value, error)) evalFn {
+	object := c.expression(get.Object)
+	var cache propertyCache
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		value, err := object(in)
+		if err != nil {
+			return Nil, err
+		}
+		var callee Value
+		var method *LoxFunction
+		instance := value.AsInstance()
+		if instance != nil {
+			callee, method, err = cache.property(instance, get.Name)
+		} else {
+			callee, err = getProperty(get.Name, value)
+		}
+		if err != nil {
+			return Nil, err
+		}
+		values, err := arguments(in)
+		if err != nil {
+			return Nil, err
+		}
+		if method != nil {
+			return in.callMethod(call.Paren, instance, method, values)
+		}
+		return in.call(call.Paren, callee, values)
+	}
+}
+
+func (c *compiler) superCall(call *ast.Call, super *ast.Super, arguments func(*TreeWalkInterpreter) ([]Value, error)) evalFn {
+	local, ok := c.locals[super]
+	if !ok {
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			return Nil, &RuntimeError{super.Keyword, "no resolved local"}
+		}
+	}
+	return func(in *TreeWalkInterpreter) (Value, error) {
+		instance, method, err := in.superMethod(super, local)
+		if err != nil {
+			return Nil, err
+		}
+		values, err := arguments(in)
+		if err != nil {
+			return Nil, err
+		}
+		return in.callMethod(call.Paren, instance, method, values)
+	}
+}
+
+func (c *compiler) arguments(exprs []ast.Expr) func(*TreeWalkInterpreter) ([]Value, error) {
+	arguments := make([]evalFn, len(exprs))
+	for i, argument := range exprs {
+		arguments[i] = c.expression(argument)
+	}
+	return func(in *TreeWalkInterpreter) ([]Value, error) {
+		values := make([]Value, len(arguments))
+		for i, argument := range arguments {
+			var err error
+			if values[i], err = argument(in); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+}
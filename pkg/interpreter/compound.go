@@ -0,0 +1,160 @@
+package interpreter
+
+import (
+	"fmt"
+
+	ast "github.com/rhomel/golox/pkg/ast/gen"
+	"github.com/rhomel/golox/pkg/util/check"
+)
+
+// Compound assignments like "x += 1" and increments and decrements are not
+// part of the book. The object and index of the target are evaluated once,
+// then the old value is read, the operand is evaluated and the result of the
+// operator is stored.
+
+// updated applies the operator of compound to the old value of its target and
+// the operand. It returns the value to store in the target and the value of
+// the expression, which is the old value for a postfix increment or decrement.
+func updated(compound *ast.Compound, old, operand Value) (stored Value, result Value, err error) {
+	stored, err = binaryOperation(compound.Operator, old, operand)
+	if err != nil {
+		return Nil, Nil, err
+	}
+	if compound.Postfix {
+		return stored, old, nil
+	}
+	return stored, stored, nil
+}
+
+func (in *TreeWalkInterpreter) visitCompoundExpr(compound *ast.Compound) (Value, error) {
+	switch target := compound.Target.(type) {
+	case *ast.Variable:
+		old, err := in.lookUpVariable(target.Name, target)
+		if err != nil {
+			return Nil, err
+		}
+		stored, result, err := in.update(compound, old)
+		if err != nil {
+			return Nil, err
+		}
+		return result, in.assignVariable(target.Name, target, stored)
+	case *ast.Get:
+		object, err := in.evaluate(target.Object)
+		if err != nil {
+			return Nil, err
+		}
+		if err := checkFields(target.Name, object); err != nil {
+			return Nil, err
+		}
+		old, err := getProperty(target.Name, object)
+		if err != nil {
+			return Nil, err
+		}
+		stored, result, err := in.update(compound, old)
+		if err != nil {
+			return Nil, err
+		}
+		return result, setProperty(target.Name, object, stored)
+	case *ast.GetIndex:
+		object, err := in.evaluate(target.Object)
+		if err != nil {
+			return Nil, err
+		}
+		index, err := in.evaluate(target.Index)
+		if err != nil {
+			return Nil, err
+		}
+		old, err := getIndex(target.Bracket, object, index)
+		if err != nil {
+			return Nil, err
+		}
+		stored, result, err := in.update(compound, old)
+		if err != nil {
+			return Nil, err
+		}
+		return result, setIndex(target.Bracket, object, index, stored)
+	}
+	panic(fmt.Sprintf("unsupported compound assignment target: %s", check.TypeOf(compound.Target)))
+}
+
+func (in *TreeWalkInterpreter) update(compound *ast.Compound, old Value) (Value, Value, error) {
+	operand, err := in.evaluate(compound.Value)
+	if err != nil {
+		return Nil, Nil, err
+	}
+	return updated(compound, old, operand)
+}
+
+func (c *compiler) compound(compound *ast.Compound) evalFn {
+	value := c.expression(compound.Value)
+	update := func(in *TreeWalkInterpreter, old Value) (Value, Value, error) {
+		operand, err := value(in)
+		if err != nil {
+			return Nil, Nil, err
+		}
+		return updated(compound, old, operand)
+	}
+	switch target := compound.Target.(type) {
+	case *ast.Variable:
+		get := c.variable(target.Name, target)
+		local, isLocal := c.locals[target]
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			old, err := get(in)
+			if err != nil {
+				return Nil, err
+			}
+			stored, result, err := update(in, old)
+			if err != nil {
+				return Nil, err
+			}
+			if isLocal {
+				in.environment.AssignAt(local.depth, local.slot, stored)
+				return result, nil
+			}
+			return result, in.globals.Assign(target.Name, stored)
+		}
+	case *ast.Get:
+		object := c.expression(target.Object)
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			instance, err := object(in)
+			if err != nil {
+				return Nil, err
+			}
+			if err := checkFields(target.Name, instance); err != nil {
+				return Nil, err
+			}
+			old, err := getProperty(target.Name, instance)
+			if err != nil {
+				return Nil, err
+			}
+			stored, result, err := update(in, old)
+			if err != nil {
+				return Nil, err
+			}
+			return result, setProperty(target.Name, instance, stored)
+		}
+	case *ast.GetIndex:
+		object := c.expression(target.Object)
+		i := c.expression(target.Index)
+		return func(in *TreeWalkInterpreter) (Value, error) {
+			list, err := object(in)
+			if err != nil {
+				return Nil, err
+			}
+			position, err := i(in)
+			if err != nil {
+				return Nil, err
+			}
+			old, err := getIndex(target.Bracket, list, position)
+			if err != nil {
+				return Nil, err
+			}
+			stored, result, err := update(in, old)
+			if err != nil {
+				return Nil, err
+			}
+			return result, setIndex(target.Bracket, list, position, stored)
+		}
+	}
+	panic(fmt.Sprintf("unsupported compound assignment target: %s", check.TypeOf(compound.Target)))
+}
@@ -6,37 +6,34 @@ import (
 	"github.com/rhomel/golox/pkg/scanner"
 )
 
+// Environment holds the local variables of one block or function call. The
+// resolver gives each local a slot in declaration order and the interpreter
+// defines them in the same order, so a local is found by walking out a known
+// number of environments and indexing its slot. Globals aren't resolved and
+// live in Globals instead.
 type Environment struct {
-	values    map[string]interface{}
+	values    []Value
 	enclosing *Environment
 }
 
 func NewEnvironment(enclosing *Environment) *Environment {
 	return &Environment{
-		values:    make(map[string]interface{}),
 		enclosing: enclosing,
 	}
 }
 
-func (e *Environment) Define(name string, value interface{}) {
-	e.values[name] = value
+// Define appends a local to the next slot and returns the slot.
+func (e *Environment) Define(value Value) int {
+	e.values = append(e.values, value)
+	return len(e.values) - 1
 }
 
-func (e *Environment) Get(name scanner.Token) interface{} {
-	if value, ok := e.values[name.Lexeme]; ok {
-		return value
-	}
-	if e.enclosing != nil {
-		return e.enclosing.Get(name)
-	}
-	panic(&RuntimeError{name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)})
+func (e *Environment) GetAt(distance, slot int) Value {
+	return e.ancestor(distance).values[slot]
 }
 
-func (e *Environment) GetAt(distance int, name string) interface{} {
-	if value, ok := e.ancestor(distance).values[name]; ok {
-		return value
-	}
-	panic(fmt.Errorf("Resolution data error: undefined ancestor at distance %d, name '%s'", distance, name))
+func (e *Environment) AssignAt(distance, slot int, value Value) {
+	e.ancestor(distance).values[slot] = value
 }
 
 func (e *Environment) ancestor(distance int) *Environment {
@@ -47,18 +44,37 @@ func (e *Environment) ancestor(distance int) *Environment {
 	return environment
 }
 
-func (e *Environment) Assign(name scanner.Token, value interface{}) {
-	if _, ok := e.values[name.Lexeme]; ok {
-		e.values[name.Lexeme] = value
-		return
+// Globals holds the global variables by name. Unlike locals they can be
+// referenced before they are defined, for example in the body of a function.
+type Globals struct {
+	values map[string]Value
+}
+
+func NewGlobals() *Globals {
+	return &Globals{
+		values: make(map[string]Value),
+	}
+}
+
+func (g *Globals) Define(name string, value Value) {
+	g.values[name] = value
+}
+
+func (g *Globals) Get(name scanner.Token) (Value, error) {
+	if value, ok := g.values[name.Lexeme]; ok {
+		return value, nil
 	}
-	if e.enclosing != nil {
-		e.enclosing.Assign(name, value)
-		return
+	return Nil, undefinedVariable(name)
+}
+
+func (g *Globals) Assign(name scanner.Token, value Value) error {
+	if _, ok := g.values[name.Lexeme]; ok {
+		g.values[name.Lexeme] = value
+		return nil
 	}
-	panic(&RuntimeError{name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)})
+	return undefinedVariable(name)
 }
 
-func (e *Environment) AssignAt(distance int, name scanner.Token, value interface{}) {
-	e.ancestor(distance).values[name.Lexeme] = value
+func undefinedVariable(name scanner.Token) *RuntimeError {
+	return &RuntimeError{name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)}
 }
@@ -0,0 +1,87 @@
+package interpreter
+
+import (
+	"sync/atomic"
+
+	"github.com/rhomel/golox/pkg/scanner"
+)
+
+// newErrorClass returns the class of the error objects that a catch clause
+// receives for runtime errors. It has no methods; an error object has a message
+// field and the line the error happened on. Each interpreter has its own class
+// because adding fields to instances updates the shapes of their class.
+func newErrorClass() *LoxClass {
+	return NewLoxClass("Error", nil, nil)
+}
+
+func (in *TreeWalkInterpreter) newErrorObject(message string, line int) Value {
+	instance := NewLoxInstance(in.errorClass)
+	instance.Set(scanner.Token{Lexeme: "message"}, String(message))
+	instance.Set(scanner.Token{Lexeme: "line"}, Number(float64(line)))
+	return Instance(instance)
+}
+
+// thrown is the error a throw statement unwinds the stack with.
+type thrown struct {
+	token      scanner.Token
+	value      Value
+	errorClass *LoxClass // the error class of the interpreter that threw value
+}
+
+// Error returns the message an uncaught throw is reported with: the message of
+// a thrown error object or else the thrown value.
+func (t *thrown) Error() string {
+	if instance := t.value.AsInstance(); instance != nil && instance.class == t.errorClass {
+		if slot, ok := instance.shape.slots["message"]; ok {
+			return instance.fields[slot].String()
+		}
+	}
+	return t.value.String()
+}
+
+// errorToken returns the token that err was raised at if err is a Lox runtime
+// error or throw.
+func errorToken(err error) (scanner.Token, bool) {
+	switch err := err.(type) {
+	case *RuntimeError:
+		return err.token, true
+	case *thrown:
+		return err.token, true
+	}
+	return scanner.Token{}, false
+}
+
+// caught returns the value a catch clause receives for err: the thrown value
+// or an error object for a runtime error. An interrupt can't be caught so that
+// it always stops the program.
+func (in *TreeWalkInterpreter) caught(err error) (Value, bool) {
+	if atomic.LoadInt32(&in.interrupted) != 0 {
+		return Nil, false
+	}
+	var value Value
+	switch err := err.(type) {
+	case *RuntimeError:
+		value = in.newErrorObject(err.message, err.token.Line)
+	case *thrown:
+		value = err.value
+	default:
+		return Nil, false
+	}
+	// the error is handled so forget the trace recorded for it
+	in.trace = nil
+	return value, true
+}
+
+// finish runs the finally clause of a try statement that completed with result
+// and err. A finally clause that completes abruptly replaces the completion of
+// the try statement.
+func (in *TreeWalkInterpreter) finish(finally func() (completion, error), result completion, err error) (completion, error) {
+	trace := in.trace
+	in.trace = nil
+	finallyResult, finallyErr := finally()
+	if finallyErr != nil || finallyResult.kind != completeNormal {
+		return finallyResult, finallyErr
+	}
+	in.trace = trace
+	return result, err
+}
@@ -0,0 +1,27 @@
+package interpreter
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestErrorObjectsPerInterpreter(t *testing.T) {
+	// interpreters run concurrently so they must not share the shapes that
+	// error objects add fields to (go test -race)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			in := NewTreeWalkInterpreter(nil)
+			err := &thrown{value: in.newErrorObject("boom", 1), errorClass: in.errorClass}
+			if got := err.Error(); got != "boom" {
+				t.Errorf("want message boom, got %q", got)
+			}
+		}()
+	}
+	wg.Wait()
+	if a, b := NewTreeWalkInterpreter(nil), NewTreeWalkInterpreter(nil); a.errorClass == b.errorClass {
+		t.Error("want each interpreter to have its own error class")
+	}
+}
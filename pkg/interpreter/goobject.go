@@ -0,0 +1,184 @@
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/rhomel/golox/pkg/scanner"
+)
+
+// GoObject exposes a Go struct or a map with string keys to Lox as an object.
+// Lox can read and write exported fields and map entries with `obj.name` and
+// call exported methods with `obj.method()`. Values are converted the same
+// way as NativeFunction arguments and results; nested structs and maps are
+// exposed as GoObjects.
+//
+// Fields can only be written if the struct is reached through a pointer.
+// Unexported members, fields tagged `lox:"-"` and hidden member names are
+// inaccessible.
+type GoObject struct {
+	value  reflect.Value
+	hidden map[string]bool
+}
+
+// NewGoObject returns an error unless value is a struct, a pointer to a struct
+// or a map with string keys. hidden lists member names that Lox can't access,
+// in this object or any object reached through it.
+func NewGoObject(value interface{}, hidden ...string) (*GoObject, error) {
+	hiddenSet := make(map[string]bool)
+	for _, name := range hidden {
+		hiddenSet[name] = true
+	}
+	object := newGoObject(reflect.ValueOf(value), hiddenSet)
+	if object == nil {
+		return nil, fmt.Errorf("%T is not a struct, pointer to a struct or map with string keys", value)
+	}
+	return object, nil
+}
+
+func newGoObject(v reflect.Value, hidden map[string]bool) *GoObject {
+	if !isGoObject(v.Type()) || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Map) && v.IsNil() {
+		return nil
+	}
+	return &GoObject{v, hidden}
+}
+
+func isGoObject(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Ptr:
+		return typ.Elem().Kind() == reflect.Struct
+	case reflect.Map:
+		return typ.Key().Kind() == reflect.String
+	}
+	return false
+}
+
+// Value returns the wrapped Go value.
+func (o *GoObject) Value() interface{} {
+	return o.value.Interface()
+}
+
+func (o *GoObject) Get(name scanner.Token) (Value, error) {
+	if o.hidden[name.Lexeme] {
+		return Nil, undefinedProperty(name)
+	}
+	target := reflect.Indirect(o.value)
+	if target.Kind() == reflect.Map {
+		if entry := target.MapIndex(reflect.ValueOf(name.Lexeme).Convert(target.Type().Key())); entry.IsValid() {
+			return o.fromGo(name, entry)
+		}
+	} else {
+		field, ok, err := o.field(name)
+		if err != nil {
+			return Nil, err
+		}
+		if ok {
+			return o.fromGo(name, field)
+		}
+	}
+	if method := o.value.MethodByName(name.Lexeme); method.IsValid() {
+		native, err := NewNativeFunction(name.Lexeme, method.Interface())
+		if err != nil {
+			return Nil, &RuntimeError{name, fmt.Sprintf("Method '%s' can't be called from Lox.", name.Lexeme)}
+		}
+		native.hidden = o.hidden
+		return Callable(native), nil
+	}
+	return Nil, undefinedProperty(name)
+}
+
+func (o *GoObject) Set(name scanner.Token, value Value) error {
+	if o.hidden[name.Lexeme] {
+		return undefinedProperty(name)
+	}
+	target := reflect.Indirect(o.value)
+	if target.Kind() == reflect.Map {
+		entry, err := toGo(value, target.Type().Elem())
+		if err != nil {
+			return &RuntimeError{name, fmt.Sprintf("Property '%s' %s.", name.Lexeme, err)}
+		}
+		target.SetMapIndex(reflect.ValueOf(name.Lexeme).Convert(target.Type().Key()), entry)
+		return nil
+	}
+	field, ok, err := o.field(name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return undefinedProperty(name)
+	}
+	if !field.CanSet() || !isConvertible(field.Type()) {
+		return &RuntimeError{name, fmt.Sprintf("Property '%s' is read-only.", name.Lexeme)}
+	}
+	v, err := toGo(value, field.Type())
+	if err != nil {
+		return &RuntimeError{name, fmt.Sprintf("Property '%s' %s.", name.Lexeme, err)}
+	}
+	field.Set(v)
+	return nil
+}
+
+// field returns the exported, visible struct field called name. It returns an
+// error for a field promoted from an embedded struct pointer that is nil.
+func (o *GoObject) field(name scanner.Token) (reflect.Value, bool, error) {
+	target := reflect.Indirect(o.value)
+	structField, ok := target.Type().FieldByName(name.Lexeme)
+	if !ok || structField.PkgPath != "" || structField.Tag.Get("lox") == "-" {
+		return reflect.Value{}, false, nil
+	}
+	field, err := target.FieldByIndexErr(structField.Index)
+	if err != nil {
+		return reflect.Value{}, false, &RuntimeError{name, fmt.Sprintf("Property '%s' is nil.", name.Lexeme)}
+	}
+	return field, true, nil
+}
+
+// fromGo converts a field or map entry. Nested structs are exposed through a
+// pointer when possible so their fields can be written.
+func (o *GoObject) fromGo(name scanner.Token, v reflect.Value) (Value, error) {
+	if v.Kind() == reflect.Struct && v.CanAddr() {
+		v = v.Addr()
+	}
+	value, err := fromGo(v, o.hidden)
+	if err != nil {
+		return Nil, &RuntimeError{name, fmt.Sprintf("Property '%s' has unsupported type %s.", name.Lexeme, v.Type())}
+	}
+	return value, nil
+}
+
+// same reports whether o and other wrap the same pointer or map. Other Go
+// values are copied into each GoObject so only the same GoObject is the same.
+func (o *GoObject) same(other *GoObject) bool {
+	if o == other {
+		return true
+	}
+	if !isReference(o.value) || o.value.Type() != other.value.Type() {
+		return false
+	}
+	return o.value.Pointer() == other.value.Pointer()
+}
+
+// identity is the same for objects that are the same.
+func (o *GoObject) identity() uintptr {
+	if isReference(o.value) {
+		return o.value.Pointer()
+	}
+	return reflect.ValueOf(o).Pointer()
+}
+
+func isReference(v reflect.Value) bool {
+	return v.Kind() == reflect.Ptr || v.Kind() == reflect.Map
+}
+
+func (o *GoObject) String() string {
+	if stringer, ok := o.value.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprint(reflect.Indirect(o.value).Interface())
+}
+
+func undefinedProperty(name scanner.Token) *RuntimeError {
+	return &RuntimeError{name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme)}
+}
@@ -4,5 +4,7 @@ import ast "github.com/rhomel/golox/pkg/ast/gen"
 
 type Interpreter interface {
 	Interpret(statements []ast.Stmt)
-	Resolve(expr ast.Expr, depth int)
+	// Resolve records that expr refers to the local variable in slot of the
+	// environment depth scopes out from where it is evaluated.
+	Resolve(expr ast.Expr, depth, slot int)
 }
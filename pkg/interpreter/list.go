@@ -0,0 +1,156 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/rhomel/golox/pkg/scanner"
+)
+
+// LoxList is a Lox list. Like instances, lists are mutable and shared by
+// reference, so two list values are equal only if they are the same list.
+type LoxList struct {
+	elements []Value
+}
+
+func NewLoxList(elements []Value) *LoxList {
+	return &LoxList{elements: elements}
+}
+
+// Elements returns the elements of the list. Changing them changes the list.
+func (l *LoxList) Elements() []Value {
+	return l.elements
+}
+
+// Index returns list[index].
+func (l *LoxList) Index(bracket scanner.Token, index Value) (Value, error) {
+	i, err := l.position(index, len(l.elements)-1)
+	if err != nil {
+		return Nil, &RuntimeError{bracket, err.Error()}
+	}
+	return l.elements[i], nil
+}
+
+// SetIndex sets list[index] to value.
+func (l *LoxList) SetIndex(bracket scanner.Token, index, value Value) error {
+	i, err := l.position(index, len(l.elements)-1)
+	if err != nil {
+		return &RuntimeError{bracket, err.Error()}
+	}
+	l.elements[i] = value
+	return nil
+}
+
+// position converts index to a position between 0 and last inclusive.
+func (l *LoxList) position(index Value, last int) (int, error) {
+	n := index.num
+	if index.kind != KindNumber || n != math.Trunc(n) {
+		return 0, errors.New("List index must be an integer.")
+	}
+	if n < 0 || n > float64(last) {
+		return 0, fmt.Errorf("List index %s is out of bounds for a list of length %d.", formatNumber(n), len(l.elements))
+	}
+	return int(n), nil
+}
+
+// String formats the list the way print does. Strings in the list are quoted
+// and a list that contains itself is printed as [...] the second time.
+func (l *LoxList) String() string {
+	var b strings.Builder
+	l.format(&b, make(map[interface{}]bool))
+	return b.String()
+}
+
+func (l *LoxList) format(b *strings.Builder, seen map[interface{}]bool) {
+	if seen[l] {
+		b.WriteString("[...]")
+		return
+	}
+	seen[l] = true
+	defer delete(seen, l)
+	b.WriteByte('[')
+	for i, element := range l.elements {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		element.format(b, seen)
+	}
+	b.WriteByte(']')
+}
+
+var listMethods = map[string]builtinMethod{
+	// push appends a value to the end of the list.
+	"push": {1, func(this Value, arguments []Value) (Value, error) {
+		list := this.AsList()
+		list.elements = append(list.elements, arguments[0])
+		return Nil, nil
+	}},
+	// pop removes and returns the last element.
+	"pop": {0, func(this Value, arguments []Value) (Value, error) {
+		list := this.AsList()
+		if len(list.elements) == 0 {
+			return Nil, errors.New("Can't pop from an empty list.")
+		}
+		last := list.elements[len(list.elements)-1]
+		list.elements[len(list.elements)-1] = Nil
+		list.elements = list.elements[:len(list.elements)-1]
+		return last, nil
+	}},
+	// insert(index, value) moves the elements from index on up by one and
+	// puts value at index. index may be the length of the list.
+	"insert": {2, func(this Value, arguments []Value) (Value, error) {
+		list := this.AsList()
+		i, err := list.position(arguments[0], len(list.elements))
+		if err != nil {
+			return Nil, err
+		}
+		list.elements = append(list.elements, Nil)
+		copy(list.elements[i+1:], list.elements[i:])
+		list.elements[i] = arguments[1]
+		return Nil, nil
+	}},
+	// remove(index) removes and returns the element at index.
+	"remove": {1, func(this Value, arguments []Value) (Value, error) {
+		list := this.AsList()
+		i, err := list.position(arguments[0], len(list.elements)-1)
+		if err != nil {
+			return Nil, err
+		}
+		removed := list.elements[i]
+		copy(list.elements[i:], list.elements[i+1:])
+		list.elements[len(list.elements)-1] = Nil
+		list.elements = list.elements[:len(list.elements)-1]
+		return removed, nil
+	}},
+	// slice(start, end) returns a new list of the elements from start up to
+	// but not including end.
+	"slice": {2, func(this Value, arguments []Value) (Value, error) {
+		list := this.AsList()
+		start, err := list.position(arguments[0], len(list.elements))
+		if err != nil {
+			return Nil, err
+		}
+		end, err := list.position(arguments[1], len(list.elements))
+		if err != nil {
+			return Nil, err
+		}
+		if start > end {
+			return Nil, fmt.Errorf("Slice start %d is after slice end %d.", start, end)
+		}
+		elements := make([]Value, end-start)
+		copy(elements, list.elements[start:end])
+		return List(NewLoxList(elements)), nil
+	}},
+	// contains reports whether an element is equal to the value.
+	"contains": {1, func(this Value, arguments []Value) (Value, error) {
+		list := this.AsList()
+		for _, element := range list.elements {
+			if element.Equal(arguments[0]) {
+				return True, nil
+			}
+		}
+		return False, nil
+	}},
+}
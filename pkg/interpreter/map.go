@@ -0,0 +1,164 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/rhomel/golox/pkg/scanner"
+)
+
+// LoxMap is a Lox map. Keys can be nil, booleans, numbers and strings and are
+// the same key if they are == in Lox. A map remembers the order its keys were
+// added in. Like lists, maps are shared by reference.
+type LoxMap struct {
+	positions map[Value]int // the position of each key in keys and values
+	keys      []Value
+	values    []Value
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{positions: make(map[Value]int)}
+}
+
+// checkKey returns an error if key can't be a map key.
+func checkKey(key Value) error {
+	switch key.kind {
+	case KindNil, KindBool, KindString:
+		return nil
+	case KindNumber:
+		if math.IsNaN(key.num) {
+			// NaN is not == to anything so it could never be found again
+			return errors.New("NaN can't be a map key.")
+		}
+		return nil
+	}
+	return errors.New("Map keys must be nil, booleans, numbers or strings.")
+}
+
+// Get returns the value of key and whether key is in the map.
+func (m *LoxMap) Get(key Value) (Value, bool) {
+	i, ok := m.positions[key]
+	if !ok {
+		return Nil, false
+	}
+	return m.values[i], true
+}
+
+// Set sets the value of key. A new key is added after the existing keys.
+func (m *LoxMap) Set(key, value Value) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if key.kind == KindNumber && key.num == 0 {
+		// -0 is the same key as 0 so store it as 0
+		key.num = 0
+	}
+	if i, ok := m.positions[key]; ok {
+		m.values[i] = value
+		return nil
+	}
+	m.positions[key] = len(m.keys)
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+	return nil
+}
+
+// Delete removes key from the map and reports whether it was there.
+func (m *LoxMap) Delete(key Value) bool {
+	i, ok := m.positions[key]
+	if !ok {
+		return false
+	}
+	delete(m.positions, key)
+	copy(m.keys[i:], m.keys[i+1:])
+	copy(m.values[i:], m.values[i+1:])
+	m.keys[len(m.keys)-1], m.values[len(m.values)-1] = Nil, Nil
+	m.keys, m.values = m.keys[:len(m.keys)-1], m.values[:len(m.values)-1]
+	for ; i < len(m.keys); i++ {
+		m.positions[m.keys[i]] = i
+	}
+	return true
+}
+
+// Keys returns the keys of the map in the order they were added. The slice
+// must not be changed.
+func (m *LoxMap) Keys() []Value {
+	return m.keys
+}
+
+// Index returns map[key].
+func (m *LoxMap) Index(bracket scanner.Token, key Value) (Value, error) {
+	if err := checkKey(key); err != nil {
+		return Nil, &RuntimeError{bracket, err.Error()}
+	}
+	value, ok := m.Get(key)
+	if !ok {
+		var b strings.Builder
+		key.format(&b, nil)
+		return Nil, &RuntimeError{bracket, fmt.Sprintf("Key %s is not in the map.", b.String())}
+	}
+	return value, nil
+}
+
+// SetIndex sets map[key] to value.
+func (m *LoxMap) SetIndex(bracket scanner.Token, key, value Value) error {
+	if err := m.Set(key, value); err != nil {
+		return &RuntimeError{bracket, err.Error()}
+	}
+	return nil
+}
+
+// String formats the map the way print does. Strings in the map are quoted
+// and a map that contains itself is printed as {...} the second time.
+func (m *LoxMap) String() string {
+	var b strings.Builder
+	m.format(&b, make(map[interface{}]bool))
+	return b.String()
+}
+
+func (m *LoxMap) format(b *strings.Builder, seen map[interface{}]bool) {
+	if seen[m] {
+		b.WriteString("{...}")
+		return
+	}
+	seen[m] = true
+	defer delete(seen, m)
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		key.format(b, seen)
+		b.WriteString(": ")
+		m.values[i].format(b, seen)
+	}
+	b.WriteByte('}')
+}
+
+var mapMethods = map[string]builtinMethod{
+	// has reports whether the key is in the map.
+	"has": {1, func(this Value, arguments []Value) (Value, error) {
+		if err := checkKey(arguments[0]); err != nil {
+			return Nil, err
+		}
+		_, ok := this.AsMap().Get(arguments[0])
+		return Bool(ok), nil
+	}},
+	// delete removes the key and reports whether it was in the map.
+	"delete": {1, func(this Value, arguments []Value) (Value, error) {
+		if err := checkKey(arguments[0]); err != nil {
+			return Nil, err
+		}
+		return Bool(this.AsMap().Delete(arguments[0])), nil
+	}},
+	// keys returns a new list of the keys in the order they were added.
+	"keys": {0, func(this Value, arguments []Value) (Value, error) {
+		return List(NewLoxList(append([]Value(nil), this.AsMap().keys...))), nil
+	}},
+	// values returns a new list of the values in the same order as keys.
+	"values": {0, func(this Value, arguments []Value) (Value, error) {
+		return List(NewLoxList(append([]Value(nil), this.AsMap().values...))), nil
+	}},
+}
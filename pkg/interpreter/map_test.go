@@ -0,0 +1,61 @@
+package interpreter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMapKeys(t *testing.T) {
+	m := NewLoxMap()
+	keys := []Value{Nil, False, True, Number(0), Number(1), String(""), String("1")}
+	for i, key := range keys {
+		if err := m.Set(key, Number(float64(i))); err != nil {
+			t.Fatalf("%v (%s): %v", key, key.Kind(), err)
+		}
+	}
+	if len(m.Keys()) != len(keys) {
+		t.Fatalf("want %d keys, got %v", len(keys), m)
+	}
+	for i, key := range keys {
+		if got, ok := m.Get(key); !ok || got.AsNumber() != float64(i) {
+			t.Errorf("%v (%s): want %d, got %v (found: %v)", key, key.Kind(), i, got, ok)
+		}
+	}
+	// equal keys are the same key
+	m.Set(Number(math.Copysign(0, -1)), String("-0"))
+	m.Set(String("l"+"ox"), String("lox"))
+	if got, _ := m.Get(Number(0)); got.AsString() != "-0" {
+		t.Errorf("want -0 to replace 0, got %v", got)
+	}
+	if got, _ := m.Get(String("lox")); got.AsString() != "lox" {
+		t.Errorf("want equal strings to be the same key, got %v", got)
+	}
+
+	for _, key := range []Value{Number(math.NaN()), List(NewLoxList(nil)), Map(NewLoxMap())} {
+		if err := m.Set(key, Nil); err == nil {
+			t.Errorf("%v (%s): want an error for an invalid key", key, key.Kind())
+		}
+	}
+}
+
+func TestMapDeleteKeepsOrder(t *testing.T) {
+	m := NewLoxMap()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		m.Set(String(key), String(key))
+	}
+	if !m.Delete(String("b")) {
+		t.Fatal("want b to be deleted")
+	}
+	if m.Delete(String("b")) {
+		t.Fatal("want b to be gone")
+	}
+	m.Set(String("b"), String("b"))
+	if want, got := `{"a": "a", "c": "c", "d": "d", "b": "b"}`, m.String(); want != got {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if got, ok := m.Get(String(key)); !ok || got.AsString() != key {
+			t.Errorf("%s: want %s, got %v (found: %v)", key, key, got, ok)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package interpreter
+
+import (
+	"fmt"
+
+	ast "github.com/rhomel/golox/pkg/ast/gen"
+	"github.com/rhomel/golox/pkg/module"
+	"github.com/rhomel/golox/pkg/scanner"
+)
+
+// ModuleLoader finds and parses the modules imported by a program. The
+// interpreter can't do it itself because the resolver depends on it.
+type ModuleLoader interface {
+	// FindModule returns the file that path names in an import statement of
+	// the file from, which is empty for a program that wasn't read from a
+	// file.
+	FindModule(from, path string) (file string, ok bool)
+	// LoadModule scans, parses and resolves the module in file. It returns an
+	// error if the file can't be read or the first syntax error, formatted like
+	// "[line 1] Error at ';': Expect expression.".
+	LoadModule(file string) ([]ast.Stmt, error)
+}
+
+// LoxModule is an imported module. Its top level variables are its
+// properties; they can be read but not assigned from outside the module.
+type LoxModule struct {
+	name    string
+	globals *Globals
+}
+
+func (m *LoxModule) Get(name scanner.Token) (Value, error) {
+	if value, ok := m.globals.values[name.Lexeme]; ok {
+		return value, nil
+	}
+	return Nil, &RuntimeError{name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme)}
+}
+
+func (m *LoxModule) String() string {
+	return "<module " + m.name + ">"
+}
+
+// newGlobals returns the globals a program or module starts with.
+func newGlobals() *Globals {
+	globals := NewGlobals()
+	globals.Define("clock", Callable(&nativeClock{}))
+	globals.Define("len", Callable(&nativeLen{}))
+	return globals
+}
+
+// SetModuleLoader sets how import statements find modules. Without a loader
+// an import is a runtime error.
+func (in *TreeWalkInterpreter) SetModuleLoader(loader ModuleLoader) {
+	in.loader = loader
+}
+
+// SetFile sets the file the program being interpreted was read from, which
+// relative imports are found from. It is empty if the program isn't a file.
+func (in *TreeWalkInterpreter) SetFile(file string) {
+	in.file = file
+}
+
+func (in *TreeWalkInterpreter) visitImportStmt(stmt *ast.ImportStmt) error {
+	module, err := in.importModule(stmt.Path)
+	if err != nil {
+		return err
+	}
+	in.define(stmt.Name.Lexeme, Module(module))
+	return nil
+}
+
+// importModule returns the module named by path, running it the first time it
+// is imported. A module that fails isn't cached so importing it again runs it
+// again.
+func (in *TreeWalkInterpreter) importModule(path scanner.Token) (*LoxModule, error) {
+	name := path.Literal.(string)
+	if in.loader == nil {
+		return nil, &RuntimeError{path, fmt.Sprintf("Can't import module '%s'.", name)}
+	}
+	file, ok := in.loader.FindModule(in.file, name)
+	if !ok {
+		return nil, &RuntimeError{path, fmt.Sprintf("Module '%s' not found.", name)}
+	}
+	if loaded, ok := in.modules[file]; ok {
+		return loaded, nil
+	}
+	if message, ok := module.Cycle(append(in.loading, in.file), file); ok {
+		return nil, &RuntimeError{path, message}
+	}
+	statements, err := in.loader.LoadModule(file)
+	if err != nil {
+		return nil, &RuntimeError{path, fmt.Sprintf("Error in module '%s': %s", name, err)}
+	}
+	loaded := &LoxModule{module.Name(file), newGlobals()}
+	if err := in.runModule(file, loaded.globals, statements); err != nil {
+		return nil, err
+	}
+	in.modules[file] = loaded
+	return loaded, nil
+}
+
+// runModule runs the statements of the module in file with its own globals.
+func (in *TreeWalkInterpreter) runModule(file string, globals *Globals, statements []ast.Stmt) error {
+	previousGlobals, previousEnvironment, previousFile := in.globals, in.environment, in.file
+	in.loading = append(in.loading, in.file)
+	in.globals, in.environment, in.file = globals, nil, file
+	var err error
+	if in.compile {
+		_, err = newCompiler(in).statements(statements)(in)
+	} else {
+		for _, stmt := range statements {
+			if _, err = in.execute(stmt); err != nil {
+				break
+			}
+		}
+	}
+	in.globals, in.environment, in.file = previousGlobals, previousEnvironment, previousFile
+	in.loading = in.loading[:len(in.loading)-1]
+	return err
+}
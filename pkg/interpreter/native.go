@@ -0,0 +1,253 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// NativeFunction is an ordinary Go function bound as a Lox function. Lox
+// arguments are converted to the function's parameter types and the results
+// are converted back to Lox values:
+//
+//   - numbers convert to any Go integer or float type. A number passed to an
+//     integer parameter must be a whole number that fits the type.
+//   - strings and booleans convert to string and bool.
+//   - structs, pointers to structs and maps with string keys convert to and
+//     from GoObjects.
+//   - a Value parameter receives the Lox value unchanged and an interface{}
+//     parameter receives Value.Interface.
+//
+// The function may return nothing, a single value, an error, or a value
+// followed by an error. A Value result, or an interface{} result holding a
+// Value or a value returned by Value.Interface, is returned unchanged. A
+// non-nil error becomes a Lox runtime error, and so does a panic. Variadic
+// functions accept any number of trailing arguments.
+type NativeFunction struct {
+	name     string
+	fn       reflect.Value
+	params   []reflect.Type
+	variadic reflect.Type    // element type of the variadic parameter or nil
+	result   bool            // the first result is a value
+	err      bool            // the last result is an error
+	hidden   map[string]bool // hidden members of GoObject results
+}
+
+var _ LoxCallable = (*NativeFunction)(nil)
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	valueType = reflect.TypeOf(Value{})
+)
+
+// NewNativeFunction returns an error if fn is not a function or if it has a
+// parameter or result type that can't be converted.
+func NewNativeFunction(name string, fn interface{}) (*NativeFunction, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("%s: %T is not a function", name, fn)
+	}
+	typ := v.Type()
+	native := &NativeFunction{name: name, fn: v}
+	for i := 0; i < typ.NumIn(); i++ {
+		param := typ.In(i)
+		if typ.IsVariadic() && i == typ.NumIn()-1 {
+			param = param.Elem()
+			native.variadic = param
+		} else {
+			native.params = append(native.params, param)
+		}
+		if !isConvertible(param) {
+			return nil, fmt.Errorf("%s: unsupported parameter type %s", name, param)
+		}
+	}
+	switch typ.NumOut() {
+	case 0:
+	case 1:
+		native.err = typ.Out(0) == errorType
+		native.result = !native.err
+	case 2:
+		if typ.Out(1) != errorType {
+			return nil, fmt.Errorf("%s: the second result must be an error", name)
+		}
+		native.result, native.err = true, true
+	default:
+		return nil, fmt.Errorf("%s: too many results", name)
+	}
+	if native.result && !isConvertible(typ.Out(0)) {
+		return nil, fmt.Errorf("%s: unsupported result type %s", name, typ.Out(0))
+	}
+	return native, nil
+}
+
+func isConvertible(typ reflect.Type) bool {
+	if typ == valueType || isGoObject(typ) {
+		// passed as a GoObject
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Interface:
+		return typ.NumMethod() == 0
+	}
+	return false
+}
+
+// Arity is the number of required arguments. A variadic function accepts more.
+func (f *NativeFunction) Arity() int {
+	return len(f.params)
+}
+
+func (f *NativeFunction) IsVariadic() bool {
+	return f.variadic != nil
+}
+
+// Call returns the error from Invoke, which the interpreter reports at the
+// call site.
+func (f *NativeFunction) Call(in *TreeWalkInterpreter, arguments []Value) (Value, error) {
+	return f.Invoke(arguments)
+}
+
+// Invoke converts arguments, calls the Go function and converts its result.
+func (f *NativeFunction) Invoke(arguments []Value) (result Value, err error) {
+	if err := CheckArity(f, len(arguments)); err != nil {
+		return Nil, err
+	}
+	in := make([]reflect.Value, len(arguments))
+	for i, argument := range arguments {
+		typ := f.variadic
+		if i < len(f.params) {
+			typ = f.params[i]
+		}
+		v, err := toGo(argument, typ)
+		if err != nil {
+			return Nil, fmt.Errorf("Argument %d to %s %s.", i+1, f.name, err)
+		}
+		in[i] = v
+	}
+	defer func() {
+		// a panicking Go function must not crash the program embedding Lox
+		if r := recover(); r != nil {
+			result, err = Nil, fmt.Errorf("%s panicked: %v.", f.name, r)
+		}
+	}()
+	out := f.fn.Call(in)
+	if f.err {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return Nil, err
+		}
+	}
+	if !f.result {
+		return Nil, nil
+	}
+	return fromGo(out[0], f.hidden)
+}
+
+func (f *NativeFunction) String() string {
+	return "<native fn>"
+}
+
+// CheckArity returns an error if function can't be called with got arguments.
+func CheckArity(function LoxCallable, got int) error {
+	expected := function.Arity()
+	if native, ok := function.(*NativeFunction); ok && native.IsVariadic() {
+		if got < expected {
+			return fmt.Errorf("Expected at least %d arguments but got %d.", expected, got)
+		}
+		return nil
+	}
+	if expected != got {
+		return fmt.Errorf("Expected %d arguments but got %d.", expected, got)
+	}
+	return nil
+}
+
+// toGo converts a Lox value to typ. The error completes the sentence
+// "Argument N to name ...".
+func toGo(value Value, typ reflect.Type) (reflect.Value, error) {
+	if typ == valueType {
+		return reflect.ValueOf(value), nil
+	}
+	if object := value.AsGoObject(); object != nil && object.value.Type().AssignableTo(typ) {
+		return object.value, nil
+	}
+	if isGoObject(typ) {
+		return reflect.Value{}, fmt.Errorf("must be a %s", typ)
+	}
+	switch typ.Kind() {
+	case reflect.Interface:
+		if value.IsNil() {
+			return reflect.Zero(typ), nil
+		}
+		return reflect.ValueOf(value.Interface()), nil
+	case reflect.Bool:
+		if value.kind == KindBool {
+			return reflect.ValueOf(value.AsBool()).Convert(typ), nil
+		}
+		return reflect.Value{}, errors.New("must be a boolean")
+	case reflect.String:
+		if value.kind == KindString {
+			return reflect.ValueOf(value.AsString()).Convert(typ), nil
+		}
+		return reflect.Value{}, errors.New("must be a string")
+	}
+	if value.kind != KindNumber {
+		return reflect.Value{}, errors.New("must be a number")
+	}
+	number := value.num
+	v := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(number)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if number != math.Trunc(number) || number < math.MinInt64 || number >= math.MaxInt64 || v.OverflowInt(int64(number)) {
+			return reflect.Value{}, fmt.Errorf("must be an integer that fits in %s", typ)
+		}
+		v.SetInt(int64(number))
+	default:
+		if number != math.Trunc(number) || number < 0 || number >= math.MaxUint64 || v.OverflowUint(uint64(number)) {
+			return reflect.Value{}, fmt.Errorf("must be an integer that fits in %s", typ)
+		}
+		v.SetUint(uint64(number))
+	}
+	return v, nil
+}
+
+// fromGo converts a result of a native function to a Lox value. GoObjects are
+// created with hidden members.
+func fromGo(v reflect.Value, hidden map[string]bool) (Value, error) {
+	if v.Kind() != reflect.Interface && v.CanInterface() {
+		// values that came from Lox are returned as they were
+		if value, ok := ValueOf(v.Interface()); ok {
+			return value, nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return Nil, nil
+		}
+		return fromGo(v.Elem(), hidden)
+	case reflect.Bool:
+		return Bool(v.Bool()), nil
+	case reflect.String:
+		return String(v.String()), nil
+	case reflect.Float32, reflect.Float64:
+		return Number(v.Float()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Number(float64(v.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Number(float64(v.Uint())), nil
+	}
+	if isGoObject(v.Type()) {
+		if object := newGoObject(v, hidden); object != nil {
+			return Object(object), nil
+		}
+		return Nil, nil
+	}
+	return Nil, fmt.Errorf("Unsupported result type %s.", v.Type())
+}
@@ -0,0 +1,102 @@
+package interpreter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNativeFunctionConversions(t *testing.T) {
+	tests := []struct {
+		name      string
+		fn        interface{}
+		arguments []interface{}
+		want      interface{}
+	}{
+		{"no results", func() {}, nil, nil},
+		{"float", func(a, b float64) float64 { return a * b }, []interface{}{2.0, 1.5}, 3.0},
+		{"ints", func(a int, b uint8, c int64) int { return a + int(b) + int(c) }, []interface{}{1.0, 2.0, 3.0}, 6.0},
+		{"float32", func(f float32) float32 { return f * 2 }, []interface{}{1.5}, 3.0},
+		{"string", func(s string, n int) string { return strings.Repeat(s, n) }, []interface{}{"ab", 2.0}, "abab"},
+		{"bool", func(b bool) bool { return !b }, []interface{}{true}, false},
+		{"interface", func(v interface{}) interface{} { return v }, []interface{}{nil}, nil},
+		{"interface int result", func() interface{} { return 7 }, nil, 7.0},
+		{"interface argument", func(v interface{}) bool { _, ok := v.(string); return ok }, []interface{}{"s"}, true},
+		{"value", func(v Value) Value { return String(v.Kind().String()) }, []interface{}{2.0}, "number"},
+		{"interface value result", func() interface{} { return Number(1) }, nil, 1.0},
+		{"value and nil error", func(s string) (string, error) { return s + "!", nil }, []interface{}{"hi"}, "hi!"},
+		{"variadic none", func(xs ...float64) int { return len(xs) }, nil, 0.0},
+		{"variadic", func(sep string, xs ...string) string { return strings.Join(xs, sep) }, []interface{}{"-", "a", "b", "c"}, "a-b-c"},
+	}
+	for _, test := range tests {
+		native, err := NewNativeFunction(test.name, test.fn)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		got, err := native.Invoke(values(test.arguments))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if want, _ := ValueOf(test.want); !got.Equal(want) {
+			t.Errorf("%s: want %v (%s), got %v (%s)", test.name, want, want.Kind(), got, got.Kind())
+		}
+	}
+}
+
+func TestNativeFunctionErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		fn        interface{}
+		arguments []interface{}
+		want      string
+	}{
+		{"arity", func(a, b float64) {}, []interface{}{1.0}, "Expected 2 arguments but got 1."},
+		{"variadic arity", func(a string, xs ...int) {}, nil, "Expected at least 1 arguments but got 0."},
+		{"type", func(s string) {}, []interface{}{1.0}, "Argument 1 to type must be a string."},
+		{"variadic type", func(xs ...float64) {}, []interface{}{1.0, "x"}, "Argument 2 to variadic type must be a number."},
+		{"fraction", func(n int) {}, []interface{}{1.5}, "Argument 1 to fraction must be an integer that fits in int."},
+		{"overflow", func(n uint8) {}, []interface{}{256.0}, "Argument 1 to overflow must be an integer that fits in uint8."},
+		{"negative", func(n uint) {}, []interface{}{-1.0}, "Argument 1 to negative must be an integer that fits in uint."},
+		{"go error", func() error { return errors.New("Something failed.") }, nil, "Something failed."},
+		{"go error with value", func() (float64, error) { return 0, errors.New("No value.") }, nil, "No value."},
+		{"unsupported result", func() interface{} { return []int{} }, nil, "Unsupported result type []int."},
+	}
+	for _, test := range tests {
+		native, err := NewNativeFunction(test.name, test.fn)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		_, err = native.Invoke(values(test.arguments))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%s: want error %q, got %v", test.name, test.want, err)
+		}
+	}
+}
+
+// values converts nil, booleans, numbers and strings.
+func values(arguments []interface{}) []Value {
+	converted := make([]Value, len(arguments))
+	for i, argument := range arguments {
+		converted[i], _ = ValueOf(argument)
+	}
+	return converted
+}
+
+func TestNewNativeFunctionRejects(t *testing.T) {
+	for name, fn := range map[string]interface{}{
+		"not a function":         42,
+		"nil function":           (func())(nil),
+		"parameter type":         func(s []string) {},
+		"result type":            func() []string { return nil },
+		"second result":          func() (int, int) { return 0, 0 },
+		"too many results":       func() (int, int, error) { return 0, 0, nil },
+		"interface with methods": func(e error) {},
+	} {
+		if _, err := NewNativeFunction(name, fn); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package interpreter
+
+import "github.com/rhomel/golox/pkg/scanner"
+
+// A shape maps the field names of an instance to slots in LoxInstance.fields.
+// Instances of a class that had the same fields added in the same order share
+// a shape. Each class has its own tree of shapes starting with the empty shape
+// of new instances, so an instance's shape also determines its class and the
+// methods that aren't shadowed by fields.
+type shape struct {
+	slots       map[string]int
+	transitions map[string]*shape // the shapes with one more field
+}
+
+// add returns the shape with name in the next slot.
+func (s *shape) add(name string) *shape {
+	if next, ok := s.transitions[name]; ok {
+		return next
+	}
+	slots := make(map[string]int, len(s.slots)+1)
+	for field, slot := range s.slots {
+		slots[field] = slot
+	}
+	slots[name] = len(s.slots)
+	next := &shape{slots: slots}
+	if s.transitions == nil {
+		s.transitions = make(map[string]*shape)
+	}
+	s.transitions[name] = next
+	return next
+}
+
+// propertyCache is an inline cache for one property access in compiled code.
+// It remembers what the access found for the last shape it saw, so another
+// instance with the same shape skips the lookups.
+type propertyCache struct {
+	shape  *shape
+	slot   int          // the slot of the field
+	method *LoxFunction // the method if there is no field
+	next   *shape       // the shape after setting the field
+}
+
+// property is LoxInstance.property with the result cached.
+func (c *propertyCache) property(instance *LoxInstance, name scanner.Token) (Value, *LoxFunction, error) {
+	if instance.shape == c.shape {
+		if c.method != nil {
+			return Nil, c.method, nil
+		}
+		return instance.fields[c.slot], nil, nil
+	}
+	field, method, err := instance.property(name)
+	if err != nil {
+		return Nil, nil, err
+	}
+	c.shape, c.slot, c.method, c.next = instance.shape, instance.shape.slots[name.Lexeme], method, nil
+	return field, method, nil
+}
+
+// set is LoxInstance.Set with the result cached.
+func (c *propertyCache) set(instance *LoxInstance, name scanner.Token, value Value) {
+	if instance.shape == c.shape && c.next != nil {
+		if c.next != c.shape {
+			instance.shape = c.next
+			instance.fields = append(instance.fields, value)
+		} else {
+			instance.fields[c.slot] = value
+		}
+		return
+	}
+	previous := instance.shape
+	instance.Set(name, value)
+	c.shape, c.slot, c.method, c.next = previous, instance.shape.slots[name.Lexeme], nil, instance.shape
+}
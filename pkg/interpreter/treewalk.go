@@ -2,186 +2,593 @@ package interpreter
 
 import (
 	"fmt"
-	"regexp"
-	"strconv"
-	"strings"
+	"io"
+	"math"
+	"os"
+	"sync/atomic"
 	"time"
 
 	ast "github.com/rhomel/golox/pkg/ast/gen"
+	"github.com/rhomel/golox/pkg/number"
 	"github.com/rhomel/golox/pkg/scanner"
 	"github.com/rhomel/golox/pkg/util/check"
-	"github.com/rhomel/golox/pkg/util/exit"
 )
 
+// DefaultMaxCallDepth bounds Lox recursion. Each Lox call recurses through
+// several Go calls so runaway recursion would otherwise overflow the Go stack,
+// which is a fatal error that can't be recovered.
+const DefaultMaxCallDepth = 10000
+
 type TreeWalkInterpreter struct {
 	reporter    RuntimeErrorReporter
-	globals     *Environment
-	environment *Environment
-	locals      map[ast.Expr]int
+	globals     *Globals
+	environment *Environment // nil in the global scope
+	locals      map[ast.Expr]local
+	stdout      io.Writer
+	compile     bool // compile into closures before running, see compile.go
+
+	loader  ModuleLoader
+	file    string                // the file of the program or module being run
+	modules map[string]*LoxModule // the modules imported so far by file
+	loading []string              // the files of the modules importing the one being run
+
+	frames       []callFrame
+	maxCallDepth int
+	trace        []StackFrame // the stack trace of the runtime error unwinding the stack
+	errorClass   *LoxClass    // the class of the error objects caught runtime errors become
+	interrupted  int32        // accessed atomically
 }
 
 var _ Interpreter = (*TreeWalkInterpreter)(nil)
 
+// local is where the resolver found a local variable
+type local struct {
+	depth int
+	slot  int
+}
+
+// callFrame is a call in progress, recorded for stack traces.
+type callFrame struct {
+	function LoxCallable
+	line     int // the line of the call site
+}
+
+// StackFrame is one line of a stack trace: the line being executed in a
+// function. Function is empty for top level code.
+type StackFrame struct {
+	Function string
+	Line     int
+}
+
+// completion is the result of executing a statement. Statements that transfer
+// control, like return and break, complete abruptly and every enclosing statement stops
+// executing and passes the completion on until it reaches the statement that
+// handles it.
+type completion struct {
+	kind  completionKind
+	value Value // the value of a return
+}
+
+type completionKind int
+
+const (
+	completeNormal completionKind = iota
+	completeReturn
+	completeBreak
+	completeContinue
+)
+
+var (
+	normal       = completion{kind: completeNormal}
+	breakLoop    = completion{kind: completeBreak}
+	continueLoop = completion{kind: completeContinue}
+)
+
 type RuntimeErrorReporter interface {
-	RuntimeError(token scanner.Token, message string)
+	// RuntimeError receives an uncaught runtime error. trace starts with the
+	// innermost call.
+	RuntimeError(token scanner.Token, message string, trace []StackFrame)
 }
 
 func NewTreeWalkInterpreter(reporter RuntimeErrorReporter) *TreeWalkInterpreter {
-	globals := NewEnvironment(nil)
-	globals.Define("clock", &nativeClock{})
-	return &TreeWalkInterpreter{reporter, globals, globals, make(map[ast.Expr]int)}
+	return &TreeWalkInterpreter{
+		reporter:     reporter,
+		globals:      newGlobals(),
+		environment:  nil,
+		locals:       make(map[ast.Expr]local),
+		modules:      make(map[string]*LoxModule),
+		stdout:       os.Stdout,
+		maxCallDepth: DefaultMaxCallDepth,
+		errorClass:   newErrorClass(),
+	}
+}
+
+// SetStdout sets where print statements write to. The default is os.Stdout.
+func (in *TreeWalkInterpreter) SetStdout(w io.Writer) {
+	in.stdout = w
+}
+
+// SetMaxCallDepth sets how many calls can be in progress before a call fails
+// with a "Stack overflow." runtime error. The default is DefaultMaxCallDepth.
+// A depth much larger than the default can exhaust the Go stack, which
+// crashes the process.
+func (in *TreeWalkInterpreter) SetMaxCallDepth(depth int) {
+	in.maxCallDepth = depth
 }
 
 func (in *TreeWalkInterpreter) Interpret(statements []ast.Stmt) {
-	defer func() {
-		if r := recover(); r != nil {
-			// TODO
-			if err, ok := r.(*RuntimeError); ok {
-				in.reporter.RuntimeError(err.token, err.message)
-			} else {
-				in.reporter.RuntimeError(scanner.Token{}, fmt.Sprintf("%v", r)) // TODO
+	if in.compile {
+		program := newCompiler(in).statements(statements)
+		in.run(func() error {
+			_, err := program(in)
+			return err
+		})
+		return
+	}
+	in.run(func() error {
+		for _, stmt := range statements {
+			// the resolver rejects a return outside of a function so a
+			// top level statement always completes normally
+			if _, err := in.execute(stmt); err != nil {
+				return err
 			}
 		}
-	}()
-	for _, stmt := range statements {
-		if stmt != nil { // TODO: on parse errors a nil entry will be inserted
-			in.execute(stmt)
+		return nil
+	})
+}
+
+// Evaluate returns the value of a resolved expression. A runtime error is
+// reported and nil is returned.
+func (in *TreeWalkInterpreter) Evaluate(expr ast.Expr) (value Value) {
+	evaluate := in.evaluate
+	if in.compile {
+		evaluate = func(ast.Expr) (Value, error) {
+			return newCompiler(in).expression(expr)(in)
 		}
 	}
+	in.run(func() (err error) {
+		value, err = evaluate(expr)
+		return err
+	})
+	return
+}
+
+// CallFunction calls function from Go. The caller is responsible for checking
+// the number of arguments. A runtime error is reported and nil is returned.
+func (in *TreeWalkInterpreter) CallFunction(function LoxCallable, arguments []Value) (result Value) {
+	in.run(func() (err error) {
+		// there is no call site in Lox so the call is on line 0
+		result, err = in.call(scanner.Token{}, Callable(function), arguments)
+		return err
+	})
+	return
+}
+
+// GetGlobal returns the value of a global variable.
+func (in *TreeWalkInterpreter) GetGlobal(name string) (Value, bool) {
+	value, ok := in.globals.values[name]
+	return value, ok
+}
+
+// DefineGlobal defines or redefines a global variable.
+func (in *TreeWalkInterpreter) DefineGlobal(name string, value Value) {
+	in.globals.Define(name, value)
+}
+
+// run resets the state left over from a previous run and calls fn, reporting
+// the error it returns.
+func (in *TreeWalkInterpreter) run(fn func() error) {
+	in.frames = in.frames[:0]
+	in.trace = nil
+	atomic.StoreInt32(&in.interrupted, 0)
+	err := fn()
+	if err == nil {
+		return
+	}
+	in.environment = nil
+	// an uncaught throw is reported like a runtime error
+	token, _ := errorToken(err)
+	if in.trace == nil {
+		in.trace = in.stackTrace(token.Line)
+	}
+	in.reporter.RuntimeError(token, err.Error(), in.trace)
+}
+
+// stackTrace returns the calls in progress starting with the innermost one,
+// which is executing line.
+func (in *TreeWalkInterpreter) stackTrace(line int) []StackFrame {
+	trace := make([]StackFrame, 0, len(in.frames)+1)
+	for i := len(in.frames) - 1; i >= 0; i-- {
+		frame := in.frames[i]
+		trace = append(trace, StackFrame{frameName(frame.function), line})
+		line = frame.line
+	}
+	if len(in.frames) > 0 && line == 0 {
+		// the outermost function was called from Go by CallFunction
+		return trace
+	}
+	return append(trace, StackFrame{Line: line})
+}
+
+func frameName(function LoxCallable) string {
+	switch function := function.(type) {
+	case *LoxFunction:
+		return function.name()
+	case *LoxClass:
+		// calling a class runs its initializer
+		return "init"
+	case *NativeFunction:
+		return function.name
+	case *boundBuiltin:
+		return function.name
+	}
+	return "native"
+}
+
+// Interrupt stops a running Interpret call with an "Interrupted." runtime
+// error at the next loop iteration or function call. It is safe to call from
+// another goroutine.
+func (in *TreeWalkInterpreter) Interrupt() {
+	atomic.StoreInt32(&in.interrupted, 1)
 }
 
-func (in *TreeWalkInterpreter) Accept(elem interface{}) interface{} {
+func (in *TreeWalkInterpreter) checkInterrupt(token scanner.Token) error {
+	if atomic.LoadInt32(&in.interrupted) != 0 {
+		return &RuntimeError{token, "Interrupted."}
+	}
+	return nil
+}
+
+// evaluate dispatches on the expression type. The interpreter doesn't use the
+// generated visitors because they can't return an error alongside the value.
+func (in *TreeWalkInterpreter) evaluate(expr ast.Expr) (Value, error) {
 	// Go has no dynamic dispatch and inheritance so we have to resort to a type switch
-	switch v := elem.(type) {
+	switch v := expr.(type) {
 	case *ast.Binary:
-		return v.Accept(in)
+		return in.visitBinaryExpr(v)
 	case *ast.Call:
-		return v.Accept(in)
+		return in.visitCallExpr(v)
+	case *ast.Comma:
+		if _, err := in.evaluate(v.Left); err != nil {
+			return Nil, err
+		}
+		return in.evaluate(v.Right)
+	case *ast.Compound:
+		return in.visitCompoundExpr(v)
+	case *ast.Conditional:
+		return in.visitConditionalExpr(v)
 	case *ast.Get:
-		return v.Accept(in)
+		return in.visitGetExpr(v)
+	case *ast.GetIndex:
+		return in.visitGetIndexExpr(v)
 	case *ast.Grouping:
-		return v.Accept(in)
+		return in.evaluate(v.Expression)
+	case *ast.Lambda:
+		return Callable(in.newFunction(v.Function, false)), nil
+	case *ast.List:
+		return in.visitListExpr(v)
 	case *ast.Literal:
-		return v.Accept(in)
+		return literal(v.Value), nil
 	case *ast.Logical:
-		return v.Accept(in)
+		return in.visitLogicalExpr(v)
+	case *ast.Map:
+		return in.visitMapExpr(v)
 	case *ast.Set:
-		return v.Accept(in)
+		return in.visitSetExpr(v)
+	case *ast.SetIndex:
+		return in.visitSetIndexExpr(v)
+	case *ast.Stringify:
+		value, err := in.evaluate(v.Expression)
+		if err != nil {
+			return Nil, err
+		}
+		return String(value.String()), nil
 	case *ast.Super:
-		return v.Accept(in)
+		return in.visitSuperExpr(v)
 	case *ast.This:
-		return v.Accept(in)
+		return in.lookUpVariable(v.Keyword, v)
 	case *ast.Unary:
-		return v.Accept(in)
+		return in.visitUnaryExpr(v)
 	case *ast.Variable:
-		return v.Accept(in)
+		return in.lookUpVariable(v.Name, v)
 	case *ast.Assign:
-		return v.Accept(in)
+		return in.visitAssignExpr(v)
 	default:
-		exit.Exitf(exit.ExitSyntaxError, "unsupported expression type: %s", check.TypeOf(elem))
-		return nil
+		panic(fmt.Sprintf("unsupported expression type: %s", check.TypeOf(expr)))
 	}
 }
 
-func (in *TreeWalkInterpreter) VisitBinaryExpr(binary *ast.Binary) interface{} {
-	left := in.evaluate(binary.Left)
-	right := in.evaluate(binary.Right)
+func (in *TreeWalkInterpreter) visitBinaryExpr(binary *ast.Binary) (Value, error) {
+	left, err := in.evaluate(binary.Left)
+	if err != nil {
+		return Nil, err
+	}
+	right, err := in.evaluate(binary.Right)
+	if err != nil {
+		return Nil, err
+	}
+	return binaryOperation(binary.Operator, left, right)
+}
+
+// binaryOperation applies a binary operator other than "and" and "or" to its
+// operands.
+func binaryOperation(operator scanner.Token, left, right Value) (Value, error) {
+	switch operator.Typ {
+	case scanner.BANG_EQUAL:
+		return Bool(!left.Equal(right)), nil
+	case scanner.EQUAL_EQUAL:
+		return Bool(left.Equal(right)), nil
+	case scanner.PLUS:
+		return add(operator, left, right)
+	}
 
-	switch binary.Operator.Typ {
+	leftDouble, rightDouble, err := numberOperands(operator, left, right)
+	if err != nil {
+		return Nil, err
+	}
+	switch operator.Typ {
 	case scanner.GREATER:
-		in.checkNumberOperands(binary.Operator, left, right)
-		return mustDouble(left) > mustDouble(right)
+		return Bool(leftDouble > rightDouble), nil
 	case scanner.GREATER_EQUAL:
-		in.checkNumberOperands(binary.Operator, left, right)
-		return mustDouble(left) >= mustDouble(right)
+		return Bool(leftDouble >= rightDouble), nil
 	case scanner.LESS:
-		in.checkNumberOperands(binary.Operator, left, right)
-		return mustDouble(left) < mustDouble(right)
+		return Bool(leftDouble < rightDouble), nil
 	case scanner.LESS_EQUAL:
-		in.checkNumberOperands(binary.Operator, left, right)
-		return mustDouble(left) <= mustDouble(right)
-	case scanner.BANG_EQUAL:
-		return !in.isEqual(left, right)
-	case scanner.EQUAL_EQUAL:
-		return in.isEqual(left, right)
+		return Bool(leftDouble <= rightDouble), nil
 	case scanner.MINUS:
-		in.checkNumberOperands(binary.Operator, left, right)
-		return mustDouble(left) - mustDouble(right)
+		return Number(leftDouble - rightDouble), nil
 	case scanner.SLASH:
-		in.checkNumberOperands(binary.Operator, left, right)
-		return mustDouble(left) / mustDouble(right)
+		return Number(leftDouble / rightDouble), nil
 	case scanner.STAR:
-		in.checkNumberOperands(binary.Operator, left, right)
-		return mustDouble(left) * mustDouble(right)
-	case scanner.PLUS:
-		leftDouble, leftIsDouble := left.(float64)
-		rightDouble, rightIsDouble := right.(float64)
-		if leftIsDouble && rightIsDouble {
-			return leftDouble + rightDouble
-		}
-		leftString, leftIsString := left.(string)
-		rightString, rightIsString := right.(string)
-		if leftIsString && rightIsString {
-			return leftString + rightString
-		}
-		if leftIsDouble && rightIsString {
-			// TODO: improve this to reference the actual token literal
-			panic(&RuntimeError{binary.Operator, fmt.Sprintf("Left operand '%f' is double but right operand '%s' is string.", leftDouble, rightString)})
-		}
-		if leftIsString && rightIsDouble {
-			// TODO: improve this to reference the actual token literal
-			panic(&RuntimeError{binary.Operator, fmt.Sprintf("Left operand '%s' is string but right operand '%f' is double.", leftString, rightDouble)})
-		}
-		if (!leftIsDouble && !leftIsString) || (!rightIsDouble && !rightIsString) {
-			panic(&RuntimeError{binary.Operator, "Operands must be two numbers or two strings."})
-		}
+		return Number(leftDouble * rightDouble), nil
+	case scanner.PERCENT:
+		return Number(math.Mod(leftDouble, rightDouble)), nil
+	case scanner.STAR_STAR:
+		return Number(math.Pow(leftDouble, rightDouble)), nil
+	case scanner.TILDE_SLASH:
+		return Number(math.Trunc(leftDouble / rightDouble)), nil
+	case scanner.AMPERSAND, scanner.PIPE, scanner.CARET, scanner.LESS_LESS, scanner.GREATER_GREATER:
+		return bitwise(operator, leftDouble, rightDouble)
+	}
+	panic(fmt.Sprintf("unsupported binary operator: %s", operator.Typ))
+}
+
+// bitwise applies a bitwise operator to two numbers, which must be integers.
+func bitwise(operator scanner.Token, left, right float64) (Value, error) {
+	a, leftOk := number.Integer(left)
+	b, rightOk := number.Integer(right)
+	if !leftOk || !rightOk {
+		return Nil, &RuntimeError{operator, "Operands must be integers."}
+	}
+	switch operator.Typ {
+	case scanner.AMPERSAND:
+		return Number(float64(a & b)), nil
+	case scanner.PIPE:
+		return Number(float64(a | b)), nil
+	case scanner.CARET:
+		return Number(float64(a ^ b)), nil
+	}
+	if b < 0 {
+		return Nil, &RuntimeError{operator, "Shift count can't be negative."}
+	}
+	if operator.Typ == scanner.LESS_LESS {
+		return Number(float64(a << b)), nil
+	}
+	return Number(float64(a >> b)), nil
+}
+
+// add adds two numbers or concatenates two strings.
+func add(operator scanner.Token, left, right Value) (Value, error) {
+	switch {
+	case left.kind == KindNumber && right.kind == KindNumber:
+		return Number(left.num + right.num), nil
+	case left.kind == KindString && right.kind == KindString:
+		return String(left.AsString() + right.AsString()), nil
+	case left.kind == KindNumber && right.kind == KindString:
+		// TODO: improve this to reference the actual token literal
+		return Nil, &RuntimeError{operator, fmt.Sprintf("Left operand '%f' is double but right operand '%s' is string.", left.num, right.AsString())}
+	case left.kind == KindString && right.kind == KindNumber:
+		// TODO: improve this to reference the actual token literal
+		return Nil, &RuntimeError{operator, fmt.Sprintf("Left operand '%s' is string but right operand '%f' is double.", left.AsString(), right.num)}
+	}
+	return Nil, &RuntimeError{operator, "Operands must be two numbers or two strings."}
+}
+
+func numberOperands(operator scanner.Token, left, right Value) (float64, float64, error) {
+	if left.kind != KindNumber || right.kind != KindNumber {
+		return 0, 0, &RuntimeError{operator, "Operands must be numbers."}
+	}
+	return left.num, right.num, nil
+}
+
+func (in *TreeWalkInterpreter) visitCallExpr(expr *ast.Call) (Value, error) {
+	switch callee := expr.Callee.(type) {
+	case *ast.Get:
+		return in.visitMethodCallExpr(expr, callee)
+	case *ast.Super:
+		return in.visitSuperCallExpr(expr, callee)
+	}
+	callee, err := in.evaluate(expr.Callee)
+	if err != nil {
+		return Nil, err
+	}
+	arguments, err := in.arguments(expr.Arguments)
+	if err != nil {
+		return Nil, err
 	}
-	return nil // unreachable
+	return in.call(expr.Paren, callee, arguments)
 }
 
-func (in *TreeWalkInterpreter) VisitCallExpr(expr *ast.Call) interface{} {
-	callee := in.evaluate(expr.Callee)
-	var arguments []interface{}
-	for _, argument := range expr.Arguments {
-		arguments = append(arguments, in.evaluate(argument))
+// visitMethodCallExpr calls a method of an instance without binding it first.
+// Anything else, including a field holding a function, is called like any
+// other callee.
+func (in *TreeWalkInterpreter) visitMethodCallExpr(expr *ast.Call, get *ast.Get) (Value, error) {
+	object, err := in.evaluate(get.Object)
+	if err != nil {
+		return Nil, err
 	}
-	function, ok := callee.(LoxCallable)
+	var callee Value
+	var method *LoxFunction
+	instance := object.AsInstance()
+	if instance != nil {
+		callee, method, err = instance.property(get.Name)
+	} else {
+		callee, err = getProperty(get.Name, object)
+	}
+	if err != nil {
+		return Nil, err
+	}
+	arguments, err := in.arguments(expr.Arguments)
+	if err != nil {
+		return Nil, err
+	}
+	if method != nil {
+		return in.callMethod(expr.Paren, instance, method, arguments)
+	}
+	return in.call(expr.Paren, callee, arguments)
+}
+
+func (in *TreeWalkInterpreter) visitSuperCallExpr(expr *ast.Call, super *ast.Super) (Value, error) {
+	local, ok := in.locals[super]
 	if !ok {
-		panic(&RuntimeError{expr.Paren, "Can only call functions and classes."})
+		return Nil, &RuntimeError{super.Keyword, "no resolved local"}
+	}
+	instance, method, err := in.superMethod(super, local)
+	if err != nil {
+		return Nil, err
 	}
-	if expected, got := function.Arity(), len(arguments); expected != got {
-		panic(&RuntimeError{expr.Paren, fmt.Sprintf("Expected %d arguments but got %d.", expected, got)})
+	arguments, err := in.arguments(expr.Arguments)
+	if err != nil {
+		return Nil, err
 	}
-	return function.Call(in, arguments)
+	return in.callMethod(expr.Paren, instance, method, arguments)
 }
 
-func (in *TreeWalkInterpreter) VisitGetExpr(get *ast.Get) interface{} {
-	object := in.evaluate(get.Object)
-	if instance, ok := object.(*LoxInstance); ok {
-		return instance.Get(get.Name)
+func (in *TreeWalkInterpreter) arguments(exprs []ast.Expr) ([]Value, error) {
+	arguments := make([]Value, len(exprs))
+	for i, argument := range exprs {
+		var err error
+		if arguments[i], err = in.evaluate(argument); err != nil {
+			return nil, err
+		}
 	}
-	panic(&RuntimeError{get.Name, "Only instances have properties."})
+	return arguments, nil
 }
 
-func (in *TreeWalkInterpreter) VisitGroupingExpr(grouping *ast.Grouping) interface{} {
-	return in.evaluate(grouping.Expression)
+func (in *TreeWalkInterpreter) call(paren scanner.Token, callee Value, arguments []Value) (Value, error) {
+	function := callee.AsCallable()
+	if function == nil {
+		return Nil, &RuntimeError{paren, "Can only call functions and classes."}
+	}
+	return in.invoke(paren, function, arguments, function.Call)
 }
 
-func (in *TreeWalkInterpreter) VisitLiteralExpr(literal *ast.Literal) interface{} {
-	return literal.Value
+// callMethod calls method with "this" bound to instance. It avoids allocating
+// the bound method that getting the property and calling it would.
+func (in *TreeWalkInterpreter) callMethod(paren scanner.Token, instance *LoxInstance, method *LoxFunction, arguments []Value) (Value, error) {
+	return in.invoke(paren, method, arguments, func(in *TreeWalkInterpreter, arguments []Value) (Value, error) {
+		return method.call(in, method.this(instance), arguments)
+	})
 }
 
-func (in *TreeWalkInterpreter) VisitLogicalExpr(logical *ast.Logical) interface{} {
-	left := in.evaluate(logical.Left)
-	leftIsTruthy := in.isTruthy(left)
+// invoke checks the call to function and records its frame while call runs
+// it.
+func (in *TreeWalkInterpreter) invoke(paren scanner.Token, function LoxCallable, arguments []Value, call func(*TreeWalkInterpreter, []Value) (Value, error)) (Value, error) {
+	if err := CheckArity(function, len(arguments)); err != nil {
+		return Nil, &RuntimeError{paren, err.Error()}
+	}
+	if len(in.frames) >= in.maxCallDepth {
+		return Nil, &RuntimeError{paren, "Stack overflow."}
+	}
+	if err := in.checkInterrupt(paren); err != nil {
+		return Nil, err
+	}
+	in.frames = append(in.frames, callFrame{function, paren.Line})
+	result, err := call(in, arguments)
+	token, isLoxError := errorToken(err)
+	if isLoxError && in.trace == nil {
+		// record the trace before the frame of the call that raised it unwinds
+		in.trace = in.stackTrace(token.Line)
+	}
+	in.frames = in.frames[:len(in.frames)-1]
+	if err != nil {
+		if !isLoxError {
+			// errors from Go functions are reported at the call site
+			err = &RuntimeError{paren, err.Error()}
+		}
+		return Nil, err
+	}
+	return result, nil
+}
+
+func (in *TreeWalkInterpreter) visitGetExpr(get *ast.Get) (Value, error) {
+	object, err := in.evaluate(get.Object)
+	if err != nil {
+		return Nil, err
+	}
+	return getProperty(get.Name, object)
+}
+
+func getProperty(name scanner.Token, object Value) (Value, error) {
+	switch object.kind {
+	case KindInstance:
+		return object.AsInstance().Get(name)
+	case KindGoObject:
+		return object.AsGoObject().Get(name)
+	case KindList:
+		return method(listMethods, object, name)
+	case KindMap:
+		return method(mapMethods, object, name)
+	case KindModule:
+		return object.AsModule().Get(name)
+	}
+	return Nil, &RuntimeError{name, "Only instances have properties."}
+}
+
+func (in *TreeWalkInterpreter) visitGetIndexExpr(index *ast.GetIndex) (Value, error) {
+	object, err := in.evaluate(index.Object)
+	if err != nil {
+		return Nil, err
+	}
+	i, err := in.evaluate(index.Index)
+	if err != nil {
+		return Nil, err
+	}
+	return getIndex(index.Bracket, object, i)
+}
+
+func getIndex(bracket scanner.Token, object, index Value) (Value, error) {
+	switch object.kind {
+	case KindList:
+		return object.AsList().Index(bracket, index)
+	case KindMap:
+		return object.AsMap().Index(bracket, index)
+	}
+	return Nil, &RuntimeError{bracket, "Only lists and maps can be indexed."}
+}
+
+func (in *TreeWalkInterpreter) visitListExpr(list *ast.List) (Value, error) {
+	elements, err := in.arguments(list.Elements)
+	if err != nil {
+		return Nil, err
+	}
+	return List(NewLoxList(elements)), nil
+}
+
+func (in *TreeWalkInterpreter) visitLogicalExpr(logical *ast.Logical) (Value, error) {
+	left, err := in.evaluate(logical.Left)
+	if err != nil {
+		return Nil, err
+	}
+	leftIsTruthy := left.Truthy()
 	switch logical.Operator.Typ {
 	case scanner.OR:
 		if leftIsTruthy {
-			return left
+			return left, nil
 		}
 	case scanner.AND:
 		if !leftIsTruthy {
-			return left
+			return left, nil
 		}
 	default:
 		panic(fmt.Sprintf("unsupported logical operator: %s", logical.Operator.Typ))
@@ -189,275 +596,396 @@ func (in *TreeWalkInterpreter) VisitLogicalExpr(logical *ast.Logical) interface{
 	return in.evaluate(logical.Right)
 }
 
-func (in *TreeWalkInterpreter) VisitSetExpr(set *ast.Set) interface{} {
-	object := in.evaluate(set.Object)
-	if instance, ok := object.(*LoxInstance); ok {
-		value := in.evaluate(set.Value)
-		instance.Set(set.Name, value)
-		return value
+func (in *TreeWalkInterpreter) visitConditionalExpr(conditional *ast.Conditional) (Value, error) {
+	condition, err := in.evaluate(conditional.Condition)
+	if err != nil {
+		return Nil, err
 	}
-	panic(&RuntimeError{set.Name, fmt.Sprintf("Only instances have fields.")})
+	if condition.Truthy() {
+		return in.evaluate(conditional.Then)
+	}
+	return in.evaluate(conditional.Else)
 }
 
-func (in *TreeWalkInterpreter) VisitSuperExpr(super *ast.Super) interface{} {
-	distance, ok := in.locals[super]
-	if !ok {
-		panic(&RuntimeError{super.Keyword, "no resolved local"})
+func (in *TreeWalkInterpreter) visitSetExpr(set *ast.Set) (Value, error) {
+	object, err := in.evaluate(set.Object)
+	if err != nil {
+		return Nil, err
 	}
-	superclass, ok := in.environment.GetAt(distance, "super").(*LoxClass)
-	if !ok {
-		panic(&RuntimeError{super.Keyword, "didn't find super class"})
+	if err := checkFields(set.Name, object); err != nil {
+		return Nil, err
 	}
-	object, ok := in.environment.GetAt(distance-1, "this").(*LoxInstance)
-	if !ok {
-		panic(&RuntimeError{super.Keyword, "didn't find super class instance"})
+	value, err := in.evaluate(set.Value)
+	if err != nil {
+		return Nil, err
 	}
-	method := superclass.FindMethod(super.Method.Lexeme)
-	if method == nil {
-		panic(&RuntimeError{super.Method, fmt.Sprintf("Undefined property '%s'.", super.Method.Lexeme)})
-	}
-	return method.Bind(object)
-}
-
-func (in *TreeWalkInterpreter) VisitThisExpr(this *ast.This) interface{} {
-	return in.lookUpVariable(this.Keyword, this)
+	return value, setProperty(set.Name, object, value)
 }
 
-func (in *TreeWalkInterpreter) VisitUnaryExpr(unary *ast.Unary) interface{} {
-	right := in.evaluate(unary.Right)
-
-	switch unary.Operator.Typ {
-	case scanner.MINUS:
-		in.checkNumberOperand(unary.Operator, right)
-		return -mustDouble(right)
-	case scanner.BANG:
-		return !in.isTruthy(right)
+func (in *TreeWalkInterpreter) visitSetIndexExpr(index *ast.SetIndex) (Value, error) {
+	object, err := in.evaluate(index.Object)
+	if err != nil {
+		return Nil, err
 	}
-	return nil // unreachable
+	i, err := in.evaluate(index.Index)
+	if err != nil {
+		return Nil, err
+	}
+	value, err := in.evaluate(index.Value)
+	if err != nil {
+		return Nil, err
+	}
+	return value, setIndex(index.Bracket, object, i, value)
 }
 
-func (in *TreeWalkInterpreter) VisitVariableExpr(variable *ast.Variable) interface{} {
-	return in.lookUpVariable(variable.Name, variable)
+func setIndex(bracket scanner.Token, object, index, value Value) error {
+	switch object.kind {
+	case KindList:
+		return object.AsList().SetIndex(bracket, index, value)
+	case KindMap:
+		return object.AsMap().SetIndex(bracket, index, value)
+	}
+	return &RuntimeError{bracket, "Only lists and maps can be indexed."}
 }
 
-func (in *TreeWalkInterpreter) lookUpVariable(name scanner.Token, expr ast.Expr) interface{} {
-	distance, ok := in.locals[expr]
-	if ok {
-		return in.environment.GetAt(distance, name.Lexeme)
-	} else {
-		return in.globals.Get(name)
+func (in *TreeWalkInterpreter) visitMapExpr(expr *ast.Map) (Value, error) {
+	m := NewLoxMap()
+	for i, key := range expr.Keys {
+		k, err := in.evaluate(key)
+		if err != nil {
+			return Nil, err
+		}
+		v, err := in.evaluate(expr.Values[i])
+		if err != nil {
+			return Nil, err
+		}
+		if err := m.Set(k, v); err != nil {
+			return Nil, &RuntimeError{expr.Brace, err.Error()}
+		}
 	}
+	return Map(m), nil
 }
 
-func (in *TreeWalkInterpreter) checkNumberOperand(operator scanner.Token, operand interface{}) {
-	if _, ok := operand.(float64); ok {
-		return
+// checkFields is checked before the value of a set expression is evaluated. A
+// module has properties but its variables can only be assigned from inside it.
+func checkFields(name scanner.Token, object Value) error {
+	switch object.kind {
+	case KindInstance, KindGoObject:
+		return nil
+	case KindModule:
+		return &RuntimeError{name, "Can't assign to a module's variables."}
 	}
-	panic(&RuntimeError{operator, "Operand must be a number."})
+	return &RuntimeError{name, "Only instances have fields."}
 }
 
-func (in *TreeWalkInterpreter) checkNumberOperands(operator scanner.Token, left, right interface{}) {
-	_, leftOk := left.(float64)
-	_, rightOk := right.(float64)
-	if leftOk && rightOk {
-		return
+func setProperty(name scanner.Token, object, value Value) error {
+	if object.kind == KindGoObject {
+		return object.AsGoObject().Set(name, value)
 	}
-	panic(&RuntimeError{operator, "Operands must be numbers."})
+	object.AsInstance().Set(name, value)
+	return nil
 }
 
-// isTruthy returns false only for 'nil' and the boolean value false
-func (in *TreeWalkInterpreter) isTruthy(it interface{}) bool {
-	if check.IsNil(it) {
-		return false
+func (in *TreeWalkInterpreter) visitSuperExpr(super *ast.Super) (Value, error) {
+	local, ok := in.locals[super]
+	if !ok {
+		return Nil, &RuntimeError{super.Keyword, "no resolved local"}
 	}
-	if b, ok := it.(bool); ok {
-		return b
+	object, method, err := in.superMethod(super, local)
+	if err != nil {
+		return Nil, err
 	}
-	return true
+	return Callable(method.Bind(object)), nil
 }
 
-func (in *TreeWalkInterpreter) isEqual(a, b interface{}) bool {
-	if check.IsNil(a) && check.IsNil(b) {
-		return true
+// superMethod returns "this" and the unbound superclass method.
+func (in *TreeWalkInterpreter) superMethod(super *ast.Super, local local) (*LoxInstance, *LoxFunction, error) {
+	superclass, ok := in.environment.GetAt(local.depth, local.slot).AsCallable().(*LoxClass)
+	if !ok {
+		return nil, nil, &RuntimeError{super.Keyword, "didn't find super class"}
+	}
+	// "this" is the only local in the environment inside the one holding "super"
+	object := in.environment.GetAt(local.depth-1, 0).AsInstance()
+	if object == nil {
+		return nil, nil, &RuntimeError{super.Keyword, "didn't find super class instance"}
 	}
-	if check.IsNil(a) {
-		return false
+	method := superclass.FindMethod(super.Method.Lexeme)
+	if method == nil {
+		return nil, nil, &RuntimeError{super.Method, fmt.Sprintf("Undefined property '%s'.", super.Method.Lexeme)}
 	}
-	return a == b // TODO: will this work?
+	return object, method, nil
 }
 
-func (in *TreeWalkInterpreter) stringify(it interface{}) string {
-	if check.IsNil(it) {
-		return "nil"
+func (in *TreeWalkInterpreter) visitUnaryExpr(unary *ast.Unary) (Value, error) {
+	right, err := in.evaluate(unary.Right)
+	if err != nil {
+		return Nil, err
 	}
-	if double, ok := it.(float64); ok {
-		text := strconv.FormatFloat(double, 'f', -1, 64)
-		text = maybeInteger(text)
-		return text
+
+	switch unary.Operator.Typ {
+	case scanner.MINUS:
+		if right.kind != KindNumber {
+			return Nil, &RuntimeError{unary.Operator, "Operand must be a number."}
+		}
+		return Number(-right.num), nil
+	case scanner.TILDE:
+		return complement(unary.Operator, right)
+	case scanner.BANG:
+		return Bool(!right.Truthy()), nil
 	}
-	if str, ok := it.(string); ok {
-		return str
+	panic(fmt.Sprintf("unsupported unary operator: %s", unary.Operator.Typ))
+}
+
+// complement returns the bitwise complement of an integer.
+func complement(operator scanner.Token, value Value) (Value, error) {
+	integer, ok := number.Integer(value.num)
+	if value.kind != KindNumber || !ok {
+		return Nil, &RuntimeError{operator, "Operand must be an integer."}
 	}
-	if str, ok := it.(fmt.Stringer); ok {
-		return str.String()
+	return Number(float64(^integer)), nil
+}
+
+func (in *TreeWalkInterpreter) visitAssignExpr(assign *ast.Assign) (Value, error) {
+	value, err := in.evaluate(assign.Value)
+	if err != nil {
+		return Nil, err
 	}
-	if b, ok := it.(bool); ok {
-		if b {
-			return "true"
-		}
-		return "false"
+	return value, in.assignVariable(assign.Name, assign, value)
+}
+
+// assignVariable assigns to the variable that expr, an assignment or variable
+// expression, was resolved to.
+func (in *TreeWalkInterpreter) assignVariable(name scanner.Token, expr ast.Expr, value Value) error {
+	if local, ok := in.locals[expr]; ok {
+		in.environment.AssignAt(local.depth, local.slot, value)
+		return nil
 	}
-	panic(fmt.Sprintf("unhandled type in stringify: %v", it)) // TODO
+	return in.globals.Assign(name, value)
 }
 
-func (in *TreeWalkInterpreter) evaluate(expr ast.Expr) interface{} {
-	return in.Accept(expr)
+func (in *TreeWalkInterpreter) lookUpVariable(name scanner.Token, expr ast.Expr) (Value, error) {
+	if local, ok := in.locals[expr]; ok {
+		return in.environment.GetAt(local.depth, local.slot), nil
+	}
+	return in.globals.Get(name)
 }
 
-func (in *TreeWalkInterpreter) execute(stmt ast.Stmt) {
+// execute dispatches on the statement type the same way evaluate does for
+// expressions.
+func (in *TreeWalkInterpreter) execute(stmt ast.Stmt) (completion, error) {
 	switch v := stmt.(type) {
 	case *ast.Class:
-		v.AcceptVoid(in)
+		return normal, in.visitClassStmt(v)
 	case *ast.IfStmt:
-		v.AcceptVoid(in)
+		return in.visitIfStmt(v)
+	case *ast.ImportStmt:
+		return normal, in.visitImportStmt(v)
 	case *ast.Block:
-		v.AcceptVoid(in)
+		return in.executeBlock(v.Statements, NewEnvironment(in.environment))
+	case *ast.BreakStmt:
+		return breakLoop, nil
+	case *ast.ContinueStmt:
+		return continueLoop, nil
 	case *ast.Expression:
-		v.AcceptVoid(in)
+		_, err := in.evaluate(v.Expression)
+		return normal, err
 	case *ast.Function:
-		v.AcceptVoid(in)
+		in.define(v.Name.Lexeme, Callable(in.newFunction(v, false)))
+		return normal, nil
 	case *ast.Print:
-		v.AcceptVoid(in)
+		return normal, in.visitPrintStmt(v)
 	case *ast.ReturnStmt:
-		v.AcceptVoid(in)
+		return in.visitReturnStmt(v)
+	case *ast.Throw:
+		return normal, in.visitThrowStmt(v)
+	case *ast.Try:
+		return in.visitTryStmt(v)
 	case *ast.VarStmt:
-		v.AcceptVoid(in)
+		return normal, in.visitVarStmt(v)
 	case *ast.While:
-		v.AcceptVoid(in)
+		return in.visitWhileStmt(v)
 	default:
-		exit.Exitf(exit.ExitSyntaxError, "unsupported statement: %s", check.TypeOf(stmt))
+		panic(fmt.Sprintf("unsupported statement: %s", check.TypeOf(stmt)))
 	}
 }
 
-func (in *TreeWalkInterpreter) executeBlock(statements []ast.Stmt, environment *Environment) {
+// executeBlock executes statements in environment until one of them completes
+// abruptly.
+func (in *TreeWalkInterpreter) executeBlock(statements []ast.Stmt, environment *Environment) (completion, error) {
 	previous := in.environment // save the current environment scope before executing block scope
-	defer func() {
-		in.environment = previous
-	}()
 	in.environment = environment
 	for _, statement := range statements {
-		in.execute(statement)
+		result, err := in.execute(statement)
+		if err != nil || result.kind != completeNormal {
+			in.environment = previous
+			return result, err
+		}
 	}
+	in.environment = previous
+	return normal, nil
 }
 
-func (in *TreeWalkInterpreter) Resolve(expr ast.Expr, depth int) {
-	in.locals[expr] = depth
+func (in *TreeWalkInterpreter) Resolve(expr ast.Expr, depth, slot int) {
+	in.locals[expr] = local{depth, slot}
 }
 
-func (in *TreeWalkInterpreter) VisitBlockStmtVoid(block *ast.Block) {
-	in.executeBlock(block.Statements, NewEnvironment(in.environment))
-}
-
-func (in *TreeWalkInterpreter) VisitExpressionStmtVoid(stmt *ast.Expression) {
-	in.evaluate(stmt.Expression)
+// define defines a variable in the current scope and returns its slot, which
+// is -1 for globals.
+func (in *TreeWalkInterpreter) define(name string, value Value) int {
+	if in.environment == nil {
+		in.globals.Define(name, value)
+		return -1
+	}
+	return in.environment.Define(value)
 }
 
-func (in *TreeWalkInterpreter) VisitFunctionStmtVoid(stmt *ast.Function) {
-	function := NewLoxFunction(stmt, in.environment, false)
-	in.environment.Define(stmt.Name.Lexeme, function)
+func (in *TreeWalkInterpreter) visitClassStmt(class *ast.Class) error {
+	var superclass Value
+	if class.Superclass != nil {
+		var err error
+		if superclass, err = in.evaluate(class.Superclass); err != nil {
+			return err
+		}
+	}
+	return in.defineClass(class, superclass, nil)
 }
 
-func (in *TreeWalkInterpreter) VisitClassStmtVoid(class *ast.Class) {
+// defineClass defines class with the evaluated superclass. bodies holds the
+// compiled method bodies when the class was compiled into closures.
+func (in *TreeWalkInterpreter) defineClass(class *ast.Class, superclass Value, bodies map[*ast.Function]execFn) error {
 	var superklass *LoxClass
 	if class.Superclass != nil {
-		superclass := in.evaluate(class.Superclass)
-		if v, ok := superclass.(*LoxClass); ok {
+		if v, ok := superclass.AsCallable().(*LoxClass); ok {
 			superklass = v
 		} else {
-			panic(&RuntimeError{class.Superclass.Name, "Superclass must be a class."})
+			return &RuntimeError{class.Superclass.Name, "Superclass must be a class."}
 		}
 	}
-	in.environment.Define(class.Name.Lexeme, nil)
+	slot := in.define(class.Name.Lexeme, Nil)
 	if class.Superclass != nil {
 		in.environment = NewEnvironment(in.environment)
-		in.environment.Define("super", superklass)
+		in.environment.Define(Callable(superklass))
 	}
 	methods := make(map[string]*LoxFunction)
 	for _, method := range class.Methods {
 		isInitializer := method.Name.Lexeme == "init"
-		function := NewLoxFunction(method, in.environment, isInitializer)
+		function := in.newFunction(method, isInitializer)
+		function.body = bodies[method]
 		methods[method.Name.Lexeme] = function
 	}
 	klass := NewLoxClass(class.Name.Lexeme, superklass, methods)
 	if superklass != nil {
 		in.environment = in.environment.enclosing
 	}
-	in.environment.Assign(class.Name, klass)
+	if slot < 0 {
+		return in.globals.Assign(class.Name, Callable(klass))
+	}
+	in.environment.AssignAt(0, slot, Callable(klass))
+	return nil
 }
 
-func (in *TreeWalkInterpreter) VisitIfStmtStmtVoid(stmt *ast.IfStmt) {
-	condition := in.evaluate(stmt.Condition)
-	if in.isTruthy(condition) {
-		in.execute(stmt.ThenBranch)
-	} else {
-		if stmt.ElseBranch != nil {
-			in.execute(stmt.ElseBranch)
-		}
+func (in *TreeWalkInterpreter) visitIfStmt(stmt *ast.IfStmt) (completion, error) {
+	condition, err := in.evaluate(stmt.Condition)
+	if err != nil {
+		return normal, err
+	}
+	if condition.Truthy() {
+		return in.execute(stmt.ThenBranch)
 	}
+	if stmt.ElseBranch != nil {
+		return in.execute(stmt.ElseBranch)
+	}
+	return normal, nil
 }
 
-func (in *TreeWalkInterpreter) VisitPrintStmtVoid(stmt *ast.Print) {
-	value := in.evaluate(stmt.Expression)
-	fmt.Println(in.stringify(value))
+func (in *TreeWalkInterpreter) visitPrintStmt(stmt *ast.Print) error {
+	value, err := in.evaluate(stmt.Expression)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(in.stdout, value.String())
+	return nil
 }
 
-func (in *TreeWalkInterpreter) VisitReturnStmtStmtVoid(stmt *ast.ReturnStmt) {
-	var value interface{}
+func (in *TreeWalkInterpreter) visitReturnStmt(stmt *ast.ReturnStmt) (completion, error) {
+	var value Value
 	if stmt.Value != nil {
-		value = in.evaluate(stmt.Value)
+		var err error
+		if value, err = in.evaluate(stmt.Value); err != nil {
+			return normal, err
+		}
 	}
-	panic(&Return{value})
+	return completion{completeReturn, value}, nil
 }
 
-func (in *TreeWalkInterpreter) VisitVarStmtStmtVoid(stmt *ast.VarStmt) {
-	var value interface{}
-	if stmt.Initializer != nil {
-		value = in.evaluate(stmt.Initializer)
+func (in *TreeWalkInterpreter) visitThrowStmt(stmt *ast.Throw) error {
+	value, err := in.evaluate(stmt.Value)
+	if err != nil {
+		return err
 	}
-	in.environment.Define(stmt.Name.Lexeme, value)
+	return &thrown{stmt.Keyword, value, in.errorClass}
 }
 
-func (in *TreeWalkInterpreter) VisitWhileStmtVoid(while *ast.While) {
-	for in.isTruthy(in.evaluate(while.Condition)) {
-		in.execute(while.Body)
+func (in *TreeWalkInterpreter) visitTryStmt(stmt *ast.Try) (completion, error) {
+	environment := in.environment
+	result, err := in.execute(stmt.Body)
+	if err != nil && stmt.Catch != nil {
+		if value, ok := in.caught(err); ok {
+			in.environment = environment
+			scope := NewEnvironment(environment)
+			scope.Define(value)
+			result, err = in.executeBlock(stmt.Catch.Body, scope)
+		}
 	}
-}
-
-func (in *TreeWalkInterpreter) VisitAssignExpr(assign *ast.Assign) interface{} {
-	value := in.evaluate(assign.Value)
-	distance, ok := in.locals[assign]
-	if ok {
-		in.environment.AssignAt(distance, assign.Name, value)
-	} else {
-		in.globals.Assign(assign.Name, value)
+	if stmt.Finally == nil {
+		return result, err
 	}
-	return value
+	in.environment = environment
+	return in.finish(func() (completion, error) {
+		return in.execute(stmt.Finally)
+	}, result, err)
 }
 
-func mustDouble(it interface{}) float64 {
-	if double, ok := it.(float64); ok {
-		return double
+func (in *TreeWalkInterpreter) visitVarStmt(stmt *ast.VarStmt) error {
+	var value Value
+	if stmt.Initializer != nil {
+		var err error
+		if value, err = in.evaluate(stmt.Initializer); err != nil {
+			return err
+		}
 	}
-	panic(fmt.Sprintf("expression value did not evaluate to a float64: %v", it)) // TODO
+	in.define(stmt.Name.Lexeme, value)
+	return nil
 }
 
-var matchTrailingZeros = regexp.MustCompile("\\.0+$")
-
-func maybeInteger(num string) string {
-	if matchTrailingZeros.Match([]byte(num)) {
-		return num[:strings.Index(num, ".")]
+func (in *TreeWalkInterpreter) visitWhileStmt(while *ast.While) (completion, error) {
+	for {
+		condition, err := in.evaluate(while.Condition)
+		if err != nil {
+			return normal, err
+		}
+		if !condition.Truthy() {
+			return normal, nil
+		}
+		result, err := in.execute(while.Body)
+		if err != nil {
+			return normal, err
+		}
+		switch result.kind {
+		case completeBreak:
+			return normal, nil
+		case completeReturn:
+			return result, nil
+		}
+		if while.Increment != nil {
+			if _, err := in.evaluate(while.Increment); err != nil {
+				return normal, err
+			}
+		}
+		if err := in.checkInterrupt(while.Keyword); err != nil {
+			return normal, err
+		}
 	}
-	return num
 }
 
 type RuntimeError struct {
@@ -471,8 +999,10 @@ func (e *RuntimeError) Error() string {
 
 var _ error = (*RuntimeError)(nil)
 
+// LoxCallable is a function or class. An error returned by Call that isn't a
+// RuntimeError is reported at the call site.
 type LoxCallable interface {
-	Call(*TreeWalkInterpreter, []interface{}) interface{}
+	Call(*TreeWalkInterpreter, []Value) (Value, error)
 	Arity() int
 }
 
@@ -484,8 +1014,8 @@ func (*nativeClock) Arity() int {
 	return 0
 }
 
-func (*nativeClock) Call(in *TreeWalkInterpreter, arguments []interface{}) interface{} {
-	return (float64)(time.Now().Unix())
+func (*nativeClock) Call(in *TreeWalkInterpreter, arguments []Value) (Value, error) {
+	return Number(float64(time.Now().Unix())), nil
 }
 
 func (*nativeClock) String() string {
@@ -495,85 +1025,138 @@ func (*nativeClock) String() string {
 type LoxFunction struct {
 	declaration   *ast.Function
 	closure       *Environment
+	globals       *Globals // the globals of the module that declared it or nil
 	isInitializer bool
+	body          execFn // the compiled body or nil to walk the declaration
 }
 
 var _ LoxCallable = (*LoxFunction)(nil)
 
 func NewLoxFunction(declaration *ast.Function, closure *Environment, isInitializer bool) *LoxFunction {
-	return &LoxFunction{declaration, closure, isInitializer}
+	return &LoxFunction{declaration: declaration, closure: closure, isInitializer: isInitializer}
+}
+
+// newFunction returns a function declared in the current scope. It sees the
+// globals of the module being run wherever it is called from.
+func (in *TreeWalkInterpreter) newFunction(declaration *ast.Function, isInitializer bool) *LoxFunction {
+	function := NewLoxFunction(declaration, in.environment, isInitializer)
+	function.globals = in.globals
+	return function
 }
 
 func (f *LoxFunction) Arity() int {
 	return len(f.declaration.Params)
 }
 
-func (f *LoxFunction) Call(in *TreeWalkInterpreter, arguments []interface{}) (ret interface{}) {
-	environment := NewEnvironment(f.closure)
+func (f *LoxFunction) Call(in *TreeWalkInterpreter, arguments []Value) (Value, error) {
+	return f.call(in, f.closure, arguments)
+}
+
+// call runs the function in an environment enclosed by closure, which differs
+// from f.closure when a method is called without binding it first.
+func (f *LoxFunction) call(in *TreeWalkInterpreter, closure *Environment, arguments []Value) (Value, error) {
+	environment := NewEnvironment(closure)
 	for i := range f.declaration.Params {
-		environment.Define(f.declaration.Params[i].Lexeme, arguments[i])
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			if re, ok := r.(*Return); ok {
-				if f.isInitializer {
-					ret = f.closure.GetAt(0, "this")
-				} else {
-					ret = re.value
-				}
-			} else {
-				panic(r)
-			}
-		}
-	}()
-	in.executeBlock(f.declaration.Body, environment)
+		environment.Define(arguments[i])
+	}
+	previousGlobals := in.globals
+	if f.globals != nil {
+		in.globals = f.globals
+	}
+	var result completion
+	var err error
+	if f.body != nil {
+		previous := in.environment
+		in.environment = environment
+		result, err = f.body(in)
+		in.environment = previous
+	} else {
+		result, err = in.executeBlock(f.declaration.Body, environment)
+	}
+	in.globals = previousGlobals
+	if err != nil {
+		return Nil, err
+	}
 	if f.isInitializer {
-		return f.closure.GetAt(0, "this")
+		return closure.GetAt(0, 0), nil // "this"
 	}
-	return nil
+	return result.value, nil
 }
 
 func (f *LoxFunction) String() string {
-	return "<fn " + f.declaration.Name.Lexeme + ">"
+	return "<fn " + f.name() + ">"
+}
+
+// name returns the name the function was declared with. A lambda is named by
+// its fun keyword and is called anonymous.
+func (f *LoxFunction) name() string {
+	if f.declaration.Name.Typ == scanner.FUN {
+		return "anonymous"
+	}
+	return f.declaration.Name.Lexeme
 }
 
 func (f *LoxFunction) Bind(instance *LoxInstance) *LoxFunction {
-	environment := NewEnvironment(f.closure)
-	environment.Define("this", instance)
-	return NewLoxFunction(f.declaration, environment, f.isInitializer)
+	bound := NewLoxFunction(f.declaration, f.this(instance), f.isInitializer)
+	bound.globals = f.globals
+	bound.body = f.body
+	return bound
 }
 
-type Return struct {
-	value interface{}
+// this returns the environment binding "this" to instance in the method.
+func (f *LoxFunction) this(instance *LoxInstance) *Environment {
+	environment := NewEnvironment(f.closure)
+	environment.Define(Instance(instance))
+	return environment
 }
 
 type LoxClass struct {
-	name       string
-	superclass *LoxClass
-	methods    map[string]*LoxFunction
+	name        string
+	superclass  *LoxClass
+	methods     map[string]*LoxFunction // including inherited methods
+	initializer *LoxFunction
+	shape       *shape // the shape of new instances
 }
 
 var _ LoxCallable = (*LoxClass)(nil)
 
+// NewLoxClass copies the inherited methods into the class so finding a method
+// doesn't walk up the superclasses. Classes can't change once they are
+// defined so the copies never go stale.
 func NewLoxClass(name string, superclass *LoxClass, methods map[string]*LoxFunction) *LoxClass {
-	return &LoxClass{name, superclass, methods}
+	resolved := make(map[string]*LoxFunction)
+	if superclass != nil {
+		for name, method := range superclass.methods {
+			resolved[name] = method
+		}
+	}
+	for name, method := range methods {
+		resolved[name] = method
+	}
+	return &LoxClass{
+		name:        name,
+		superclass:  superclass,
+		methods:     resolved,
+		initializer: resolved["init"],
+		shape:       &shape{},
+	}
 }
 
 func (c *LoxClass) Arity() int {
-	initializer := c.FindMethod("init")
-	if initializer == nil {
+	if c.initializer == nil {
 		return 0
 	}
-	return initializer.Arity()
+	return c.initializer.Arity()
 }
 
-func (c *LoxClass) Call(in *TreeWalkInterpreter, args []interface{}) interface{} {
+func (c *LoxClass) Call(in *TreeWalkInterpreter, args []Value) (Value, error) {
 	instance := NewLoxInstance(c)
-	initializer := c.FindMethod("init")
-	if initializer != nil {
-		initializer.Bind(instance).Call(in, args)
+	if c.initializer != nil {
+		if _, err := c.initializer.call(in, c.initializer.this(instance), args); err != nil {
+			return Nil, err
+		}
 	}
-	return instance
+	return Instance(instance), nil
 }
 
 func (c *LoxClass) String() string {
@@ -581,36 +1164,46 @@ func (c *LoxClass) String() string {
 }
 
 func (c *LoxClass) FindMethod(name string) *LoxFunction {
-	if method, ok := c.methods[name]; ok {
-		return method
-	}
-	if c.superclass != nil {
-		return c.superclass.FindMethod(name)
-	}
-	return nil
+	return c.methods[name]
 }
 
 type LoxInstance struct {
 	class  *LoxClass
-	fields map[string]interface{}
+	shape  *shape
+	fields []Value // indexed by the slots of shape
 }
 
 func NewLoxInstance(class *LoxClass) *LoxInstance {
-	return &LoxInstance{class, make(map[string]interface{})}
+	return &LoxInstance{class: class, shape: class.shape}
 }
 
-func (i *LoxInstance) Get(name scanner.Token) interface{} {
-	if field, ok := i.fields[name.Lexeme]; ok {
-		return field
+func (i *LoxInstance) Get(name scanner.Token) (Value, error) {
+	field, method, err := i.property(name)
+	if method != nil {
+		return Callable(method.Bind(i)), nil
+	}
+	return field, err
+}
+
+// property returns the field called name or, if there is no such field, the
+// unbound method.
+func (i *LoxInstance) property(name scanner.Token) (Value, *LoxFunction, error) {
+	if slot, ok := i.shape.slots[name.Lexeme]; ok {
+		return i.fields[slot], nil, nil
 	}
 	if method := i.class.FindMethod(name.Lexeme); method != nil {
-		return method.Bind(i)
+		return Nil, method, nil
 	}
-	panic(&RuntimeError{name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme)})
+	return Nil, nil, undefinedProperty(name)
 }
 
-func (i *LoxInstance) Set(name scanner.Token, value interface{}) {
-	i.fields[name.Lexeme] = value
+func (i *LoxInstance) Set(name scanner.Token, value Value) {
+	if slot, ok := i.shape.slots[name.Lexeme]; ok {
+		i.fields[slot] = value
+		return
+	}
+	i.shape = i.shape.add(name.Lexeme)
+	i.fields = append(i.fields, value)
 }
 
 func (i *LoxInstance) String() string {
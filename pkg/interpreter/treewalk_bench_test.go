@@ -0,0 +1,146 @@
+package interpreter_test
+
+import (
+	"os"
+	"testing"
+
+	ast "github.com/rhomel/golox/pkg/ast/gen"
+	"github.com/rhomel/golox/pkg/interpreter"
+	"github.com/rhomel/golox/pkg/parser"
+	"github.com/rhomel/golox/pkg/resolver"
+	"github.com/rhomel/golox/pkg/scanner"
+)
+
+// benchmarkSample loads the functions declared in a sample and then measures
+// evaluating expression. The samples themselves run for too long to be
+// benchmarked directly.
+func benchmarkSample(b *testing.B, file, expression string) {
+	source, err := os.ReadFile(file)
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkSource(b, string(source), expression)
+}
+
+// benchmarkSource loads the functions and classes declared in source and then
+// measures evaluating expression with the tree-walker and the closure compiler.
+func benchmarkSource(b *testing.B, source, expression string) {
+	b.Run("treewalk", func(b *testing.B) {
+		benchmarkInterpreter(b, interpreter.NewTreeWalkInterpreter, source, expression)
+	})
+	b.Run("closure", func(b *testing.B) {
+		benchmarkInterpreter(b, interpreter.NewClosureInterpreter, source, expression)
+	})
+}
+
+func benchmarkInterpreter(b *testing.B, newInterpreter func(interpreter.RuntimeErrorReporter) *interpreter.TreeWalkInterpreter, source, expression string) {
+	reporter := &errorCounter{}
+	in := newInterpreter(reporter)
+	var declarations []ast.Stmt
+	for _, stmt := range parser.NewParser(scanner.NewScanner(source, reporter).ScanTokens(), reporter).Parse() {
+		switch stmt.(type) {
+		case *ast.Function, *ast.Class:
+			declarations = append(declarations, stmt)
+		}
+	}
+	resolver.NewResolver(in, reporter).ResolveStmts(declarations)
+	in.Interpret(declarations)
+	expr := parser.NewParser(scanner.NewScanner(expression, reporter).ScanTokens(), reporter).ParseExpression()
+	resolver.NewResolver(in, reporter).ResolveExpr(expr)
+	if reporter.errors > 0 {
+		b.Fatalf("%d errors loading the benchmark", reporter.errors)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		in.Evaluate(expr)
+	}
+	if reporter.errors > 0 {
+		b.Fatalf("%d errors evaluating %s", reporter.errors, expression)
+	}
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkSample(b, "../../samples/14-fib-bench.lox", "fib(20)")
+}
+
+// BenchmarkEarlyReturn returns from inside a loop and nested blocks, which
+// unwinds through every enclosing statement.
+func BenchmarkEarlyReturn(b *testing.B) {
+	benchmarkSource(b, `
+fun find(n) {
+  var i = 0;
+  while (true) {
+    {
+      if (i == n) return i;
+    }
+    i = i + 1;
+  }
+}
+
+fun run() {
+  var sum = 0;
+  for (var i = 0; i < 1000; i = i + 1) {
+    sum = sum + find(10);
+  }
+  return sum;
+}
+`, "run()")
+}
+
+func BenchmarkMethodCall(b *testing.B) {
+	benchmarkSource(b, `
+class Counter {
+  init() {
+    this.count = 0;
+  }
+
+  increment() {
+    this.count = this.count + 1;
+    return this.count;
+  }
+}
+
+fun run() {
+  var counter = Counter();
+  for (var i = 0; i < 10000; i = i + 1) {
+    counter.increment();
+  }
+  return counter.count;
+}
+`, "run()")
+}
+
+// BenchmarkInheritedMethodCall calls methods inherited from a few classes up
+// and creates instances that set their fields in an initializer.
+func BenchmarkInheritedMethodCall(b *testing.B) {
+	benchmarkSource(b, `
+class Base {
+  init(x) {
+    this.x = x;
+    this.y = 0;
+  }
+
+  sum() {
+    return this.x + this.y;
+  }
+}
+
+class Middle < Base {}
+
+class Leaf < Middle {
+  init(x) {
+    super.init(x);
+    this.y = 1;
+  }
+}
+
+fun run() {
+  var total = 0;
+  for (var i = 0; i < 2000; i = i + 1) {
+    var leaf = Leaf(i);
+    total = total + leaf.sum() + leaf.sum();
+  }
+  return total;
+}
+`, "run()")
+}
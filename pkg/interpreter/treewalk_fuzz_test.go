@@ -0,0 +1,65 @@
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/rhomel/golox/pkg/interpreter"
+	"github.com/rhomel/golox/pkg/parser"
+	"github.com/rhomel/golox/pkg/resolver"
+	"github.com/rhomel/golox/pkg/scanner"
+	"github.com/rhomel/golox/pkg/util/fuzz"
+)
+
+// the resolver imports this package so the fuzz target lives in an external
+// test package
+
+type errorCounter struct {
+	errors int
+}
+
+func (e *errorCounter) Error(line int, message string) {
+	e.errors++
+}
+
+func (e *errorCounter) ParseError(token scanner.Token, message string) {
+	e.errors++
+}
+
+func (e *errorCounter) ResolveError(token scanner.Token, message string) {
+	e.errors++
+}
+
+func (e *errorCounter) RuntimeError(token scanner.Token, message string, trace []interpreter.StackFrame) {
+	e.errors++
+}
+
+func FuzzInterpret(f *testing.F) {
+	fuzzInterpreter(f, interpreter.NewTreeWalkInterpreter)
+}
+
+func FuzzCompile(f *testing.F) {
+	fuzzInterpreter(f, interpreter.NewClosureInterpreter)
+}
+
+func fuzzInterpreter(f *testing.F, newInterpreter func(interpreter.RuntimeErrorReporter) *interpreter.TreeWalkInterpreter) {
+	fuzz.AddSamples(f, "../../samples")
+	// unbounded recursion used to overflow the Go stack
+	f.Add("fun f() { f(); } f();")
+	f.Add("while (true) {}")
+	f.Add("fun f() { while (true) f(); } f();")
+	f.Fuzz(func(t *testing.T, source string) {
+		fuzz.Silence(t)
+		reporter := &errorCounter{}
+		tokens := scanner.NewScanner(source, reporter).ScanTokens()
+		statements := parser.NewParser(tokens, reporter).Parse()
+		if reporter.errors > 0 {
+			return
+		}
+		in := newInterpreter(reporter)
+		resolver.NewResolver(in, reporter).ResolveStmts(statements)
+		if reporter.errors > 0 {
+			return
+		}
+		fuzz.RunBounded(t, func() { in.Interpret(statements) }, in.Interrupt)
+	})
+}
@@ -0,0 +1,342 @@
+package interpreter
+
+import (
+	"hash/fnv"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Kind is the type of a Lox value.
+type Kind uint8
+
+const (
+	KindNil Kind = iota
+	KindBool
+	KindNumber
+	KindString
+	KindFunction // Lox and native functions
+	KindClass
+	KindInstance
+	KindGoObject
+	KindList
+	KindMap
+	KindModule
+)
+
+var kindNames = [...]string{
+	KindNil:      "nil",
+	KindBool:     "boolean",
+	KindNumber:   "number",
+	KindString:   "string",
+	KindFunction: "function",
+	KindClass:    "class",
+	KindInstance: "instance",
+	KindGoObject: "object",
+	KindList:     "list",
+	KindMap:      "map",
+	KindModule:   "module",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+// Value is a Lox value. The zero Value is nil.
+//
+// Numbers and booleans are stored unboxed so arithmetic doesn't allocate.
+// Values are comparable with == only in the Go sense; use Equal for Lox
+// equality.
+type Value struct {
+	kind Kind
+	num  float64     // numbers, and booleans as 0 or 1
+	ref  interface{} // a string, LoxCallable, *LoxInstance, *GoObject, *LoxList, *LoxMap or *LoxModule
+}
+
+var (
+	Nil   = Value{}
+	True  = Value{kind: KindBool, num: 1}
+	False = Value{kind: KindBool}
+)
+
+func Bool(b bool) Value {
+	if b {
+		return True
+	}
+	return False
+}
+
+func Number(n float64) Value {
+	return Value{kind: KindNumber, num: n}
+}
+
+func String(s string) Value {
+	return Value{kind: KindString, ref: s}
+}
+
+// Callable returns a class value for *LoxClass and a function value for any
+// other callable.
+func Callable(function LoxCallable) Value {
+	if class, ok := function.(*LoxClass); ok {
+		return Value{kind: KindClass, ref: class}
+	}
+	return Value{kind: KindFunction, ref: function}
+}
+
+func Instance(instance *LoxInstance) Value {
+	return Value{kind: KindInstance, ref: instance}
+}
+
+func Object(object *GoObject) Value {
+	return Value{kind: KindGoObject, ref: object}
+}
+
+func List(list *LoxList) Value {
+	return Value{kind: KindList, ref: list}
+}
+
+func Map(m *LoxMap) Value {
+	return Value{kind: KindMap, ref: m}
+}
+
+func Module(module *LoxModule) Value {
+	return Value{kind: KindModule, ref: module}
+}
+
+// ValueOf is the inverse of Value.Interface. It also accepts a Value, a
+// LoxCallable and a *GoObject. ok is false for any other type.
+func ValueOf(value interface{}) (v Value, ok bool) {
+	switch value := value.(type) {
+	case Value:
+		return value, true
+	case nil:
+		return Nil, true
+	case bool:
+		return Bool(value), true
+	case float64:
+		return Number(value), true
+	case string:
+		return String(value), true
+	case *LoxInstance:
+		return Instance(value), true
+	case *GoObject:
+		return Object(value), true
+	case *LoxList:
+		return List(value), true
+	case *LoxMap:
+		return Map(value), true
+	case *LoxModule:
+		return Module(value), true
+	case LoxCallable:
+		return Callable(value), true
+	}
+	return Nil, false
+}
+
+// literal converts the value of a literal in the syntax tree.
+func literal(value interface{}) Value {
+	switch v := value.(type) {
+	case nil:
+		return Nil
+	case bool:
+		return Bool(v)
+	case float64:
+		return Number(v)
+	case string:
+		return String(v)
+	}
+	panic("unsupported literal: " + reflect.TypeOf(value).String())
+}
+
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+func (v Value) IsNil() bool {
+	return v.kind == KindNil
+}
+
+// AsBool returns false unless v is true. See Truthy for Lox truthiness.
+func (v Value) AsBool() bool {
+	return v.kind == KindBool && v.num != 0
+}
+
+// AsNumber returns 0 unless v is a number.
+func (v Value) AsNumber() float64 {
+	if v.kind != KindNumber {
+		return 0
+	}
+	return v.num
+}
+
+// AsString returns "" unless v is a string. See String for formatting any
+// value.
+func (v Value) AsString() string {
+	s, _ := v.ref.(string)
+	return s
+}
+
+// AsCallable returns nil unless v is a function or class.
+func (v Value) AsCallable() LoxCallable {
+	function, _ := v.ref.(LoxCallable)
+	return function
+}
+
+// AsInstance returns nil unless v is an instance.
+func (v Value) AsInstance() *LoxInstance {
+	instance, _ := v.ref.(*LoxInstance)
+	return instance
+}
+
+// AsGoObject returns nil unless v is a Go object.
+func (v Value) AsGoObject() *GoObject {
+	object, _ := v.ref.(*GoObject)
+	return object
+}
+
+// AsList returns nil unless v is a list.
+func (v Value) AsList() *LoxList {
+	list, _ := v.ref.(*LoxList)
+	return list
+}
+
+// AsMap returns nil unless v is a map.
+func (v Value) AsMap() *LoxMap {
+	m, _ := v.ref.(*LoxMap)
+	return m
+}
+
+// AsModule returns nil unless v is a module.
+func (v Value) AsModule() *LoxModule {
+	module, _ := v.ref.(*LoxModule)
+	return module
+}
+
+// Interface returns v as an ordinary Go value: nil, a bool, a float64, a
+// string, a LoxCallable, a *LoxInstance, a *LoxList, a *LoxMap, a *LoxModule
+// or the value wrapped by a GoObject.
+func (v Value) Interface() interface{} {
+	switch v.kind {
+	case KindNil:
+		return nil
+	case KindBool:
+		return v.num != 0
+	case KindNumber:
+		return v.num
+	case KindGoObject:
+		return v.AsGoObject().Value()
+	}
+	return v.ref
+}
+
+// Truthy returns false only for nil and false.
+func (v Value) Truthy() bool {
+	switch v.kind {
+	case KindNil:
+		return false
+	case KindBool:
+		return v.num != 0
+	}
+	return true
+}
+
+// Equal reports whether v == other in Lox. Values of different kinds are
+// never equal, numbers follow IEEE 754 (NaN is not equal to itself), strings
+// are equal if they have the same contents and everything else is equal only
+// to itself. Go objects are the same object if they wrap the same pointer or
+// map.
+func (v Value) Equal(other Value) bool {
+	if v.kind != other.kind {
+		return false
+	}
+	switch v.kind {
+	case KindNil:
+		return true
+	case KindBool, KindNumber:
+		return v.num == other.num
+	case KindString:
+		return v.AsString() == other.AsString()
+	case KindGoObject:
+		return v.AsGoObject().same(other.AsGoObject())
+	}
+	return v.ref == other.ref
+}
+
+// Hash returns a hash of v that is the same for any two Equal values.
+func (v Value) Hash() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte{byte(v.kind)})
+	var bits uint64
+	switch v.kind {
+	case KindBool, KindNumber:
+		if v.num == 0 {
+			// 0 and -0 are equal
+			bits = 0
+		} else {
+			bits = math.Float64bits(v.num)
+		}
+	case KindString:
+		h.Write([]byte(v.AsString()))
+		return h.Sum64()
+	case KindGoObject:
+		bits = uint64(v.AsGoObject().identity())
+	case KindNil:
+	default:
+		bits = uint64(reflect.ValueOf(v.ref).Pointer())
+	}
+	var b [8]byte
+	for i := range b {
+		b[i] = byte(bits >> (8 * i))
+	}
+	h.Write(b[:])
+	return h.Sum64()
+}
+
+// String formats v the way print does.
+func (v Value) String() string {
+	switch v.kind {
+	case KindNil:
+		return "nil"
+	case KindBool:
+		if v.num != 0 {
+			return "true"
+		}
+		return "false"
+	case KindNumber:
+		return formatNumber(v.num)
+	case KindString:
+		return v.AsString()
+	}
+	if stringer, ok := v.ref.(interface{ String() string }); ok {
+		return stringer.String()
+	}
+	return "<" + v.kind.String() + ">"
+}
+
+// format writes v the way it is printed inside a list or map, where strings
+// are quoted. seen holds the lists and maps being formatted so one that
+// contains itself is only written once.
+func (v Value) format(b *strings.Builder, seen map[interface{}]bool) {
+	switch v.kind {
+	case KindList:
+		v.AsList().format(b, seen)
+	case KindMap:
+		v.AsMap().format(b, seen)
+	case KindString:
+		b.WriteByte('"')
+		b.WriteString(v.AsString())
+		b.WriteByte('"')
+	default:
+		b.WriteString(v.String())
+	}
+}
+
+// formatNumber uses the fewest digits that represent n exactly, so integers
+// have no fractional part.
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -0,0 +1,117 @@
+package interpreter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValueEqual(t *testing.T) {
+	newFunction := func() Value {
+		native, _ := NewNativeFunction("f", func() {})
+		return Callable(native)
+	}
+	function := newFunction()
+	instance := Instance(NewLoxInstance(NewLoxClass("A", nil, nil)))
+	type point struct{ X, Y int }
+	p := &point{1, 2}
+	object, _ := NewGoObject(p)
+	sameObject, _ := NewGoObject(p)
+	otherObject, _ := NewGoObject(&point{1, 2})
+	list := List(NewLoxList([]Value{Number(1)}))
+	m := Map(NewLoxMap())
+	tests := []struct {
+		a, b Value
+		want bool
+	}{
+		{Nil, Nil, true},
+		{Nil, False, false},
+		{True, True, true},
+		{True, False, false},
+		{Number(1), Number(1), true},
+		{Number(0), Number(math.Copysign(0, -1)), true},
+		{Number(math.NaN()), Number(math.NaN()), false},
+		{Number(1), String("1"), false},
+		{Number(1), True, false},
+		{String("lox"), String("lo" + "x"), true},
+		{String(""), Nil, false},
+		{function, function, true},
+		{function, newFunction(), false},
+		{instance, instance, true},
+		{instance, Instance(NewLoxInstance(NewLoxClass("A", nil, nil))), false},
+		{Object(object), Object(sameObject), true},
+		{Object(object), Object(otherObject), false},
+		{list, list, true},
+		{list, List(NewLoxList([]Value{Number(1)})), false},
+		{m, m, true},
+		{m, Map(NewLoxMap()), false},
+	}
+	for _, test := range tests {
+		if got := test.a.Equal(test.b); got != test.want {
+			t.Errorf("%v (%s) == %v (%s): want %v, got %v", test.a, test.a.Kind(), test.b, test.b.Kind(), test.want, got)
+		}
+		if got := test.b.Equal(test.a); got != test.want {
+			t.Errorf("%v (%s) == %v (%s): want %v, got %v", test.b, test.b.Kind(), test.a, test.a.Kind(), test.want, got)
+		}
+		if test.want && test.a.Hash() != test.b.Hash() {
+			t.Errorf("%v (%s) and %v (%s) are equal but hash differently", test.a, test.a.Kind(), test.b, test.b.Kind())
+		}
+	}
+}
+
+func TestValueHashDistinguishesKinds(t *testing.T) {
+	values := []Value{Nil, False, True, Number(0), Number(1), String(""), String("1")}
+	seen := make(map[uint64]Value)
+	for _, value := range values {
+		if other, ok := seen[value.Hash()]; ok {
+			t.Errorf("%v (%s) and %v (%s) have the same hash", value, value.Kind(), other, other.Kind())
+		}
+		seen[value.Hash()] = value
+	}
+}
+
+func TestValueString(t *testing.T) {
+	cycle := NewLoxList([]Value{Number(1), Nil})
+	cycle.Elements()[1] = List(cycle)
+	m := NewLoxMap()
+	m.Set(String("a"), List(NewLoxList([]Value{Number(1)})))
+	m.Set(Nil, Map(m))
+	tests := []struct {
+		value Value
+		want  string
+	}{
+		{Nil, "nil"},
+		{True, "true"},
+		{False, "false"},
+		{Number(3), "3"},
+		{Number(-0.5), "-0.5"},
+		{Number(1e21), "1000000000000000000000"},
+		{Number(math.Inf(1)), "+Inf"},
+		{String("lox"), "lox"},
+		{Callable(&nativeClock{}), "<native fn>"},
+		{Callable(NewLoxClass("A", nil, nil)), "A"},
+		{Instance(NewLoxInstance(NewLoxClass("A", nil, nil))), "A instance"},
+		{List(NewLoxList(nil)), "[]"},
+		{List(NewLoxList([]Value{Number(1), String("a"), Nil, List(NewLoxList([]Value{True}))})), `[1, "a", nil, [true]]`},
+		{List(cycle), "[1, [...]]"},
+		{Map(NewLoxMap()), "{}"},
+		{Map(m), `{"a": [1], nil: {...}}`},
+	}
+	for _, test := range tests {
+		if got := test.value.String(); got != test.want {
+			t.Errorf("%s: want %q, got %q", test.value.Kind(), test.want, got)
+		}
+	}
+}
+
+func TestValueOf(t *testing.T) {
+	class := NewLoxClass("A", nil, nil)
+	for _, value := range []Value{Nil, True, Number(2), String("s"), Callable(class), Instance(NewLoxInstance(class)), List(NewLoxList(nil)), Map(NewLoxMap())} {
+		got, ok := ValueOf(value.Interface())
+		if !ok || got.Kind() != value.Kind() || !got.Equal(value) {
+			t.Errorf("%v (%s): want the same value back, got %v (%s)", value, value.Kind(), got, got.Kind())
+		}
+	}
+	if _, ok := ValueOf([]int{}); ok {
+		t.Errorf("want []int to have no Value")
+	}
+}
@@ -0,0 +1,65 @@
+// Package module finds the files named by import statements. Each interpreter
+// loads and caches modules itself; this package only decides which file an
+// import refers to and how an import cycle is reported, so that they all
+// agree.
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Find returns the file that path names in an import statement of the file
+// from, which is empty for a program that wasn't read from a file. A relative
+// path is looked up in the directory of from, or the current directory, and
+// then in each directory of searchPath. The file is returned as an absolute
+// path so a module imported from different places is the same module.
+func Find(from, path string, searchPath []string) (string, bool) {
+	if filepath.IsAbs(path) {
+		return found(path)
+	}
+	dirs := append([]string{filepath.Dir(from)}, searchPath...)
+	if from == "" {
+		dirs[0] = "."
+	}
+	for _, dir := range dirs {
+		if file, ok := found(filepath.Join(dir, path)); ok {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+func found(file string) (string, bool) {
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	file, err = filepath.Abs(file)
+	return file, err == nil
+}
+
+// Name returns the name a module is shown with: the name of its file without
+// the directory and extension.
+func Name(file string) string {
+	name := filepath.Base(file)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// Cycle returns the message for importing file while the files in loading are
+// being imported, outermost first. ok is false if file is not one of them.
+func Cycle(loading []string, file string) (message string, ok bool) {
+	for i, f := range loading {
+		if f != file {
+			continue
+		}
+		names := make([]string, 0, len(loading)-i+1)
+		for _, f := range loading[i:] {
+			names = append(names, filepath.Base(f))
+		}
+		names = append(names, filepath.Base(file))
+		return "Import cycle: " + strings.Join(names, " -> ") + ".", true
+	}
+	return "", false
+}
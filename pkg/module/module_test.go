@@ -0,0 +1,49 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	for _, file := range []string{"main.lox", "local.lox", "lib/shared.lox", "lib/local.lox"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	main := filepath.Join(dir, "main.lox")
+	tests := []struct {
+		path string
+		want string
+	}{
+		// the importing file's directory is searched before the search path
+		{"local.lox", filepath.Join(dir, "local.lox")},
+		{"shared.lox", filepath.Join(lib, "shared.lox")},
+		{"lib/shared.lox", filepath.Join(lib, "shared.lox")},
+		{filepath.Join(lib, "local.lox"), filepath.Join(lib, "local.lox")},
+		{"missing.lox", ""},
+		{"lib", ""},
+	}
+	for _, test := range tests {
+		got, ok := Find(main, test.path, []string{lib})
+		if got != test.want || ok != (test.want != "") {
+			t.Errorf("Find(%q) = %q, %v, want %q", test.path, got, ok, test.want)
+		}
+	}
+}
+
+func TestCycle(t *testing.T) {
+	loading := []string{"/src/main.lox", "/src/a.lox", "/src/b.lox"}
+	if message, ok := Cycle(loading, "/src/a.lox"); !ok || message != "Import cycle: a.lox -> b.lox -> a.lox." {
+		t.Errorf("Cycle(a.lox) = %q, %v", message, ok)
+	}
+	if message, ok := Cycle(loading, "/src/c.lox"); ok {
+		t.Errorf("Cycle(c.lox) = %q, want no cycle", message)
+	}
+}
@@ -0,0 +1,88 @@
+// Package number parses number literals and converts numbers for the bitwise
+// operators. Each scanner finds the extent of a literal itself; this package
+// decides what it means and which literals are errors, so that the tree-walk
+// interpreters and the VM agree.
+package number
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var bases = map[rune]int{'x': 16, 'X': 16, 'o': 8, 'O': 8, 'b': 2, 'B': 2}
+
+var baseNames = map[int]string{16: "hexadecimal", 8: "octal", 2: "binary"}
+
+// Base returns the base of a number literal that starts with '0' and then c,
+// or 10 if c is not one of the base letters x, o and b.
+func Base(c rune) int {
+	if base, ok := bases[c]; ok {
+		return base
+	}
+	return 10
+}
+
+// Parse returns the value of the number literal. A literal is a decimal number
+// with an optional fraction and exponent like "1.5e-3", or an integer in
+// hexadecimal, octal or binary like "0x1f", "0o17" or "0b101". A "_" can
+// separate two digits. If the literal is invalid Parse returns the message it
+// is reported with.
+func Parse(literal string) (value float64, message string, ok bool) {
+	base, digits := 10, literal
+	if len(literal) > 1 && literal[0] == '0' {
+		if base = Base(rune(literal[1])); base != 10 {
+			digits = literal[2:]
+			if digits == "" {
+				return 0, fmt.Sprintf("Expect digits after '%s'.", literal[:2]), false
+			}
+		}
+	}
+	for i := 0; i < len(digits); i++ {
+		c := digits[i]
+		if c == '_' {
+			if i == 0 || i == len(digits)-1 || !isDigit(digits[i-1], base) || !isDigit(digits[i+1], base) {
+				return 0, "Digit separator '_' must be between digits.", false
+			}
+			continue
+		}
+		if base != 10 && !isDigit(c, base) {
+			return 0, fmt.Sprintf("Invalid digit '%c' in %s number.", c, baseNames[base]), false
+		}
+	}
+	digits = strings.ReplaceAll(digits, "_", "")
+	if base == 10 {
+		value, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			return 0, "Number literal is too large.", false
+		}
+		return value, "", true
+	}
+	integer, err := strconv.ParseUint(digits, base, 64)
+	if err != nil {
+		return 0, "Number literal is too large.", false
+	}
+	return float64(integer), "", true
+}
+
+func isDigit(c byte, base int) bool {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c-'0') < base
+	case c >= 'a' && c <= 'f':
+		return base == 16
+	case c >= 'A' && c <= 'F':
+		return base == 16
+	}
+	return false
+}
+
+// Integer returns x as an integer for the bitwise operators. ok is false if x
+// has a fractional part or doesn't fit in 64 bits.
+func Integer(x float64) (integer int64, ok bool) {
+	if x != math.Trunc(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(x), true
+}
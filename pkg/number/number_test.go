@@ -0,0 +1,63 @@
+package number
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		literal string
+		want    float64
+		message string
+	}{
+		{"123", 123, ""},
+		{"1.5", 1.5, ""},
+		{"1e9", 1e9, ""},
+		{"2.5E-3", 2.5e-3, ""},
+		{"1_000_000", 1000000, ""},
+		{"0x1F", 31, ""},
+		{"0xff_ff", 65535, ""},
+		{"0o17", 15, ""},
+		{"0b1010", 10, ""},
+		{"0", 0, ""},
+		{"0x", 0, "Expect digits after '0x'."},
+		{"0b102", 0, "Invalid digit '2' in binary number."},
+		{"0o8", 0, "Invalid digit '8' in octal number."},
+		{"0xfg", 0, "Invalid digit 'g' in hexadecimal number."},
+		{"1__0", 0, "Digit separator '_' must be between digits."},
+		{"1_", 0, "Digit separator '_' must be between digits."},
+		{"0x_1", 0, "Digit separator '_' must be between digits."},
+		{"1_.5", 0, "Digit separator '_' must be between digits."},
+		{"0x1_0000_0000_0000_0000", 0, "Number literal is too large."},
+		{"1e400", 0, "Number literal is too large."},
+	}
+	for _, test := range tests {
+		got, message, ok := Parse(test.literal)
+		if got != test.want || message != test.message || ok != (test.message == "") {
+			t.Errorf("Parse(%q) = %v, %q, %v, want %v, %q", test.literal, got, message, ok, test.want, test.message)
+		}
+	}
+}
+
+func TestInteger(t *testing.T) {
+	tests := []struct {
+		x    float64
+		want int64
+		ok   bool
+	}{
+		{5, 5, true},
+		{-3, -3, true},
+		{1.5, 0, false},
+		{math.Inf(1), 0, false},
+		{math.NaN(), 0, false},
+		{math.MinInt64, math.MinInt64, true},
+		{math.MaxInt64, 0, false},
+	}
+	for _, test := range tests {
+		got, ok := Integer(test.x)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Integer(%v) = %v, %v, want %v, %v", test.x, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -9,21 +9,39 @@ package parser
 //   [https://craftinginterpreters.com/functions.html#function-calls]
 //   [https://craftinginterpreters.com/classes.html#properties-on-instances]
 //
-// expression     → assignment ;
-// assignment     → ( call "." )? IDENTIFIER "=" assignment
-//                | logic_or ;
+// expression     → comma ;
+// comma          → assignment ( "," assignment )* ;
+// assignment     → ( call "." )? IDENTIFIER assignOp assignment
+//                | call "[" expression "]" assignOp assignment
+//                | conditional ;
+// assignOp       → "=" | "+=" | "-=" | "*=" | "/=" | "%=" ;
+// conditional    → logic_or ( "?" expression ":" conditional )? ;
 // logic_or       → logic_and ( "or" logic_and )* ;
 // logic_and      → equality ( "and" equality )* ;
 // equality       → comparison ( ( "!=" | "==" ) comparison )* ;
-// comparison     → term ( ( ">" | ">=" | "<" | "<=" ) term )* ;
+// comparison     → bitOr ( ( ">" | ">=" | "<" | "<=" ) bitOr )* ;
+// bitOr          → bitXor ( "|" bitXor )* ;
+// bitXor         → bitAnd ( "^" bitAnd )* ;
+// bitAnd         → shift ( "&" shift )* ;
+// shift          → term ( ( "<<" | ">>" ) term )* ;
 // term           → factor ( ( "-" | "+" ) factor )* ;
-// factor         → unary ( ( "/" | "*" ) unary )* ;
-// unary          → ( "!" | "-" ) unary | call ;
-// call           → primary ( "(" arguments? ")" | "." IDENTIFIER )* ;
-// arguments      → expression ( "," expression )* ;
+// factor         → unary ( ( "/" | "*" | "%" | "~/" ) unary )* ;
+// unary          → ( "!" | "-" | "~" | "++" | "--" ) unary | power ;
+// power          → postfix ( "**" unary )? ;
+// postfix        → call ( "++" | "--" )* ;
+// call           → primary ( "(" arguments? ")" | "." IDENTIFIER
+//                | "[" expression "]" )* ;
+// arguments      → assignment ( "," assignment )* ;
 // primary        → "true" | "false" | "nil" | "this"
 //                | NUMBER | STRING | IDENTIFIER | "(" expression ")"
-//                | "super" "." IDENTIFIER ;
+//                | interpolation
+//                | "[" arguments? "]"
+//                | "{" ( entry ( "," entry )* )? "}"
+//                | "super" "." IDENTIFIER
+//                | lambda ;
+// entry          → assignment ":" assignment ;
+// interpolation  → ( INTERPOLATION expression )+ STRING ;
+// lambda         → "fun" "(" parameters? ")" ( block | "=>" assignment ) ;
 
 // ## statement rules
 //   [https://craftinginterpreters.com/statements-and-state.html#statements]
@@ -36,6 +54,7 @@ package parser
 //
 // declaration    → classDecl
 //                | funDecl
+//                | importDecl
 //                | varDecl
 //                | statement ;
 //
@@ -45,6 +64,7 @@ package parser
 // parameters     → IDENTIFIER ( "," IDENTIFIER )* ;
 //
 // varDecl        → "var" IDENTIFIER ( "=" expression )? ";" ;
+// importDecl     → "import" STRING "as" IDENTIFIER ";" ;
 //
 // ## block syntax
 //   [https://craftinginterpreters.com/statements-and-state.html#block-syntax-and-semantics]
@@ -57,14 +77,23 @@ package parser
 // ## return statement
 //   [https://craftinginterpreters.com/functions.html#return-statements]
 // statement      → exprStmt
+//                | breakStmt
+//                | continueStmt
 //                | forStmt
 //                | ifStmt
 //                | printStmt
 //                | returnStmt
+//                | throwStmt
+//                | tryStmt
 //                | whileStmt
 //                | block ;
 //
 // returnStmt     → "return" expression? ";" ;
+// breakStmt      → "break" ";" ;
+// continueStmt   → "continue" ";" ;
+// throwStmt      → "throw" expression ";" ;
+// tryStmt        → "try" block ( "catch" "(" IDENTIFIER ")" block )?
+//                ( "finally" block )? ;
 //
 // forStmt        → "for" "(" ( varDecl | expr Stmt | ";" )
 //                expression? ";"
@@ -103,13 +132,47 @@ func NewParser(tokens []*scanner.Token, reporter ParseErrorReporter) *Parser {
 
 func (p *Parser) Parse() (statements []ast.Stmt) {
 	for !p.isAtEnd() {
-		statements = append(statements, p.declaration())
+		// declarations that failed to parse are reported and dropped
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
 	}
 	return
 }
 
+// ParseExpression parses tokens that must contain exactly one expression. It
+// returns nil if there is a syntax error.
+func (p *Parser) ParseExpression() (expr ast.Expr) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*ParseError); !ok {
+				panic(r)
+			}
+			expr = nil
+		}
+	}()
+	expr = p.expression()
+	if !p.isAtEnd() {
+		panic(p.err(p.peek(), "Expect end of expression."))
+	}
+	return expr
+}
+
 func (p *Parser) expression() ast.Expr {
-	return p.assignment()
+	return p.comma()
+}
+
+// comma parses expressions separated by the comma operator, which evaluates
+// both operands and results in the right one. Arguments and the elements of
+// list and map literals are parsed with assignment so their commas separate
+// them instead.
+func (p *Parser) comma() ast.Expr {
+	expr := p.assignment()
+	for p.match(scanner.COMMA) {
+		right := p.assignment()
+		expr = &ast.Comma{expr, right}
+	}
+	return expr
 }
 
 func (p *Parser) assignment() ast.Expr {
@@ -117,7 +180,7 @@ func (p *Parser) assignment() ast.Expr {
 	// ref: https://craftinginterpreters.com/statements-and-state.html#assignment-syntax
 	// assume there's an equality expression for now, even if it is an
 	// IDENTIFER it will be parsed as an IDENTIFIER
-	expr := p.or()
+	expr := p.conditional()
 
 	// see if there's an EQUAL token
 	if p.match(scanner.EQUAL) {
@@ -130,9 +193,56 @@ func (p *Parser) assignment() ast.Expr {
 		} else if get, isGet := expr.(*ast.Get); isGet {
 			// matched expr was a instance.get expression
 			return &ast.Set{get.Object, get.Name, value}
+		} else if index, isIndex := expr.(*ast.GetIndex); isIndex {
+			// matched expr was a list[index] expression
+			return &ast.SetIndex{index.Object, index.Bracket, index.Index, value}
 		}
 		p.err(equals, "Invalid assignment target.")
 	}
+	if p.match(scanner.PLUS_EQUAL, scanner.MINUS_EQUAL, scanner.STAR_EQUAL, scanner.SLASH_EQUAL, scanner.PERCENT_EQUAL) {
+		operator := p.previous()
+		value := p.assignment()
+		return p.compound(expr, operator, value, false)
+	}
+	return expr
+}
+
+// compoundOperators are the binary operators that compound assignments and
+// increments apply.
+var compoundOperators = map[scanner.TokenType]scanner.TokenType{
+	scanner.PLUS_EQUAL:    scanner.PLUS,
+	scanner.MINUS_EQUAL:   scanner.MINUS,
+	scanner.STAR_EQUAL:    scanner.STAR,
+	scanner.SLASH_EQUAL:   scanner.SLASH,
+	scanner.PERCENT_EQUAL: scanner.PERCENT,
+	scanner.PLUS_PLUS:     scanner.PLUS,
+	scanner.MINUS_MINUS:   scanner.MINUS,
+}
+
+// compound returns the update of target by a compound assignment like "+=" or
+// an increment or decrement, whose value is 1. The operator of the update is
+// the binary operator it applies but keeps the lexeme and line of the token.
+func (p *Parser) compound(target ast.Expr, token scanner.Token, value ast.Expr, postfix bool) ast.Expr {
+	switch target.(type) {
+	case *ast.Variable, *ast.Get, *ast.GetIndex:
+		operator := scanner.Token{compoundOperators[token.Typ], token.Lexeme, nil, token.Line}
+		return &ast.Compound{target, operator, value, postfix}
+	}
+	p.err(token, "Invalid assignment target.")
+	return target
+}
+
+// conditional parses "condition ? then : else". Only the chosen branch is
+// evaluated. The else branch is a conditional itself so the operator is right
+// associative.
+func (p *Parser) conditional() ast.Expr {
+	expr := p.or()
+	if p.match(scanner.QUESTION) {
+		then := p.expression()
+		p.consume(scanner.COLON, "Expect ':' after then branch of conditional expression.")
+		otherwise := p.conditional()
+		expr = &ast.Conditional{expr, then, otherwise}
+	}
 	return expr
 }
 
@@ -166,9 +276,14 @@ func (p *Parser) declaration() (stmt ast.Stmt) {
 	if p.match(scanner.CLASS) {
 		return p.class()
 	}
-	if p.match(scanner.FUN) {
+	if p.check(scanner.FUN) && !p.checkNext(scanner.LEFT_PAREN) {
+		// a fun without a name starts an expression statement with a lambda
+		p.advance()
 		return p.function("function")
 	}
+	if p.match(scanner.IMPORT) {
+		return p.importDeclaration()
+	}
 	if p.match(scanner.VAR) {
 		return p.varDeclaration()
 	}
@@ -201,12 +316,27 @@ func (p *Parser) varDeclaration() ast.Stmt {
 	return &ast.VarStmt{identifier, initializer}
 }
 
+// importDeclaration parses an import. "as" is not a keyword so it can still be
+// used as a name.
+func (p *Parser) importDeclaration() ast.Stmt {
+	keyword := p.previous()
+	path := p.consume(scanner.STRING, "Expect module path after 'import'.")
+	if !p.check(scanner.IDENTIFIER) || p.peek().Lexeme != "as" {
+		panic(p.err(p.peek(), "Expect 'as' after module path."))
+	}
+	p.advance()
+	name := p.consume(scanner.IDENTIFIER, "Expect module name after 'as'.")
+	p.consume(scanner.SEMICOLON, "Expect ';' after import.")
+	return &ast.ImportStmt{keyword, path, name}
+}
+
 func (p *Parser) whileStatement() ast.Stmt {
-	p.consume(scanner.LEFT_PAREN, "Expect '(' after 'if'.")
+	keyword := p.previous()
+	p.consume(scanner.LEFT_PAREN, "Expect '(' after 'while'.")
 	condition := p.expression()
-	p.consume(scanner.RIGHT_PAREN, "Expect ')' after if condition.")
+	p.consume(scanner.RIGHT_PAREN, "Expect ')' after condition.")
 	body := p.statement()
-	return &ast.While{condition, body}
+	return &ast.While{keyword, condition, body, nil}
 }
 
 func (p *Parser) statement() ast.Stmt {
@@ -222,6 +352,25 @@ func (p *Parser) statement() ast.Stmt {
 	if p.match(scanner.RETURN) {
 		return p.returnStatement()
 	}
+	if p.match(scanner.BREAK) {
+		keyword := p.previous()
+		p.consume(scanner.SEMICOLON, "Expect ';' after 'break'.")
+		return &ast.BreakStmt{keyword}
+	}
+	if p.match(scanner.CONTINUE) {
+		keyword := p.previous()
+		p.consume(scanner.SEMICOLON, "Expect ';' after 'continue'.")
+		return &ast.ContinueStmt{keyword}
+	}
+	if p.match(scanner.THROW) {
+		keyword := p.previous()
+		value := p.expression()
+		p.consume(scanner.SEMICOLON, "Expect ';' after thrown value.")
+		return &ast.Throw{keyword, value}
+	}
+	if p.match(scanner.TRY) {
+		return p.tryStatement()
+	}
 	if p.match(scanner.WHILE) {
 		return p.whileStatement()
 	}
@@ -231,7 +380,30 @@ func (p *Parser) statement() ast.Stmt {
 	return p.expressionStatement()
 }
 
+func (p *Parser) tryStatement() ast.Stmt {
+	p.consume(scanner.LEFT_BRACE, "Expect '{' after 'try'.")
+	body := &ast.Block{p.block()}
+	var catch *ast.Catch
+	if p.match(scanner.CATCH) {
+		p.consume(scanner.LEFT_PAREN, "Expect '(' after 'catch'.")
+		name := p.consume(scanner.IDENTIFIER, "Expect catch variable name.")
+		p.consume(scanner.RIGHT_PAREN, "Expect ')' after catch variable.")
+		p.consume(scanner.LEFT_BRACE, "Expect '{' before catch body.")
+		catch = &ast.Catch{name, p.block()}
+	}
+	var finally ast.Stmt
+	if p.match(scanner.FINALLY) {
+		p.consume(scanner.LEFT_BRACE, "Expect '{' after 'finally'.")
+		finally = &ast.Block{p.block()}
+	}
+	if catch == nil && finally == nil {
+		panic(p.err(p.peek(), "Expect 'catch' or 'finally' after try block."))
+	}
+	return &ast.Try{body, catch, finally}
+}
+
 func (p *Parser) forStatement() ast.Stmt {
+	keyword := p.previous()
 	p.consume(scanner.LEFT_PAREN, "Expect '(' after 'for'.")
 	var initializer ast.Stmt
 	if p.match(scanner.SEMICOLON) {
@@ -253,18 +425,11 @@ func (p *Parser) forStatement() ast.Stmt {
 	p.consume(scanner.RIGHT_PAREN, "Expect ')' after for clauses.")
 	body := p.statement()
 
-	if increment != nil {
-		body = &ast.Block{
-			Statements: []ast.Stmt{
-				body,
-				&ast.Expression{increment},
-			},
-		}
-	}
+	// the increment isn't appended to the body so that continue runs it
 	if condition == nil {
 		condition = &ast.Literal{true}
 	}
-	body = &ast.While{condition, body}
+	body = &ast.While{keyword, condition, body, increment}
 	if initializer != nil {
 		body = &ast.Block{[]ast.Stmt{initializer, body}}
 	}
@@ -311,6 +476,15 @@ func (p *Parser) expressionStatement() ast.Stmt {
 func (p *Parser) function(kind string) *ast.Function {
 	name := p.consume(scanner.IDENTIFIER, fmt.Sprintf("Expect %s name.", kind))
 	p.consume(scanner.LEFT_PAREN, fmt.Sprintf("Expect '(' after %s name.", kind))
+	parameters := p.parameters()
+	p.consume(scanner.LEFT_BRACE, fmt.Sprintf("Expect '{' before %s body.", kind))
+	body := p.block()
+	return &ast.Function{name, parameters, body}
+}
+
+// parameters parses the parameter list after the opening '(' up to and
+// including the closing ')'.
+func (p *Parser) parameters() []scanner.Token {
 	var parameters []scanner.Token
 	if !p.check(scanner.RIGHT_PAREN) {
 		parameters = append(parameters, p.consume(scanner.IDENTIFIER, "Expect parameter name."))
@@ -322,15 +496,33 @@ func (p *Parser) function(kind string) *ast.Function {
 		}
 	}
 	p.consume(scanner.RIGHT_PAREN, "Expect ')' after parameters.")
-	p.consume(scanner.LEFT_BRACE, fmt.Sprintf("Expect '{' before %s body.", kind))
-	body := p.block()
-	return &ast.Function{name, parameters, body}
+	return parameters
+}
+
+// lambda parses an anonymous function after its fun keyword. The function is
+// named by the keyword. An arrow body is a single expression that is
+// returned.
+func (p *Parser) lambda() ast.Expr {
+	keyword := p.previous()
+	p.consume(scanner.LEFT_PAREN, "Expect '(' after 'fun'.")
+	parameters := p.parameters()
+	var body []ast.Stmt
+	if p.match(scanner.ARROW) {
+		arrow := p.previous()
+		body = []ast.Stmt{&ast.ReturnStmt{arrow, p.assignment()}}
+	} else {
+		p.consume(scanner.LEFT_BRACE, "Expect '{' or '=>' before lambda body.")
+		body = p.block()
+	}
+	return &ast.Lambda{&ast.Function{keyword, parameters, body}}
 }
 
 func (p *Parser) block() []ast.Stmt {
 	var statements []ast.Stmt
 	for !p.check(scanner.RIGHT_BRACE) && !p.isAtEnd() {
-		statements = append(statements, p.declaration())
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
 	}
 	p.consume(scanner.RIGHT_BRACE, "Expect '}' after block.")
 	return statements
@@ -347,8 +539,51 @@ func (p *Parser) equality() ast.Expr {
 }
 
 func (p *Parser) comparison() ast.Expr {
-	var expr ast.Expr = p.term()
+	var expr ast.Expr = p.bitOr()
 	for p.match(scanner.GREATER, scanner.GREATER_EQUAL, scanner.LESS, scanner.LESS_EQUAL) {
+		operator := p.previous()
+		right := p.bitOr()
+		expr = &ast.Binary{expr, operator, right}
+	}
+	return expr
+}
+
+// The bitwise operators bind tighter than comparisons so that "x & 1 == 0"
+// means "(x & 1) == 0".
+
+func (p *Parser) bitOr() ast.Expr {
+	var expr ast.Expr = p.bitXor()
+	for p.match(scanner.PIPE) {
+		operator := p.previous()
+		right := p.bitXor()
+		expr = &ast.Binary{expr, operator, right}
+	}
+	return expr
+}
+
+func (p *Parser) bitXor() ast.Expr {
+	var expr ast.Expr = p.bitAnd()
+	for p.match(scanner.CARET) {
+		operator := p.previous()
+		right := p.bitAnd()
+		expr = &ast.Binary{expr, operator, right}
+	}
+	return expr
+}
+
+func (p *Parser) bitAnd() ast.Expr {
+	var expr ast.Expr = p.shift()
+	for p.match(scanner.AMPERSAND) {
+		operator := p.previous()
+		right := p.shift()
+		expr = &ast.Binary{expr, operator, right}
+	}
+	return expr
+}
+
+func (p *Parser) shift() ast.Expr {
+	var expr ast.Expr = p.term()
+	for p.match(scanner.LESS_LESS, scanner.GREATER_GREATER) {
 		operator := p.previous()
 		right := p.term()
 		expr = &ast.Binary{expr, operator, right}
@@ -368,7 +603,7 @@ func (p *Parser) term() ast.Expr {
 
 func (p *Parser) factor() ast.Expr {
 	var expr ast.Expr = p.unary()
-	for p.match(scanner.SLASH, scanner.STAR) {
+	for p.match(scanner.SLASH, scanner.STAR, scanner.PERCENT, scanner.TILDE_SLASH) {
 		operator := p.previous()
 		right := p.unary()
 		expr = &ast.Binary{expr, operator, right}
@@ -377,12 +612,41 @@ func (p *Parser) factor() ast.Expr {
 }
 
 func (p *Parser) unary() ast.Expr {
-	if p.match(scanner.BANG, scanner.MINUS) {
+	if p.match(scanner.BANG, scanner.MINUS, scanner.TILDE) {
 		operator := p.previous()
 		right := p.unary()
 		return &ast.Unary{operator, right}
 	}
-	return p.call()
+	if p.match(scanner.PLUS_PLUS, scanner.MINUS_MINUS) {
+		operator := p.previous()
+		target := p.unary()
+		return p.compound(target, operator, &ast.Literal{1.0}, false)
+	}
+	return p.power()
+}
+
+// power parses "**", which binds tighter than a unary operator on its left so
+// "-2 ** 2" is -4, and is right associative because its right operand is a
+// unary expression.
+func (p *Parser) power() ast.Expr {
+	expr := p.postfix()
+	if p.match(scanner.STAR_STAR) {
+		operator := p.previous()
+		right := p.unary()
+		expr = &ast.Binary{expr, operator, right}
+	}
+	return expr
+}
+
+// postfix parses an increment or decrement after its target, which evaluates
+// to the value before the update.
+func (p *Parser) postfix() ast.Expr {
+	expr := p.call()
+	for p.match(scanner.PLUS_PLUS, scanner.MINUS_MINUS) {
+		// "x++++" is parsed so that the second "++" is an invalid target
+		expr = p.compound(expr, p.previous(), &ast.Literal{1.0}, true)
+	}
+	return expr
 }
 
 func (p *Parser) call() ast.Expr {
@@ -393,6 +657,10 @@ func (p *Parser) call() ast.Expr {
 		} else if p.match(scanner.DOT) {
 			name := p.consume(scanner.IDENTIFIER, "Expect property name after '.'.")
 			expr = &ast.Get{expr, name}
+		} else if p.match(scanner.LEFT_BRACKET) {
+			index := p.expression()
+			bracket := p.consume(scanner.RIGHT_BRACKET, "Expect ']' after index.")
+			expr = &ast.GetIndex{expr, bracket, index}
 		} else {
 			break
 		}
@@ -403,12 +671,12 @@ func (p *Parser) call() ast.Expr {
 func (p *Parser) finishCall(callee ast.Expr) ast.Expr {
 	var arguments []ast.Expr
 	if !p.check(scanner.RIGHT_PAREN) {
-		arguments = append(arguments, p.expression())
+		arguments = append(arguments, p.assignment())
 		for p.match(scanner.COMMA) {
 			if len(arguments) >= 255 {
 				p.err(p.peek(), "Can't have more than 255 arguments.")
 			}
-			arguments = append(arguments, p.expression())
+			arguments = append(arguments, p.assignment())
 		}
 	}
 	paren := p.consume(scanner.RIGHT_PAREN, "Expect ')' after arguments.")
@@ -425,9 +693,17 @@ func (p *Parser) primary() ast.Expr {
 	if p.match(scanner.NIL) {
 		return &ast.Literal{nil}
 	}
+	if p.continuesString() {
+		// the rest of a string after an embedded expression, like the `}"`
+		// in "${1 +}", isn't an operand
+		panic(p.err(p.peek(), "Expect expression."))
+	}
 	if p.match(scanner.NUMBER, scanner.STRING) {
 		return &ast.Literal{p.previous().Literal}
 	}
+	if p.match(scanner.INTERPOLATION) {
+		return p.interpolation()
+	}
 	if p.match(scanner.SUPER) {
 		keyword := p.previous()
 		p.consume(scanner.DOT, "Expect '.' after 'super'.")
@@ -446,9 +722,77 @@ func (p *Parser) primary() ast.Expr {
 		p.consume(scanner.RIGHT_PAREN, "Expect ')' after expression.")
 		return &ast.Grouping{expr}
 	}
+	if p.match(scanner.LEFT_BRACKET) {
+		return p.list()
+	}
+	if p.match(scanner.LEFT_BRACE) {
+		return p.mapLiteral()
+	}
+	if p.match(scanner.FUN) {
+		return p.lambda()
+	}
 	panic(p.err(p.peek(), "Expect expression."))
 }
 
+// interpolation desugars a string with embedded expressions into
+// concatenating its parts, with each expression converted to a string the way
+// print formats it.
+func (p *Parser) interpolation() ast.Expr {
+	plus := scanner.Token{scanner.PLUS, "+", nil, p.previous().Line}
+	var expr ast.Expr = &ast.Literal{p.previous().Literal}
+	for {
+		if p.continuesString() {
+			// "${}" has no expression
+			panic(p.err(p.peek(), "Expect expression."))
+		}
+		expr = &ast.Binary{expr, plus, &ast.Stringify{p.expression()}}
+		if !p.continuesString() {
+			panic(p.err(p.peek(), "Expect '}' after interpolated expression."))
+		}
+		part := p.advance()
+		if part.Literal != "" {
+			expr = &ast.Binary{expr, plus, &ast.Literal{part.Literal}}
+		}
+		if part.Typ == scanner.STRING {
+			return expr
+		}
+	}
+}
+
+// continuesString reports whether the next token is the rest of a string
+// after an embedded expression, which starts with the "}" closing it.
+func (p *Parser) continuesString() bool {
+	return (p.check(scanner.INTERPOLATION) || p.check(scanner.STRING)) && p.peek().Lexeme[0] == '}'
+}
+
+func (p *Parser) list() ast.Expr {
+	var elements []ast.Expr
+	if !p.check(scanner.RIGHT_BRACKET) {
+		elements = append(elements, p.assignment())
+		for p.match(scanner.COMMA) {
+			elements = append(elements, p.assignment())
+		}
+	}
+	bracket := p.consume(scanner.RIGHT_BRACKET, "Expect ']' after list elements.")
+	return &ast.List{bracket, elements}
+}
+
+func (p *Parser) mapLiteral() ast.Expr {
+	var keys, values []ast.Expr
+	if !p.check(scanner.RIGHT_BRACE) {
+		for {
+			keys = append(keys, p.assignment())
+			p.consume(scanner.COLON, "Expect ':' after map key.")
+			values = append(values, p.assignment())
+			if !p.match(scanner.COMMA) {
+				break
+			}
+		}
+	}
+	brace := p.consume(scanner.RIGHT_BRACE, "Expect '}' after map entries.")
+	return &ast.Map{brace, keys, values}
+}
+
 func (p *Parser) match(types ...scanner.TokenType) bool {
 	for _, typ := range types {
 		if p.check(typ) {
@@ -473,6 +817,14 @@ func (p *Parser) check(typ scanner.TokenType) bool {
 	return p.peek().Typ == typ
 }
 
+// checkNext is like check but looks at the token after the current one.
+func (p *Parser) checkNext(typ scanner.TokenType) bool {
+	if p.isAtEnd() {
+		return false
+	}
+	return p.tokens[p.current+1].Typ == typ
+}
+
 func (p *Parser) advance() scanner.Token {
 	if !p.isAtEnd() {
 		p.current++
@@ -506,7 +858,7 @@ func (p *Parser) syncrhonize() {
 		}
 
 		switch p.peek().Typ {
-		case scanner.CLASS, scanner.FUN, scanner.VAR, scanner.FOR, scanner.IF, scanner.WHILE, scanner.PRINT, scanner.RETURN:
+		case scanner.CLASS, scanner.FUN, scanner.IMPORT, scanner.VAR, scanner.FOR, scanner.IF, scanner.WHILE, scanner.PRINT, scanner.RETURN:
 			return
 		}
 
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/rhomel/golox/pkg/scanner"
+	"github.com/rhomel/golox/pkg/util/fuzz"
+)
+
+type errorCounter struct {
+	errors int
+}
+
+func (e *errorCounter) Error(line int, message string) {
+	e.errors++
+}
+
+func (e *errorCounter) ParseError(token scanner.Token, message string) {
+	e.errors++
+}
+
+func FuzzParse(f *testing.F) {
+	fuzz.AddSamples(f, "../../samples")
+	f.Fuzz(func(t *testing.T, source string) {
+		reporter := &errorCounter{}
+		tokens := scanner.NewScanner(source, reporter).ScanTokens()
+		statements := NewParser(tokens, reporter).Parse()
+		for _, stmt := range statements {
+			if stmt == nil {
+				t.Fatal("Parse returned a nil statement")
+			}
+		}
+	})
+}
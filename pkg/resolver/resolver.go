@@ -1,11 +1,12 @@
 package resolver
 
 import (
+	"fmt"
+
 	ast "github.com/rhomel/golox/pkg/ast/gen"
 	"github.com/rhomel/golox/pkg/interpreter"
 	"github.com/rhomel/golox/pkg/scanner"
 	"github.com/rhomel/golox/pkg/util/check"
-	"github.com/rhomel/golox/pkg/util/exit"
 )
 
 type ErrorReporter interface {
@@ -35,6 +36,7 @@ type Resolver struct {
 	scopes          *stack
 	currentFunction FunctionType
 	curentClass     ClassType
+	loopDepth       int // the number of loops around the code being resolved
 }
 
 func NewResolver(in interpreter.Interpreter, reporter ErrorReporter) *Resolver {
@@ -53,22 +55,44 @@ func (re *Resolver) ResolveStmts(statements []ast.Stmt) {
 	}
 }
 
+func (re *Resolver) ResolveExpr(expr ast.Expr) {
+	re.resolve(expr)
+}
+
 func (re *Resolver) resolve(elem interface{}) {
 	switch v := elem.(type) {
 	case *ast.Binary:
 		v.AcceptVoid(re)
 	case *ast.Call:
 		v.AcceptVoid(re)
+	case *ast.Comma:
+		v.AcceptVoid(re)
+	case *ast.Compound:
+		v.AcceptVoid(re)
+	case *ast.Conditional:
+		v.AcceptVoid(re)
 	case *ast.Get:
 		v.AcceptVoid(re)
+	case *ast.GetIndex:
+		v.AcceptVoid(re)
 	case *ast.Grouping:
 		v.AcceptVoid(re)
+	case *ast.Lambda:
+		v.AcceptVoid(re)
+	case *ast.List:
+		v.AcceptVoid(re)
 	case *ast.Literal:
 		v.AcceptVoid(re)
 	case *ast.Logical:
 		v.AcceptVoid(re)
+	case *ast.Map:
+		v.AcceptVoid(re)
 	case *ast.Set:
 		v.AcceptVoid(re)
+	case *ast.SetIndex:
+		v.AcceptVoid(re)
+	case *ast.Stringify:
+		v.AcceptVoid(re)
 	case *ast.Super:
 		v.AcceptVoid(re)
 	case *ast.This:
@@ -83,8 +107,16 @@ func (re *Resolver) resolve(elem interface{}) {
 		v.AcceptVoid(re)
 	case *ast.IfStmt:
 		v.AcceptVoid(re)
+	case *ast.ImportStmt:
+		v.AcceptVoid(re)
 	case *ast.Block:
 		v.AcceptVoid(re)
+	case *ast.BreakStmt:
+		v.AcceptVoid(re)
+	case *ast.Catch:
+		v.AcceptVoid(re)
+	case *ast.ContinueStmt:
+		v.AcceptVoid(re)
 	case *ast.Expression:
 		v.AcceptVoid(re)
 	case *ast.Function:
@@ -93,20 +125,23 @@ func (re *Resolver) resolve(elem interface{}) {
 		v.AcceptVoid(re)
 	case *ast.ReturnStmt:
 		v.AcceptVoid(re)
+	case *ast.Throw:
+		v.AcceptVoid(re)
+	case *ast.Try:
+		v.AcceptVoid(re)
 	case *ast.VarStmt:
 		v.AcceptVoid(re)
 	case *ast.While:
 		v.AcceptVoid(re)
 	default:
-		exit.Exitf(exit.ExitSyntaxError, "unsupported expression/statement: %s", check.TypeOf(elem))
+		panic(fmt.Sprintf("unsupported expression/statement: %s", check.TypeOf(elem)))
 	}
 }
 
 func (re *Resolver) resolveLocal(expr ast.Expr, name scanner.Token) {
 	for i := re.scopes.size() - 1; i >= 0; i-- {
-		_, containsKey := re.scopes.get(i)[name.Lexeme]
-		if containsKey {
-			re.in.Resolve(expr, re.scopes.size()-1-i)
+		if local, ok := re.scopes.get(i).locals[name.Lexeme]; ok {
+			re.in.Resolve(expr, re.scopes.size()-1-i, local.slot)
 			return
 		}
 	}
@@ -115,6 +150,9 @@ func (re *Resolver) resolveLocal(expr ast.Expr, name scanner.Token) {
 func (re *Resolver) resolveFunction(function *ast.Function, typ FunctionType) {
 	enclosingFunction := re.currentFunction
 	re.currentFunction = typ
+	// break and continue can't leave a function
+	enclosingLoopDepth := re.loopDepth
+	re.loopDepth = 0
 	re.beginScope()
 	for _, param := range function.Params {
 		re.declare(param)
@@ -122,11 +160,12 @@ func (re *Resolver) resolveFunction(function *ast.Function, typ FunctionType) {
 	}
 	re.ResolveStmts(function.Body)
 	re.endScope()
+	re.loopDepth = enclosingLoopDepth
 	re.currentFunction = enclosingFunction
 }
 
 func (re *Resolver) beginScope() {
-	re.scopes.push(make(map[string]bool))
+	re.scopes.push(newScope())
 }
 
 func (re *Resolver) endScope() {
@@ -138,18 +177,21 @@ func (re *Resolver) declare(name scanner.Token) {
 		return
 	}
 	scope := re.scopes.peek()
-	if _, ok := scope[name.Lexeme]; ok {
+	if local, ok := scope.locals[name.Lexeme]; ok {
 		re.reporter.ResolveError(name, "Already a variable with this name in this scope.")
+		local.defined = false
+		return
 	}
-	scope[name.Lexeme] = false
+	scope.declare(name.Lexeme)
 }
 
 func (re *Resolver) define(name scanner.Token) {
 	if re.scopes.isEmpty() {
 		return
 	}
-	scope := re.scopes.peek()
-	scope[name.Lexeme] = true
+	if local, ok := re.scopes.peek().locals[name.Lexeme]; ok {
+		local.defined = true
+	}
 }
 
 func (re *Resolver) VisitBlockStmtVoid(block *ast.Block) {
@@ -180,10 +222,10 @@ func (re *Resolver) VisitClassStmtVoid(class *ast.Class) {
 		re.curentClass = SUBCLASS
 		re.resolve(class.Superclass)
 		re.beginScope()
-		re.scopes.peek()["super"] = true
+		re.scopes.peek().declare("super").defined = true
 	}
 	re.beginScope()
-	re.scopes.peek()["this"] = true
+	re.scopes.peek().declare("this").defined = true
 	for _, method := range class.Methods {
 		declaration := METHOD
 		if method.Name.Lexeme == "init" {
@@ -206,6 +248,11 @@ func (re *Resolver) VisitIfStmtStmtVoid(stmt *ast.IfStmt) {
 	}
 }
 
+func (re *Resolver) VisitImportStmtStmtVoid(stmt *ast.ImportStmt) {
+	re.declare(stmt.Name)
+	re.define(stmt.Name)
+}
+
 func (re *Resolver) VisitPrintStmtVoid(stmt *ast.Print) {
 	re.resolve(stmt.Expression)
 }
@@ -232,7 +279,48 @@ func (re *Resolver) VisitVarStmtStmtVoid(stmt *ast.VarStmt) {
 
 func (re *Resolver) VisitWhileStmtVoid(while *ast.While) {
 	re.resolve(while.Condition)
+	re.loopDepth++
 	re.resolve(while.Body)
+	re.loopDepth--
+	if while.Increment != nil {
+		re.resolve(while.Increment)
+	}
+}
+
+func (re *Resolver) VisitBreakStmtStmtVoid(stmt *ast.BreakStmt) {
+	if re.loopDepth == 0 {
+		re.reporter.ResolveError(stmt.Keyword, "Can't use 'break' outside of a loop.")
+	}
+}
+
+func (re *Resolver) VisitContinueStmtStmtVoid(stmt *ast.ContinueStmt) {
+	if re.loopDepth == 0 {
+		re.reporter.ResolveError(stmt.Keyword, "Can't use 'continue' outside of a loop.")
+	}
+}
+
+func (re *Resolver) VisitThrowStmtVoid(stmt *ast.Throw) {
+	re.resolve(stmt.Value)
+}
+
+func (re *Resolver) VisitTryStmtVoid(stmt *ast.Try) {
+	re.resolve(stmt.Body)
+	if stmt.Catch != nil {
+		re.resolve(stmt.Catch)
+	}
+	if stmt.Finally != nil {
+		re.resolve(stmt.Finally)
+	}
+}
+
+// VisitCatchStmtVoid resolves the catch clause of a try statement. The caught
+// value is the first local in the clause's scope.
+func (re *Resolver) VisitCatchStmtVoid(catch *ast.Catch) {
+	re.beginScope()
+	re.declare(catch.Name)
+	re.define(catch.Name)
+	re.ResolveStmts(catch.Body)
+	re.endScope()
 }
 
 func (re *Resolver) VisitAssignExprVoid(assign *ast.Assign) {
@@ -252,14 +340,45 @@ func (re *Resolver) VisitCallExprVoid(expr *ast.Call) {
 	}
 }
 
+func (re *Resolver) VisitCommaExprVoid(comma *ast.Comma) {
+	re.resolve(comma.Left)
+	re.resolve(comma.Right)
+}
+
+func (re *Resolver) VisitCompoundExprVoid(compound *ast.Compound) {
+	re.resolve(compound.Target)
+	re.resolve(compound.Value)
+}
+
+func (re *Resolver) VisitConditionalExprVoid(conditional *ast.Conditional) {
+	re.resolve(conditional.Condition)
+	re.resolve(conditional.Then)
+	re.resolve(conditional.Else)
+}
+
 func (re *Resolver) VisitGetExprVoid(get *ast.Get) {
 	re.resolve(get.Object)
 }
 
+func (re *Resolver) VisitGetIndexExprVoid(index *ast.GetIndex) {
+	re.resolve(index.Object)
+	re.resolve(index.Index)
+}
+
 func (re *Resolver) VisitGroupingExprVoid(grouping *ast.Grouping) {
 	re.resolve(grouping.Expression)
 }
 
+func (re *Resolver) VisitLambdaExprVoid(lambda *ast.Lambda) {
+	re.resolveFunction(lambda.Function, FUNCTION)
+}
+
+func (re *Resolver) VisitListExprVoid(list *ast.List) {
+	for _, element := range list.Elements {
+		re.resolve(element)
+	}
+}
+
 func (re *Resolver) VisitLiteralExprVoid(literal *ast.Literal) {
 	// no-op
 }
@@ -269,11 +388,28 @@ func (re *Resolver) VisitLogicalExprVoid(logical *ast.Logical) {
 	re.resolve(logical.Right)
 }
 
+func (re *Resolver) VisitMapExprVoid(m *ast.Map) {
+	for i, key := range m.Keys {
+		re.resolve(key)
+		re.resolve(m.Values[i])
+	}
+}
+
 func (re *Resolver) VisitSetExprVoid(set *ast.Set) {
 	re.resolve(set.Value)
 	re.resolve(set.Object)
 }
 
+func (re *Resolver) VisitSetIndexExprVoid(index *ast.SetIndex) {
+	re.resolve(index.Value)
+	re.resolve(index.Object)
+	re.resolve(index.Index)
+}
+
+func (re *Resolver) VisitStringifyExprVoid(stringify *ast.Stringify) {
+	re.resolve(stringify.Expression)
+}
+
 func (re *Resolver) VisitSuperExprVoid(super *ast.Super) {
 	if re.curentClass == NOCLASS {
 		re.reporter.ResolveError(super.Keyword, "Can't use 'super' outside of a class.")
@@ -296,16 +432,38 @@ func (re *Resolver) VisitUnaryExprVoid(unary *ast.Unary) {
 }
 
 func (re *Resolver) VisitVariableExprVoid(variable *ast.Variable) {
-	isEmpty := re.scopes.isEmpty()
-	v, ok := re.scopes.peek()[variable.Name.Lexeme]
-	if !isEmpty && v == false && ok {
-		re.reporter.ResolveError(variable.Name, "Can't read local variable in its own initializer.")
+	if !re.scopes.isEmpty() {
+		if local, ok := re.scopes.peek().locals[variable.Name.Lexeme]; ok && !local.defined {
+			re.reporter.ResolveError(variable.Name, "Can't read local variable in its own initializer.")
+		}
 	}
 	re.resolveLocal(variable, variable.Name)
 }
 
+// scope tracks the locals declared in one block or function body. Each local
+// gets the next slot in declaration order, which is the order the interpreter
+// defines them in at runtime.
+type scope struct {
+	locals map[string]*local
+}
+
+type local struct {
+	slot    int
+	defined bool
+}
+
+func newScope() *scope {
+	return &scope{make(map[string]*local)}
+}
+
+func (s *scope) declare(name string) *local {
+	local := &local{slot: len(s.locals)}
+	s.locals[name] = local
+	return local
+}
+
 type stack struct {
-	elems []map[string]bool
+	elems []*scope
 }
 
 func (s *stack) size() int {
@@ -316,11 +474,11 @@ func (s *stack) isEmpty() bool {
 	return s.size() == 0
 }
 
-func (s *stack) push(elem map[string]bool) {
+func (s *stack) push(elem *scope) {
 	s.elems = append(s.elems, elem)
 }
 
-func (s *stack) pop() map[string]bool {
+func (s *stack) pop() *scope {
 	if s.size() == 0 {
 		return nil
 	}
@@ -330,7 +488,7 @@ func (s *stack) pop() map[string]bool {
 	return elem
 }
 
-func (s *stack) peek() map[string]bool {
+func (s *stack) peek() *scope {
 	if s.size() == 0 {
 		return nil
 	}
@@ -338,7 +496,7 @@ func (s *stack) peek() map[string]bool {
 	return s.elems[last]
 }
 
-func (s *stack) get(i int) map[string]bool {
+func (s *stack) get(i int) *scope {
 	// TODO: check bounds
 	return s.elems[i]
 }
@@ -0,0 +1,44 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/rhomel/golox/pkg/interpreter"
+	"github.com/rhomel/golox/pkg/parser"
+	"github.com/rhomel/golox/pkg/scanner"
+	"github.com/rhomel/golox/pkg/util/fuzz"
+)
+
+type errorCounter struct {
+	errors int
+}
+
+func (e *errorCounter) Error(line int, message string) {
+	e.errors++
+}
+
+func (e *errorCounter) ParseError(token scanner.Token, message string) {
+	e.errors++
+}
+
+func (e *errorCounter) ResolveError(token scanner.Token, message string) {
+	e.errors++
+}
+
+func (e *errorCounter) RuntimeError(token scanner.Token, message string, trace []interpreter.StackFrame) {
+	e.errors++
+}
+
+func FuzzResolve(f *testing.F) {
+	fuzz.AddSamples(f, "../../samples")
+	f.Fuzz(func(t *testing.T, source string) {
+		reporter := &errorCounter{}
+		tokens := scanner.NewScanner(source, reporter).ScanTokens()
+		statements := parser.NewParser(tokens, reporter).Parse()
+		if reporter.errors > 0 {
+			return
+		}
+		in := interpreter.NewTreeWalkInterpreter(reporter)
+		NewResolver(in, reporter).ResolveStmts(statements)
+	})
+}
@@ -3,77 +3,121 @@ package scanner
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rhomel/golox/pkg/number"
 )
 
 type TokenType string
 
 const (
 	// Single-character tokens.
-	LEFT_PAREN  TokenType = "LEFT_PAREN"
-	RIGHT_PAREN TokenType = "RIGHT_PAREN"
-	LEFT_BRACE  TokenType = "LEFT_BRACE"
-	RIGHT_BRACE TokenType = "RIGHT_BRACE"
-	COMMA       TokenType = "COMMA"
-	DOT         TokenType = "DOT"
-	MINUS       TokenType = "MINUS"
-	PLUS        TokenType = "PLUS"
-	SEMICOLON   TokenType = "SEMICOLON"
-	SLASH       TokenType = "SLASH"
-	STAR        TokenType = "STAR"
+	LEFT_PAREN    TokenType = "LEFT_PAREN"
+	RIGHT_PAREN   TokenType = "RIGHT_PAREN"
+	LEFT_BRACE    TokenType = "LEFT_BRACE"
+	RIGHT_BRACE   TokenType = "RIGHT_BRACE"
+	LEFT_BRACKET  TokenType = "LEFT_BRACKET"
+	RIGHT_BRACKET TokenType = "RIGHT_BRACKET"
+	COLON         TokenType = "COLON"
+	COMMA         TokenType = "COMMA"
+	DOT           TokenType = "DOT"
+	MINUS         TokenType = "MINUS"
+	PLUS          TokenType = "PLUS"
+	SEMICOLON     TokenType = "SEMICOLON"
+	SLASH         TokenType = "SLASH"
+	STAR          TokenType = "STAR"
+	PERCENT       TokenType = "PERCENT"
+	AMPERSAND     TokenType = "AMPERSAND"
+	PIPE          TokenType = "PIPE"
+	CARET         TokenType = "CARET"
+	QUESTION      TokenType = "QUESTION"
 
 	// One or two character tokens.
-	BANG          TokenType = "BANG"
-	BANG_EQUAL    TokenType = "BANG_EQUAL"
-	EQUAL         TokenType = "EQUAL"
-	EQUAL_EQUAL   TokenType = "EQUAL_EQUAL"
-	GREATER       TokenType = "GREATER"
-	GREATER_EQUAL TokenType = "GREATER_EQUAL"
-	LESS          TokenType = "LESS"
-	LESS_EQUAL    TokenType = "LESS_EQUAL"
+	BANG            TokenType = "BANG"
+	BANG_EQUAL      TokenType = "BANG_EQUAL"
+	EQUAL           TokenType = "EQUAL"
+	EQUAL_EQUAL     TokenType = "EQUAL_EQUAL"
+	ARROW           TokenType = "ARROW"
+	GREATER         TokenType = "GREATER"
+	GREATER_EQUAL   TokenType = "GREATER_EQUAL"
+	LESS            TokenType = "LESS"
+	LESS_EQUAL      TokenType = "LESS_EQUAL"
+	STAR_STAR       TokenType = "STAR_STAR"
+	TILDE           TokenType = "TILDE"
+	TILDE_SLASH     TokenType = "TILDE_SLASH"
+	LESS_LESS       TokenType = "LESS_LESS"
+	GREATER_GREATER TokenType = "GREATER_GREATER"
+	PLUS_EQUAL      TokenType = "PLUS_EQUAL"
+	MINUS_EQUAL     TokenType = "MINUS_EQUAL"
+	STAR_EQUAL      TokenType = "STAR_EQUAL"
+	SLASH_EQUAL     TokenType = "SLASH_EQUAL"
+	PERCENT_EQUAL   TokenType = "PERCENT_EQUAL"
+	PLUS_PLUS       TokenType = "PLUS_PLUS"
+	MINUS_MINUS     TokenType = "MINUS_MINUS"
 
 	// Literals.
 	IDENTIFIER TokenType = "IDENTIFIER"
 	STRING     TokenType = "STRING"
-	NUMBER     TokenType = "NUMBER"
+	// INTERPOLATION is the part of a string before an embedded expression.
+	INTERPOLATION TokenType = "INTERPOLATION"
+	NUMBER        TokenType = "NUMBER"
+
+	// Trivia. Comments aren't part of the token stream; see Scanner.Comments.
+	COMMENT TokenType = "COMMENT"
 
 	// Keywords.
-	AND    TokenType = "AND"
-	CLASS  TokenType = "CLASS"
-	ELSE   TokenType = "ELSE"
-	FALSE  TokenType = "FALSE"
-	FUN    TokenType = "FUN"
-	FOR    TokenType = "FOR"
-	IF     TokenType = "IF"
-	NIL    TokenType = "NIL"
-	OR     TokenType = "OR"
-	PRINT  TokenType = "PRINT"
-	RETURN TokenType = "RETURN"
-	SUPER  TokenType = "SUPER"
-	THIS   TokenType = "THIS"
-	TRUE   TokenType = "TRUE"
-	VAR    TokenType = "VAR"
-	WHILE  TokenType = "WHILE"
+	AND      TokenType = "AND"
+	BREAK    TokenType = "BREAK"
+	CATCH    TokenType = "CATCH"
+	CLASS    TokenType = "CLASS"
+	CONTINUE TokenType = "CONTINUE"
+	ELSE     TokenType = "ELSE"
+	FALSE    TokenType = "FALSE"
+	FINALLY  TokenType = "FINALLY"
+	FUN      TokenType = "FUN"
+	FOR      TokenType = "FOR"
+	IF       TokenType = "IF"
+	IMPORT   TokenType = "IMPORT"
+	NIL      TokenType = "NIL"
+	OR       TokenType = "OR"
+	PRINT    TokenType = "PRINT"
+	RETURN   TokenType = "RETURN"
+	SUPER    TokenType = "SUPER"
+	THIS     TokenType = "THIS"
+	THROW    TokenType = "THROW"
+	TRUE     TokenType = "TRUE"
+	TRY      TokenType = "TRY"
+	VAR      TokenType = "VAR"
+	WHILE    TokenType = "WHILE"
 
 	EOF TokenType = "EOF"
 )
 
 var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":      AND,
+	"break":    BREAK,
+	"catch":    CATCH,
+	"class":    CLASS,
+	"continue": CONTINUE,
+	"else":     ELSE,
+	"false":    FALSE,
+	"finally":  FINALLY,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"import":   IMPORT,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"return":   RETURN,
+	"super":    SUPER,
+	"this":     THIS,
+	"throw":    THROW,
+	"true":     TRUE,
+	"try":      TRY,
+	"var":      VAR,
+	"while":    WHILE,
 }
 
 type Token struct {
@@ -90,12 +134,18 @@ func (t *Token) String() string {
 type Scanner struct {
 	reporter ErrorReporter
 
-	source []rune
-	tokens []*Token
+	source   []rune
+	tokens   []*Token
+	comments []*Token
 
 	start   int
 	current int
 	line    int
+
+	// interpolations holds the number of unclosed braces in each embedded
+	// expression being scanned, innermost last. The "}" that closes the
+	// expression continues the string.
+	interpolations []int
 }
 
 type ErrorReporter interface {
@@ -121,6 +171,15 @@ func (s *Scanner) ScanTokens() []*Token {
 	return s.tokens
 }
 
+// Comments returns the line and block comments scanned by ScanTokens in the
+// order they appear in the source. The parser ignores comments but tools that
+// reproduce the source, like a formatter, need them. Each comment is a COMMENT
+// token whose lexeme includes the delimiters and whose line is the line it
+// starts on.
+func (s *Scanner) Comments() []*Token {
+	return s.comments
+}
+
 func (s *Scanner) scanToken() {
 	c := s.advance()
 	switch c {
@@ -129,21 +188,76 @@ func (s *Scanner) scanToken() {
 	case ')':
 		s.addToken(RIGHT_PAREN)
 	case '{':
+		if n := len(s.interpolations); n > 0 {
+			s.interpolations[n-1]++
+		}
 		s.addToken(LEFT_BRACE)
 	case '}':
+		if n := len(s.interpolations); n > 0 {
+			if s.interpolations[n-1] == 0 {
+				s.interpolations = s.interpolations[:n-1]
+				s.string()
+				return
+			}
+			s.interpolations[n-1]--
+		}
 		s.addToken(RIGHT_BRACE)
+	case '[':
+		s.addToken(LEFT_BRACKET)
+	case ']':
+		s.addToken(RIGHT_BRACKET)
+	case ':':
+		s.addToken(COLON)
+	case '?':
+		s.addToken(QUESTION)
 	case ',':
 		s.addToken(COMMA)
 	case '.':
 		s.addToken(DOT)
 	case '-':
-		s.addToken(MINUS)
+		if s.match('-') {
+			s.addToken(MINUS_MINUS)
+		} else if s.match('=') {
+			s.addToken(MINUS_EQUAL)
+		} else {
+			s.addToken(MINUS)
+		}
 	case '+':
-		s.addToken(PLUS)
+		if s.match('+') {
+			s.addToken(PLUS_PLUS)
+		} else if s.match('=') {
+			s.addToken(PLUS_EQUAL)
+		} else {
+			s.addToken(PLUS)
+		}
 	case ';':
 		s.addToken(SEMICOLON)
 	case '*':
-		s.addToken(STAR)
+		if s.match('*') {
+			s.addToken(STAR_STAR)
+		} else if s.match('=') {
+			s.addToken(STAR_EQUAL)
+		} else {
+			s.addToken(STAR)
+		}
+	case '%':
+		if s.match('=') {
+			s.addToken(PERCENT_EQUAL)
+		} else {
+			s.addToken(PERCENT)
+		}
+	case '&':
+		s.addToken(AMPERSAND)
+	case '|':
+		s.addToken(PIPE)
+	case '^':
+		s.addToken(CARET)
+	case '~':
+		if s.match('/') {
+			s.addToken(TILDE_SLASH)
+		} else {
+			s.addToken(TILDE)
+		}
 	case '!':
 		if s.match('=') {
 			s.addToken(BANG_EQUAL)
@@ -153,18 +267,24 @@ func (s *Scanner) scanToken() {
 	case '=':
 		if s.match('=') {
 			s.addToken(EQUAL_EQUAL)
+		} else if s.match('>') {
+			s.addToken(ARROW)
 		} else {
 			s.addToken(EQUAL)
 		}
 	case '<':
 		if s.match('=') {
 			s.addToken(LESS_EQUAL)
+		} else if s.match('<') {
+			s.addToken(LESS_LESS)
 		} else {
 			s.addToken(LESS)
 		}
 	case '>':
 		if s.match('=') {
 			s.addToken(GREATER_EQUAL)
+		} else if s.match('>') {
+			s.addToken(GREATER_GREATER)
 		} else {
 			s.addToken(GREATER)
 		}
@@ -174,6 +294,11 @@ func (s *Scanner) scanToken() {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			s.addComment(s.line)
+		} else if s.match('*') {
+			s.blockComment()
+		} else if s.match('=') {
+			s.addToken(SLASH_EQUAL)
 		} else {
 			s.addToken(SLASH)
 		}
@@ -187,6 +312,8 @@ func (s *Scanner) scanToken() {
 		s.line++
 	case '"':
 		s.string()
+	case '`':
+		s.rawString()
 	default:
 		if s.isDigit(c) {
 			s.number()
@@ -220,6 +347,34 @@ func (s *Scanner) addTokenLiteral(typ TokenType, literal interface{}) {
 	s.tokens = append(s.tokens, &Token{typ, string(text), literal, s.line})
 }
 
+// addComment records the comment from start to current, which started on line.
+func (s *Scanner) addComment(line int) {
+	text := s.source[s.start:s.current]
+	s.comments = append(s.comments, &Token{COMMENT, string(text), nil, line})
+}
+
+// blockComment scans a comment between "/*" and "*/". Block comments nest so
+// that code containing one can be commented out.
+func (s *Scanner) blockComment() {
+	line := s.line
+	depth := 1
+	for depth > 0 {
+		if s.isAtEnd() {
+			s.reporter.Error(s.line, "Unterminated block comment.")
+			return
+		}
+		switch c := s.advance(); {
+		case c == '\n':
+			s.line++
+		case c == '/' && s.match('*'):
+			depth++
+		case c == '*' && s.match('/'):
+			depth--
+		}
+	}
+	s.addComment(line)
+}
+
 func (s *Scanner) advance() rune {
 	current := s.current
 	s.current++
@@ -259,8 +414,100 @@ func (s *Scanner) isAtEnd() bool {
 	return s.current >= len(s.source)
 }
 
+// string scans a string, or the rest of one after an embedded expression. A
+// string with embedded expressions is scanned as an INTERPOLATION token for
+// the part before each expression, the tokens of the expression and a STRING
+// token starting with the "}" that closes the last expression. The literal of
+// each token has its escape sequences decoded; only the first invalid one in a
+// part is reported.
 func (s *Scanner) string() {
+	var value strings.Builder
+	valid := true
 	for s.peek() != '"' && !s.isAtEnd() {
+		c := s.advance()
+		switch {
+		case c == '$' && s.peek() == '{':
+			s.advance()
+			s.addTokenLiteral(INTERPOLATION, value.String())
+			s.interpolations = append(s.interpolations, 0)
+			return
+		case c == '\\' && !s.isAtEnd():
+			if message, ok := s.escape(&value); !ok && valid {
+				s.reporter.Error(s.line, message)
+				valid = false
+			}
+		default:
+			if c == '\n' {
+				s.line++
+			}
+			value.WriteRune(c)
+		}
+	}
+
+	if s.isAtEnd() {
+		s.reporter.Error(s.line, "Unterminated string.")
+		return
+	}
+
+	// consume closing '"'
+	s.advance()
+
+	s.addTokenLiteral(STRING, value.String())
+}
+
+// escape decodes the escape sequence after a backslash into value. It returns
+// the error message if the sequence is invalid.
+func (s *Scanner) escape(value *strings.Builder) (string, bool) {
+	c := s.advance()
+	switch c {
+	case 'n':
+		value.WriteRune('\n')
+	case 'r':
+		value.WriteRune('\r')
+	case 't':
+		value.WriteRune('\t')
+	case '0':
+		value.WriteRune(0)
+	case '"', '\\', '$':
+		value.WriteRune(c)
+	case 'u':
+		return s.unicodeEscape(value)
+	default:
+		if c == '\n' {
+			s.line++
+		}
+		return fmt.Sprintf("Invalid escape sequence '\\%s'.", runeToReadableString(c)), false
+	}
+	return "", true
+}
+
+// unicodeEscape decodes the code point of a "\u{...}" escape sequence, which
+// has one to six hexadecimal digits.
+func (s *Scanner) unicodeEscape(value *strings.Builder) (string, bool) {
+	const message = "Invalid Unicode escape sequence."
+	if !s.match('{') {
+		return message, false
+	}
+	start := s.current
+	for s.isHexDigit(s.peek()) {
+		s.advance()
+	}
+	digits := string(s.source[start:s.current])
+	if !s.match('}') || len(digits) == 0 || len(digits) > 6 {
+		return message, false
+	}
+	code, _ := strconv.ParseInt(digits, 16, 32)
+	if !utf8.ValidRune(rune(code)) {
+		return message, false
+	}
+	value.WriteRune(rune(code))
+	return "", true
+}
+
+// rawString scans a string between backticks, which can span lines and has no
+// escape sequences or embedded expressions. It is not part of the book.
+func (s *Scanner) rawString() {
+	for s.peek() != '`' && !s.isAtEnd() {
 		if s.peek() == '\n' {
 			s.line++
 		}
@@ -272,39 +519,69 @@ func (s *Scanner) string() {
 		return
 	}
 
-	// consume closing '"'
+	// consume closing '`'
 	s.advance()
 
-	// ignore surrounding quotes
-	value := string(s.source[s.start+1 : s.current-1])
-	s.addTokenLiteral(STRING, value)
+	s.addTokenLiteral(STRING, string(s.source[s.start+1:s.current-1]))
 }
 
+// number scans a number literal, which package number parses. Besides the
+// decimal numbers of the book a literal can be an integer in another base like
+// "0x1f", have an exponent like "1e9" and separate digits with "_".
 func (s *Scanner) number() {
-	// scan digits
-	for s.isDigit(s.peek()) {
+	if s.source[s.start] == '0' && number.Base(s.peek()) != 10 {
+		// consume the base letter and whatever was meant as digits so that an
+		// invalid digit is reported
 		s.advance()
-	}
+		for s.isAlphaNumeric(s.peek()) {
+			s.advance()
+		}
+	} else {
+		s.digits()
 
-	// floating point
-	if s.peek() == '.' && s.isDigit(s.peekNext()) {
-		// consume '.'
-		s.advance()
+		// floating point
+		if s.peek() == '.' && s.isDigit(s.peekNext()) {
+			// consume '.'
+			s.advance()
+			s.digits()
+		}
 
-		// scan remaining digits
-		for s.isDigit(s.peek()) {
+		if s.exponent() {
+			// consume 'e' and the sign
 			s.advance()
+			if s.peek() == '+' || s.peek() == '-' {
+				s.advance()
+			}
+			s.digits()
 		}
 	}
 
-	svalue := string(s.source[s.start:s.current])
-	value, err := strconv.ParseFloat(svalue, 64)
-	if err != nil {
-		s.reporter.Error(s.line, fmt.Sprintf("Unsupported number '%s': %v", svalue, err))
+	value, message, ok := number.Parse(string(s.source[s.start:s.current]))
+	if !ok {
+		s.reporter.Error(s.line, message)
 	}
 	s.addTokenLiteral(NUMBER, value)
 }
 
+// digits scans decimal digits and the "_" separating them.
+func (s *Scanner) digits() {
+	for s.isDigit(s.peek()) || s.peek() == '_' {
+		s.advance()
+	}
+}
+
+// exponent reports whether an exponent like "e9" or "E-3" is next.
+func (s *Scanner) exponent() bool {
+	if s.peek() != 'e' && s.peek() != 'E' {
+		return false
+	}
+	next := s.current + 1
+	if next < len(s.source) && (s.source[next] == '+' || s.source[next] == '-') {
+		next++
+	}
+	return next < len(s.source) && s.isDigit(s.source[next])
+}
+
 func (s *Scanner) identifier() {
 	for s.isAlphaNumeric(s.peek()) {
 		s.advance()
@@ -324,6 +601,10 @@ func (s *Scanner) isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 
+func (s *Scanner) isHexDigit(c rune) bool {
+	return s.isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
 func (s *Scanner) isAlpha(c rune) bool {
 	return (c >= 'a' && c <= 'z') ||
 		(c >= 'A' && c <= 'Z') ||
@@ -0,0 +1,30 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/rhomel/golox/pkg/util/fuzz"
+)
+
+type errorCounter struct {
+	errors int
+}
+
+func (e *errorCounter) Error(line int, message string) {
+	e.errors++
+}
+
+func FuzzScanTokens(f *testing.F) {
+	fuzz.AddSamples(f, "../../samples")
+	f.Fuzz(func(t *testing.T, source string) {
+		tokens := NewScanner(source, &errorCounter{}).ScanTokens()
+		if len(tokens) == 0 || tokens[len(tokens)-1].Typ != EOF {
+			t.Fatalf("token stream must end with EOF: %v", tokens)
+		}
+		for _, token := range tokens[:len(tokens)-1] {
+			if token.Typ == EOF {
+				t.Fatalf("unexpected EOF token before the end of the stream: %v", tokens)
+			}
+		}
+	})
+}
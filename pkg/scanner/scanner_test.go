@@ -0,0 +1,46 @@
+package scanner
+
+import "testing"
+
+func TestComments(t *testing.T) {
+	source := "// line\nprint /* a /* nested */\ncomment */ 1;\n"
+	scanner := NewScanner(source, &errorCounter{})
+	tokens := scanner.ScanTokens()
+	var types []TokenType
+	for _, token := range tokens {
+		types = append(types, token.Typ)
+	}
+	want := []TokenType{PRINT, NUMBER, SEMICOLON, EOF}
+	if len(types) != len(want) {
+		t.Fatalf("comments must not be tokens: got %v, want %v", types, want)
+	}
+	// lines inside a block comment are counted
+	if number := tokens[1]; number.Line != 3 {
+		t.Errorf("number on line %d, want 3", number.Line)
+	}
+	comments := scanner.Comments()
+	wantComments := []Token{
+		{COMMENT, "// line", nil, 1},
+		{COMMENT, "/* a /* nested */\ncomment */", nil, 2},
+	}
+	if len(comments) != len(wantComments) {
+		t.Fatalf("got %d comments, want %d", len(comments), len(wantComments))
+	}
+	for i, comment := range comments {
+		if *comment != wantComments[i] {
+			t.Errorf("comment %d = %v on line %d, want %v on line %d", i, comment, comment.Line, &wantComments[i], wantComments[i].Line)
+		}
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	errors := &errorCounter{}
+	scanner := NewScanner("/* a /* b */\n", errors)
+	tokens := scanner.ScanTokens()
+	if errors.errors != 1 {
+		t.Errorf("got %d errors, want 1", errors.errors)
+	}
+	if len(tokens) != 1 || len(scanner.Comments()) != 0 {
+		t.Errorf("got tokens %v and comments %v, want only EOF", tokens, scanner.Comments())
+	}
+}
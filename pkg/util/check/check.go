@@ -14,6 +14,9 @@ func IsNil(i interface{}) bool {
 }
 
 func TypeOf(it interface{}) string {
+	if it == nil {
+		return "nil"
+	}
 	typ := reflect.TypeOf(it)
 	switch typ.Kind() {
 	case reflect.Ptr:
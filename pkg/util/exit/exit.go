@@ -8,6 +8,7 @@ import (
 const (
 	ExitCodeOK         = 0
 	ExitCodeUsageError = 1
+	ExitTestFailure    = 2
 	ExitSyntaxError    = 65
 	ExitRuntimeError   = 70
 	ExitIOError        = 100
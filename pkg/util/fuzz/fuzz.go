@@ -0,0 +1,96 @@
+package fuzz
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+// Helpers shared by the fuzz targets in the scanner, parser, resolver and
+// interpreter packages. Run a target with, for example:
+//
+//   go test ./pkg/parser -run '^$' -fuzz FuzzParse -fuzztime 30s
+
+const (
+	// RunTimeout is how long a fuzzed program may run before it is interrupted.
+	RunTimeout = 100 * time.Millisecond
+	// StopTimeout is how long an interrupted program has to stop before the
+	// input is reported as a hang.
+	StopTimeout = 5 * time.Second
+)
+
+// AddSamples adds every program in the samples directory to the seed corpus.
+// dir is the path to the samples directory relative to the test package.
+func AddSamples(f *testing.F, dir string) {
+	f.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.lox"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	if len(files) == 0 {
+		f.Fatalf("no samples found in %s", dir)
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(b))
+	}
+}
+
+// Silence redirects os.Stdout and os.Stderr to the null device until the test
+// finishes. The interpreters print directly to both.
+func Silence(tb testing.TB) {
+	tb.Helper()
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = null, null
+	tb.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		null.Close()
+	})
+}
+
+// RunBounded calls run in a new goroutine and calls interrupt if it hasn't
+// returned within RunTimeout. The input fails if run panics or if it still
+// hasn't returned StopTimeout after the interrupt.
+func RunBounded(tb testing.TB, run func(), interrupt func()) {
+	tb.Helper()
+	done := make(chan string, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Sprintf("panic: %v\n%s", r, debug.Stack())
+				return
+			}
+			done <- ""
+		}()
+		run()
+	}()
+	timer := time.NewTimer(RunTimeout)
+	defer timer.Stop()
+	select {
+	case failure := <-done:
+		if failure != "" {
+			tb.Fatal(failure)
+		}
+		return
+	case <-timer.C:
+		interrupt()
+	}
+	select {
+	case failure := <-done:
+		if failure != "" {
+			tb.Fatal(failure)
+		}
+	case <-time.After(StopTimeout):
+		tb.Fatalf("program did not stop within %v of being interrupted", StopTimeout)
+	}
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Both interpreter implementations write directly to os.Stdout/os.Stderr and
+// terminate with os.Exit, so the most reliable way to observe a run is to
+// execute the golox binary as a separate process.
+
+const (
+	TreeWalk = "treewalk"
+	Closure  = "closure"
+	VM       = "vm"
+)
+
+// Implementations lists every value accepted by the golox -implementation flag.
+var Implementations = []string{TreeWalk, Closure, VM}
+
+// DefaultTimeout bounds a single run so a runaway script can't hang a test.
+const DefaultTimeout = 10 * time.Second
+
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	TimedOut bool
+}
+
+// Build compiles cmd/golox into dir and returns the path to the binary.
+func Build(dir string) (string, error) {
+	binary := filepath.Join(dir, "golox")
+	cmd := exec.Command("go", "build", "-o", binary, "github.com/rhomel/golox/cmd/golox")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("go build failed: %v\n%s", err, out)
+	}
+	return binary, nil
+}
+
+// Run executes file with the golox binary using the given implementation.
+func Run(binary, implementation, file string, timeout time.Duration) (*Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, "-implementation", implementation, file)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	result := &Result{
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		result.TimedOut = true
+		result.ExitCode = -1
+		return result, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
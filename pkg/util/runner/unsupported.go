@@ -0,0 +1,48 @@
+package runner
+
+// Unsupported lists, for each implementation, the samples that use features it
+// doesn't have yet along with the reason why. The golden tests skip them and
+// the differential tests don't compare them.
+var Unsupported = map[string]map[string]string{
+	VM: {
+		"10.2.1-native-function.lox":                      "native functions not implemented",
+		"10.3-function-definition.lox":                    "functions not implemented",
+		"10.4.1-interpret-functions.lox":                  "functions not implemented",
+		"10.5.1-returns.lox":                              "functions not implemented",
+		"10.6-closures.lox":                               "closures not implemented",
+		"11.1-static-scope.lox":                           "functions not implemented",
+		"11.5-duplicate-variable-definitions.lox":         "functions not implemented",
+		"11.5.1-top-level-return.lox":                     "return statements not implemented",
+		"12.2-class-declarations.lox":                     "classes not implemented",
+		"12.3-class-instances.lox":                        "classes not implemented",
+		"12.4-instance-properties.lox":                    "classes not implemented",
+		"12.5-class-methods.lox":                          "classes not implemented",
+		"12.6-this-keyword.lox":                           "classes not implemented",
+		"12.6.1-invalid-this.lox":                         "classes not implemented",
+		"12.7-initializer.lox":                            "classes not implemented",
+		"12.7.1-initializer-always-returns-instance.lox":  "classes not implemented",
+		"12.7.2-initializer-cannot-have-return-value.lox": "classes not implemented",
+		"13.1-parse-superclass.lox":                       "classes not implemented",
+		"13.2-inheriting-methods.lox":                     "classes not implemented",
+		"13.3-calling-superclass-methods.lox":             "classes not implemented",
+		"13.3.3-invalid-super.lox":                        "classes not implemented",
+		"14-fib-bench.lox":                                "functions not implemented",
+		"7.4.2-sample-runtime-error.lox":                  "tree-walker specific error message",
+		"break-continue-in-function.lox":                  "functions not implemented",
+		"compound-assignment-properties.lox":              "classes not implemented",
+		"exceptions-in-functions.lox":                     "functions not implemented",
+		"fib-iterative.lox":                               "functions not implemented",
+		"fib-tail-recursion.lox":                          "functions not implemented",
+		"lambda-errors.lox":                               "functions not implemented",
+		"lambdas.lox":                                     "functions not implemented",
+		"list-methods.lox":                                "functions not implemented",
+		"local-slots.lox":                                 "functions not implemented",
+		"map-methods.lox":                                 "functions not implemented",
+		"modules-assignment.lox":                          "property assignment not implemented",
+		"modules-functions.lox":                           "functions not implemented",
+		"property-caching.lox":                            "classes not implemented",
+		"regression-394.lox":                              "classes not implemented",
+		"stack-overflow.lox":                              "functions not implemented",
+		"stack-trace.lox":                                 "functions not implemented",
+	},
+}
@@ -1,6 +1,9 @@
 package vm
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+)
 
 const (
 	OP_CONSTANT uint8 = iota
@@ -8,6 +11,8 @@ const (
 	OP_TRUE
 	OP_FALSE
 	OP_POP
+	OP_PICK
+	OP_TUCK
 	OP_GET_LOCAL
 	OP_SET_LOCAL
 	OP_GET_GLOBAL
@@ -15,17 +20,39 @@ const (
 	OP_SET_GLOBAL
 	OP_EQUAL
 	OP_GREATER
+	OP_GREATER_EQUAL
 	OP_LESS
+	OP_LESS_EQUAL
 	OP_ADD
 	OP_SUBTRACT
 	OP_MULTIPLY
 	OP_DIVIDE
+	OP_MODULO
+	OP_POWER
+	OP_INT_DIVIDE
+	OP_BIT_AND
+	OP_BIT_OR
+	OP_BIT_XOR
+	OP_SHIFT_LEFT
+	OP_SHIFT_RIGHT
 	OP_NOT
 	OP_NEGATE
+	OP_BIT_NOT
 	OP_PRINT
 	OP_JUMP
 	OP_JUMP_IF_FALSE
 	OP_LOOP
+	OP_BUILD_LIST
+	OP_BUILD_MAP
+	OP_GET_INDEX
+	OP_SET_INDEX
+	OP_GET_PROPERTY
+	OP_THROW
+	OP_TRY
+	OP_END_TRY
+	OP_END_FINALLY
+	OP_IMPORT
+	OP_TO_STRING
 	OP_RETURN
 )
 
@@ -85,6 +112,10 @@ func (c *Chunk) DisassembleInstruction(offset int) int {
 		return simpleInstruction("OP_FALSE", offset)
 	case OP_POP:
 		return simpleInstruction("OP_POP", offset)
+	case OP_PICK:
+		return byteInstruction("OP_PICK", c, offset)
+	case OP_TUCK:
+		return byteInstruction("OP_TUCK", c, offset)
 	case OP_GET_LOCAL:
 		return byteInstruction("OP_GET_LOCAL", c, offset)
 	case OP_SET_LOCAL:
@@ -99,8 +130,12 @@ func (c *Chunk) DisassembleInstruction(offset int) int {
 		return simpleInstruction("OP_EQUAL", offset)
 	case OP_GREATER:
 		return simpleInstruction("OP_GREATER", offset)
+	case OP_GREATER_EQUAL:
+		return simpleInstruction("OP_GREATER_EQUAL", offset)
 	case OP_LESS:
 		return simpleInstruction("OP_LESS", offset)
+	case OP_LESS_EQUAL:
+		return simpleInstruction("OP_LESS_EQUAL", offset)
 	case OP_ADD:
 		return simpleInstruction("OP_ADD", offset)
 	case OP_SUBTRACT:
@@ -109,10 +144,28 @@ func (c *Chunk) DisassembleInstruction(offset int) int {
 		return simpleInstruction("OP_MULTIPLY", offset)
 	case OP_DIVIDE:
 		return simpleInstruction("OP_DIVIDE", offset)
+	case OP_MODULO:
+		return simpleInstruction("OP_MODULO", offset)
+	case OP_POWER:
+		return simpleInstruction("OP_POWER", offset)
+	case OP_INT_DIVIDE:
+		return simpleInstruction("OP_INT_DIVIDE", offset)
+	case OP_BIT_AND:
+		return simpleInstruction("OP_BIT_AND", offset)
+	case OP_BIT_OR:
+		return simpleInstruction("OP_BIT_OR", offset)
+	case OP_BIT_XOR:
+		return simpleInstruction("OP_BIT_XOR", offset)
+	case OP_SHIFT_LEFT:
+		return simpleInstruction("OP_SHIFT_LEFT", offset)
+	case OP_SHIFT_RIGHT:
+		return simpleInstruction("OP_SHIFT_RIGHT", offset)
 	case OP_NOT:
 		return simpleInstruction("OP_NOT", offset)
 	case OP_NEGATE:
 		return simpleInstruction("OP_NEGATE", offset)
+	case OP_BIT_NOT:
+		return simpleInstruction("OP_BIT_NOT", offset)
 	case OP_PRINT:
 		return simpleInstruction("OP_PRINT", offset)
 	case OP_JUMP:
@@ -121,6 +174,28 @@ func (c *Chunk) DisassembleInstruction(offset int) int {
 		return jumpInstruction("OP_JUMP_IF_FALSE", 1, c, offset)
 	case OP_LOOP:
 		return jumpInstruction("OP_LOOP", -1, c, offset)
+	case OP_BUILD_LIST:
+		return byteInstruction("OP_BUILD_LIST", c, offset)
+	case OP_BUILD_MAP:
+		return byteInstruction("OP_BUILD_MAP", c, offset)
+	case OP_GET_INDEX:
+		return simpleInstruction("OP_GET_INDEX", offset)
+	case OP_SET_INDEX:
+		return simpleInstruction("OP_SET_INDEX", offset)
+	case OP_GET_PROPERTY:
+		return constantInstruction("OP_GET_PROPERTY", c, offset)
+	case OP_THROW:
+		return simpleInstruction("OP_THROW", offset)
+	case OP_TRY:
+		return tryInstruction("OP_TRY", c, offset)
+	case OP_END_TRY:
+		return simpleInstruction("OP_END_TRY", offset)
+	case OP_END_FINALLY:
+		return simpleInstruction("OP_END_FINALLY", offset)
+	case OP_IMPORT:
+		return constantInstruction("OP_IMPORT", c, offset)
+	case OP_TO_STRING:
+		return simpleInstruction("OP_TO_STRING", offset)
 	case OP_RETURN:
 		return simpleInstruction("OP_RETURN", offset)
 	default:
@@ -156,14 +231,25 @@ func jumpInstruction(name string, sign int, chunk *Chunk, offset int) int {
 	return offset + 3
 }
 
+// tryInstruction prints the stack depth and the handler of a try instruction.
+func tryInstruction(name string, chunk *Chunk, offset int) int {
+	depth := chunk.Code[offset+1]
+	high := (uint16)(chunk.Code[offset+2]) << 8
+	low := uint16(chunk.Code[offset+3])
+	var jump uint16 = high | low
+	fmt.Printf("%-16s %4d %4d -> %d\n", name, depth, offset, offset+4+int(jump))
+	return offset + 4
+}
+
 func printValue(value Value) {
 	switch value.Type {
 	case ValBool:
-		fmt.Printf("%v", value.AsBool())
+		fmt.Fprintf(stdout, "%v", value.AsBool())
 	case ValNil:
-		fmt.Printf("nil")
+		fmt.Fprint(stdout, "nil")
 	case ValNumber:
-		fmt.Printf("%g", value.AsNumber())
+		// same formatting as the tree-walk interpreter's stringify
+		fmt.Fprint(stdout, strconv.FormatFloat(value.AsNumber(), 'f', -1, 64))
 	case ValObj:
 		printObject(value)
 	}
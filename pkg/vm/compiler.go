@@ -3,8 +3,10 @@ package vm
 import (
 	"fmt"
 	"math"
-	"os"
 	"strconv"
+	"strings"
+
+	"github.com/rhomel/golox/pkg/number"
 )
 
 var debugPrintCode = false
@@ -16,14 +18,7 @@ func currentChunk() *Chunk {
 }
 
 func compile(source string) *ObjectFunction {
-	scanner := InitScanner(source)
-	parser.scanner = scanner
-	parser.hadError = false
-	parser.panicMode = false
-	compiler := Compiler{}
-	parser.InitCompiler(&compiler, TypeScript)
-
-	parser.advance()
+	beginCompile(source)
 
 	for !parser.match(TOKEN_EOF) {
 		parser.declaration()
@@ -36,6 +31,32 @@ func compile(source string) *ObjectFunction {
 	return function
 }
 
+// compileExpression compiles source that must contain exactly one expression.
+// Running the function leaves the expression's value on top of the stack.
+func compileExpression(source string) *ObjectFunction {
+	beginCompile(source)
+
+	parser.expression()
+	parser.consume(TOKEN_EOF, "Expect end of expression.")
+
+	function := endCompiler()
+	if parser.hadError {
+		return nil
+	}
+	return function
+}
+
+func beginCompile(source string) {
+	scanner := InitScanner(source)
+	parser.scanner = scanner
+	parser.hadError = false
+	parser.panicMode = false
+	compiler := Compiler{}
+	parser.InitCompiler(&compiler, TypeScript)
+
+	parser.advance()
+}
+
 func endCompiler() *ObjectFunction {
 	parser.emitReturn()
 	function := current.function
@@ -55,6 +76,54 @@ func (p *Parser) beginScope() {
 	current.scopeDepth++
 }
 
+// popLocals emits pops for the locals deeper than depth without ending their
+// scopes, for jumping out of them.
+func (p *Parser) popLocals(depth int) {
+	for i := current.localCount - 1; i >= 0 && current.locals[i].depth > depth; i-- {
+		p.emitByte(OP_POP)
+	}
+}
+
+func (p *Parser) beginLoop(start int) {
+	current.loop = &Loop{
+		enclosing:  current.loop,
+		start:      start,
+		scopeDepth: current.scopeDepth,
+	}
+}
+
+// endLoop patches the loop's break jumps to jump to the next instruction.
+func (p *Parser) endLoop() {
+	for _, jump := range current.loop.breakJumps {
+		p.patchJump(jump)
+	}
+	current.loop = current.loop.enclosing
+}
+
+// exitLoop emits a break or continue of the innermost loop. If the loop has
+// a try statement in it, the exit goes through its finally clause.
+func (p *Parser) exitLoop(exit TokenType) {
+	for try := current.try; try != nil && try.loop == current.loop; try = try.enclosing {
+		if try.inFinally {
+			// the finally clause has no handler and has already started
+			continue
+		}
+		p.popLocals(try.scopeDepth)
+		p.emitByte(OP_END_TRY)
+		p.emitByte(OP_NIL)
+		p.emitConstant(NumberValue(-float64(len(try.exits) + 1)))
+		try.exits = append(try.exits, exit)
+		try.finallyJumps = append(try.finallyJumps, p.emitJump(OP_JUMP))
+		return
+	}
+	p.popLocals(current.loop.scopeDepth)
+	if exit == TOKEN_BREAK {
+		current.loop.breakJumps = append(current.loop.breakJumps, p.emitJump(OP_JUMP))
+	} else {
+		p.emitLoop(current.loop.start)
+	}
+}
+
 func (p *Parser) endScope() {
 	current.scopeDepth--
 
@@ -98,6 +167,20 @@ type Parser struct {
 	current   Token
 	previous  Token
 	scanner   *Scanner
+
+	// access is the variable or index read compiled last, which a "++" or
+	// "--" turns into an update. It is not part of the book.
+	access access
+}
+
+// access is where a variable or index read was compiled. An index read has
+// no get and set instructions of its own: it is always OP_GET_INDEX.
+type access struct {
+	chunk        *Chunk
+	start, end   int
+	getOp, setOp uint8
+	arg          uint8
+	index        bool
 }
 
 func InitParser() *Parser {
@@ -109,16 +192,23 @@ var parser = InitParser()
 type Precedence int
 
 const (
-	PREC_NONE       Precedence = iota
-	PREC_ASSIGNMENT            // =
-	PREC_OR                    // or
-	PREC_AND                   // and
-	PREC_EQUALITY              // == !=
-	PREC_COMPARISON            // < > <= >=
-	PREC_TERM                  // + -
-	PREC_FACTOR                // * /
-	PREC_UNARY                 // ! -
-	PREC_CALL                  // . ()
+	PREC_NONE        Precedence = iota
+	PREC_COMMA                  // ,
+	PREC_ASSIGNMENT             // =
+	PREC_CONDITIONAL            // ?:
+	PREC_OR                     // or
+	PREC_AND                    // and
+	PREC_EQUALITY               // == !=
+	PREC_COMPARISON             // < > <= >=
+	PREC_BIT_OR                 // |
+	PREC_BIT_XOR                // ^
+	PREC_BIT_AND                // &
+	PREC_SHIFT                  // << >>
+	PREC_TERM                   // + -
+	PREC_FACTOR                 // * / % ~/
+	PREC_UNARY                  // ! - ~
+	PREC_POWER                  // **
+	PREC_CALL                   // . ()
 	PREC_PRIMARY
 )
 
@@ -152,6 +242,32 @@ type Compiler struct {
 	locals     [UINT8_COUNT]Local
 	localCount int
 	scopeDepth int
+
+	loop *Loop // the innermost loop being compiled or nil
+	try  *Try  // the innermost try statement being compiled or nil
+}
+
+// Loop is a loop being compiled. It is not part of the book; it is needed for
+// break and continue.
+type Loop struct {
+	enclosing  *Loop
+	start      int   // where continue jumps to
+	scopeDepth int   // the scope depth outside the loop body
+	breakJumps []int // the jumps to patch to the end of the loop
+}
+
+// Try is a try statement being compiled. It is not part of the book. A break
+// or continue can't jump straight out of a try statement because the handler
+// has to be removed and the finally clause run first. So it jumps to the
+// finally clause, which ends by jumping to an exit that does the break or
+// continue from outside the try statement.
+type Try struct {
+	enclosing    *Try
+	loop         *Loop       // the innermost loop around the try statement
+	scopeDepth   int         // the scope depth outside the try statement
+	inFinally    bool        // whether the finally clause is being compiled
+	exits        []TokenType // TOKEN_BREAK or TOKEN_CONTINUE for each exit
+	finallyJumps []int       // the jumps from the exits to the finally clause
 }
 
 // Return true if scopeDepth is greater than 0. This is not part of the book,
@@ -167,15 +283,23 @@ var rules = make([]ParseRule, TOKEN_EOF+1)
 func init() {
 	rules[TOKEN_LEFT_PAREN] = ParseRule{parser.grouping, nil, PREC_NONE}
 	rules[TOKEN_RIGHT_PAREN] = ParseRule{nil, nil, PREC_NONE}
-	rules[TOKEN_LEFT_BRACE] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_LEFT_BRACE] = ParseRule{parser.mapLiteral, nil, PREC_NONE}
 	rules[TOKEN_RIGHT_BRACE] = ParseRule{nil, nil, PREC_NONE}
-	rules[TOKEN_COMMA] = ParseRule{nil, nil, PREC_NONE}
-	rules[TOKEN_DOT] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_LEFT_BRACKET] = ParseRule{parser.list, parser.index, PREC_CALL}
+	rules[TOKEN_RIGHT_BRACKET] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_COLON] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_COMMA] = ParseRule{nil, parser.comma, PREC_COMMA}
+	rules[TOKEN_DOT] = ParseRule{nil, parser.dot, PREC_CALL}
 	rules[TOKEN_MINUS] = ParseRule{parser.unary, parser.binary, PREC_TERM}
 	rules[TOKEN_PLUS] = ParseRule{nil, parser.binary, PREC_TERM}
 	rules[TOKEN_SEMICOLON] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_SLASH] = ParseRule{nil, parser.binary, PREC_FACTOR}
 	rules[TOKEN_STAR] = ParseRule{nil, parser.binary, PREC_FACTOR}
+	rules[TOKEN_PERCENT] = ParseRule{nil, parser.binary, PREC_FACTOR}
+	rules[TOKEN_AMPERSAND] = ParseRule{nil, parser.binary, PREC_BIT_AND}
+	rules[TOKEN_PIPE] = ParseRule{nil, parser.binary, PREC_BIT_OR}
+	rules[TOKEN_CARET] = ParseRule{nil, parser.binary, PREC_BIT_XOR}
+	rules[TOKEN_QUESTION] = ParseRule{nil, parser.conditional, PREC_CONDITIONAL}
 	rules[TOKEN_BANG] = ParseRule{parser.unary, nil, PREC_NONE}
 	rules[TOKEN_BANG_EQUAL] = ParseRule{nil, parser.binary, PREC_EQUALITY}
 	rules[TOKEN_EQUAL] = ParseRule{nil, nil, PREC_NONE}
@@ -184,23 +308,43 @@ func init() {
 	rules[TOKEN_GREATER_EQUAL] = ParseRule{nil, parser.binary, PREC_COMPARISON}
 	rules[TOKEN_LESS] = ParseRule{nil, parser.binary, PREC_COMPARISON}
 	rules[TOKEN_LESS_EQUAL] = ParseRule{nil, parser.binary, PREC_COMPARISON}
+	rules[TOKEN_STAR_STAR] = ParseRule{nil, parser.binary, PREC_POWER}
+	rules[TOKEN_TILDE] = ParseRule{parser.unary, nil, PREC_NONE}
+	rules[TOKEN_TILDE_SLASH] = ParseRule{nil, parser.binary, PREC_FACTOR}
+	rules[TOKEN_LESS_LESS] = ParseRule{nil, parser.binary, PREC_SHIFT}
+	rules[TOKEN_GREATER_GREATER] = ParseRule{nil, parser.binary, PREC_SHIFT}
+	rules[TOKEN_PLUS_EQUAL] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_MINUS_EQUAL] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_STAR_EQUAL] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_SLASH_EQUAL] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_PERCENT_EQUAL] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_PLUS_PLUS] = ParseRule{parser.prefixUpdate, parser.postfixUpdate, PREC_CALL}
+	rules[TOKEN_MINUS_MINUS] = ParseRule{parser.prefixUpdate, parser.postfixUpdate, PREC_CALL}
 	rules[TOKEN_IDENTIFIER] = ParseRule{parser.variable, nil, PREC_NONE}
 	rules[TOKEN_STRING] = ParseRule{parser.string, nil, PREC_NONE}
+	rules[TOKEN_INTERPOLATION] = ParseRule{parser.interpolation, nil, PREC_NONE}
 	rules[TOKEN_NUMBER] = ParseRule{parser.number, nil, PREC_NONE}
 	rules[TOKEN_AND] = ParseRule{nil, parser.and_, PREC_AND}
+	rules[TOKEN_BREAK] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_CATCH] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_CLASS] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_CONTINUE] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_ELSE] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_FALSE] = ParseRule{parser.literal, nil, PREC_NONE}
+	rules[TOKEN_FINALLY] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_FOR] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_FUN] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_IF] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_IMPORT] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_NIL] = ParseRule{parser.literal, nil, PREC_NONE}
 	rules[TOKEN_OR] = ParseRule{nil, parser.or_, PREC_OR}
 	rules[TOKEN_PRINT] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_RETURN] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_SUPER] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_THIS] = ParseRule{nil, nil, PREC_NONE}
+	rules[TOKEN_THROW] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_TRUE] = ParseRule{parser.literal, nil, PREC_NONE}
+	rules[TOKEN_TRY] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_VAR] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_WHILE] = ParseRule{nil, nil, PREC_NONE}
 	rules[TOKEN_ERROR] = ParseRule{nil, nil, PREC_NONE}
@@ -215,11 +359,17 @@ func (p *Parser) advance() {
 		if p.current.Type != TOKEN_ERROR {
 			break
 		}
-		p.errorAtCurrent(p.current.StartAsString(p.scanner.source)) // TODO: verify the purpose of using the token as the message
+		p.errorAtCurrent(p.current.Error)
 	}
 }
 
 func (p *Parser) expression() {
+	p.parsePrecedence(PREC_COMMA)
+}
+
+// assignment compiles an expression that can't contain the comma operator:
+// the value of an assignment and the elements of list and map literals.
+func (p *Parser) assignment() {
 	p.parsePrecedence(PREC_ASSIGNMENT)
 }
 
@@ -266,18 +416,20 @@ func (p *Parser) forStatement() {
 		p.emitByte(OP_POP)
 		p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after for clauses.")
 
-		p.emitLoop(uint8(loopStart))
+		p.emitLoop(loopStart)
 		loopStart = incrementStart
 		p.patchJump(bodyJump)
 	}
 
+	p.beginLoop(loopStart)
 	p.statement()
-	p.emitLoop(uint8(loopStart))
+	p.emitLoop(loopStart)
 
 	if exitJump != -1 {
 		p.patchJump(exitJump)
 		p.emitByte(OP_POP)
 	}
+	p.endLoop()
 
 	p.endScope()
 }
@@ -285,7 +437,7 @@ func (p *Parser) forStatement() {
 func (p *Parser) ifStatement() {
 	p.consume(TOKEN_LEFT_PAREN, "Expect '(' after 'if'.")
 	p.expression()
-	p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after condition.")
+	p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after if condition.")
 
 	var thenJump int = p.emitJump(OP_JUMP_IF_FALSE)
 	p.emitByte(OP_POP)
@@ -312,15 +464,142 @@ func (p *Parser) whileStatement() {
 	loopStart := currentChunk().Count()
 	p.consume(TOKEN_LEFT_PAREN, "Expect '(' after 'while'.")
 	p.expression()
-	p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after 'while'.")
+	p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after condition.")
 
 	exitJump := p.emitJump(OP_JUMP_IF_FALSE)
 	p.emitByte(OP_POP)
+	p.beginLoop(loopStart)
 	p.statement()
-	p.emitLoop(uint8(loopStart))
+	p.emitLoop(loopStart)
 
 	p.patchJump(exitJump)
 	p.emitByte(OP_POP)
+	p.endLoop()
+}
+
+func (p *Parser) breakStatement() {
+	if current.loop == nil {
+		p.error("Can't use 'break' outside of a loop.")
+	}
+	p.consume(TOKEN_SEMICOLON, "Expect ';' after 'break'.")
+	if current.loop == nil {
+		return
+	}
+	p.exitLoop(TOKEN_BREAK)
+}
+
+func (p *Parser) continueStatement() {
+	if current.loop == nil {
+		p.error("Can't use 'continue' outside of a loop.")
+	}
+	p.consume(TOKEN_SEMICOLON, "Expect ';' after 'continue'.")
+	if current.loop == nil {
+		return
+	}
+	p.exitLoop(TOKEN_CONTINUE)
+}
+
+func (p *Parser) throwStatement() {
+	p.expression()
+	p.consume(TOKEN_SEMICOLON, "Expect ';' after thrown value.")
+	p.emitByte(OP_THROW)
+}
+
+// tryStatement compiles a try statement. Every way out of the try block and
+// the catch clause goes through the finally clause, which is entered with two
+// values on the stack:
+//
+//   - nil and nil when the try statement completed normally
+//   - the thrown value and the line it was thrown on, to rethrow it
+//   - nil and -(i+1) for exit i, a break or continue to do afterwards
+//
+// A try statement without a finally clause has an empty one.
+func (p *Parser) tryStatement() {
+	try := &Try{
+		enclosing:  current.try,
+		loop:       current.loop,
+		scopeDepth: current.scopeDepth,
+	}
+	current.try = try
+	depth := current.localCount
+
+	p.consume(TOKEN_LEFT_BRACE, "Expect '{' after 'try'.")
+	handler := p.emitTry(depth)
+	p.beginScope()
+	p.block()
+	p.endScope()
+	p.emitByte(OP_END_TRY)
+	normalJumps := []int{p.emitJump(OP_JUMP)}
+
+	// the handler has the thrown value and its line on the stack
+	p.patchJump(handler)
+	hasCatch := p.match(TOKEN_CATCH)
+	if hasCatch {
+		p.emitByte(OP_POP) // the line
+		p.beginScope()
+		p.consume(TOKEN_LEFT_PAREN, "Expect '(' after 'catch'.")
+		p.consume(TOKEN_IDENTIFIER, "Expect catch variable name.")
+		p.declareVariable()
+		p.markInitialized()
+		p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after catch variable.")
+		p.consume(TOKEN_LEFT_BRACE, "Expect '{' before catch body.")
+		// a value thrown by the catch clause still runs the finally clause
+		handler = p.emitTry(depth)
+		p.block()
+		p.emitByte(OP_END_TRY)
+		p.endScope()
+		normalJumps = append(normalJumps, p.emitJump(OP_JUMP))
+		p.patchJump(handler)
+	}
+	throwJump := p.emitJump(OP_JUMP)
+	for _, jump := range normalJumps {
+		p.patchJump(jump)
+	}
+	p.emitBytes(OP_NIL, OP_NIL)
+
+	p.patchJump(throwJump)
+	for _, jump := range try.finallyJumps {
+		p.patchJump(jump)
+	}
+	try.inFinally = true
+	// the two values the finally clause is entered with are hidden locals
+	p.beginScope()
+	p.addLocal(Token{})
+	p.markInitialized()
+	p.addLocal(Token{})
+	p.markInitialized()
+	if p.match(TOKEN_FINALLY) {
+		p.consume(TOKEN_LEFT_BRACE, "Expect '{' after 'finally'.")
+		p.beginScope()
+		p.block()
+		p.endScope()
+	} else if !hasCatch {
+		p.errorAtCurrent("Expect 'catch' or 'finally' after try block.")
+	}
+	// OP_END_FINALLY pops the hidden locals
+	p.emitByte(OP_END_FINALLY)
+	current.scopeDepth--
+	current.localCount -= 2
+	current.try = try.enclosing
+
+	endJump := p.emitJump(OP_JUMP)
+	exitJumps := make([]int, len(try.exits))
+	for i := range try.exits {
+		exitJumps[i] = p.emitJump(OP_JUMP)
+	}
+	for i, exit := range try.exits {
+		p.patchJump(exitJumps[i])
+		p.exitLoop(exit)
+	}
+	p.patchJump(endJump)
+}
+
+// emitTry emits a try instruction that unwinds the stack to depth values and
+// returns the offset of its handler jump to patch.
+func (p *Parser) emitTry(depth int) int {
+	p.emitBytes(OP_TRY, uint8(depth))
+	p.emitBytes(0xff, 0xff)
+	return currentChunk().Count() - 2
 }
 
 func (p *Parser) synchronize() {
@@ -341,6 +620,8 @@ func (p *Parser) synchronize() {
 			return
 		case TOKEN_IF:
 			return
+		case TOKEN_IMPORT:
+			return
 		case TOKEN_WHILE:
 			return
 		case TOKEN_PRINT:
@@ -353,7 +634,9 @@ func (p *Parser) synchronize() {
 }
 
 func (p *Parser) declaration() {
-	if p.match(TOKEN_VAR) {
+	if p.match(TOKEN_IMPORT) {
+		p.importDeclaration()
+	} else if p.match(TOKEN_VAR) {
 		p.varDeclaration()
 	} else {
 		p.statement()
@@ -377,6 +660,24 @@ func (p *Parser) varDeclaration() {
 	p.defineVariable(global)
 }
 
+// importDeclaration compiles an import, which defines a variable holding the
+// module. It is not part of the book.
+func (p *Parser) importDeclaration() {
+	p.consume(TOKEN_STRING, "Expect module path after 'import'.")
+	token := p.previous
+	path := p.makeConstant(ObjVal(copyString(p.stringValue(token))))
+	as := p.current
+	if as.Type != TOKEN_IDENTIFIER || string(p.scanner.source[as.Start:as.Start+as.Length]) != "as" {
+		p.errorAtCurrent("Expect 'as' after module path.")
+		return
+	}
+	p.advance()
+	var global uint8 = p.parseVariable("Expect module name after 'as'.")
+	p.emitBytes(OP_IMPORT, path)
+	p.consume(TOKEN_SEMICOLON, "Expect ';' after import.")
+	p.defineVariable(global)
+}
+
 func (p *Parser) statement() {
 	if p.match(TOKEN_PRINT) {
 		p.printStatement()
@@ -386,6 +687,14 @@ func (p *Parser) statement() {
 		p.ifStatement()
 	} else if p.match(TOKEN_WHILE) {
 		p.whileStatement()
+	} else if p.match(TOKEN_BREAK) {
+		p.breakStatement()
+	} else if p.match(TOKEN_CONTINUE) {
+		p.continueStatement()
+	} else if p.match(TOKEN_THROW) {
+		p.throwStatement()
+	} else if p.match(TOKEN_TRY) {
+		p.tryStatement()
 	} else if p.match(TOKEN_LEFT_BRACE) {
 		p.beginScope()
 		p.block()
@@ -398,7 +707,13 @@ func (p *Parser) statement() {
 func (p *Parser) binary(canAssign bool) {
 	operatorType := p.previous.Type
 	rule := getRule(operatorType)
-	p.parsePrecedence(Precedence(rule.precedence + 1))
+	if operatorType == TOKEN_STAR_STAR {
+		// right associative and the right operand can be a unary expression
+		// like "2 ** -1"
+		p.parsePrecedence(PREC_UNARY)
+	} else {
+		p.parsePrecedence(Precedence(rule.precedence + 1))
+	}
 	switch operatorType {
 	case TOKEN_BANG_EQUAL:
 		p.emitBytes(OP_EQUAL, OP_NOT)
@@ -407,11 +722,13 @@ func (p *Parser) binary(canAssign bool) {
 	case TOKEN_GREATER:
 		p.emitByte(OP_GREATER)
 	case TOKEN_GREATER_EQUAL:
-		p.emitBytes(OP_LESS, OP_NOT)
+		// the book emits OP_LESS, OP_NOT here but that is not equivalent when
+		// an operand is NaN
+		p.emitByte(OP_GREATER_EQUAL)
 	case TOKEN_LESS:
 		p.emitByte(OP_LESS)
 	case TOKEN_LESS_EQUAL:
-		p.emitBytes(OP_GREATER, OP_NOT)
+		p.emitByte(OP_LESS_EQUAL)
 	case TOKEN_PLUS:
 		p.emitByte(OP_ADD)
 	case TOKEN_MINUS:
@@ -420,6 +737,22 @@ func (p *Parser) binary(canAssign bool) {
 		p.emitByte(OP_MULTIPLY)
 	case TOKEN_SLASH:
 		p.emitByte(OP_DIVIDE)
+	case TOKEN_PERCENT:
+		p.emitByte(OP_MODULO)
+	case TOKEN_STAR_STAR:
+		p.emitByte(OP_POWER)
+	case TOKEN_TILDE_SLASH:
+		p.emitByte(OP_INT_DIVIDE)
+	case TOKEN_AMPERSAND:
+		p.emitByte(OP_BIT_AND)
+	case TOKEN_PIPE:
+		p.emitByte(OP_BIT_OR)
+	case TOKEN_CARET:
+		p.emitByte(OP_BIT_XOR)
+	case TOKEN_LESS_LESS:
+		p.emitByte(OP_SHIFT_LEFT)
+	case TOKEN_GREATER_GREATER:
+		p.emitByte(OP_SHIFT_RIGHT)
 	default:
 		return // unreachable
 	}
@@ -441,13 +774,83 @@ func (p *Parser) literal(canAssign bool) {
 func (p *Parser) grouping(canAssign bool) {
 	p.expression()
 	p.consume(TOKEN_RIGHT_PAREN, "Expect ')' after expression.")
+	// "(x)" can't be incremented
+	p.access = access{}
+}
+
+func (p *Parser) list(canAssign bool) {
+	count := 0
+	if !p.check(TOKEN_RIGHT_BRACKET) {
+		for {
+			p.assignment()
+			if count == 255 {
+				p.error("Can't have more than 255 elements in a list literal.")
+			}
+			count++
+			if !p.match(TOKEN_COMMA) {
+				break
+			}
+		}
+	}
+	p.consume(TOKEN_RIGHT_BRACKET, "Expect ']' after list elements.")
+	p.emitBytes(OP_BUILD_LIST, uint8(count))
+}
+
+func (p *Parser) mapLiteral(canAssign bool) {
+	count := 0
+	if !p.check(TOKEN_RIGHT_BRACE) {
+		for {
+			p.assignment()
+			p.consume(TOKEN_COLON, "Expect ':' after map key.")
+			p.assignment()
+			if count == 255 {
+				p.error("Can't have more than 255 entries in a map literal.")
+			}
+			count++
+			if !p.match(TOKEN_COMMA) {
+				break
+			}
+		}
+	}
+	p.consume(TOKEN_RIGHT_BRACE, "Expect '}' after map entries.")
+	p.emitBytes(OP_BUILD_MAP, uint8(count))
+}
+
+func (p *Parser) index(canAssign bool) {
+	p.expression()
+	p.consume(TOKEN_RIGHT_BRACKET, "Expect ']' after index.")
+	if canAssign && p.match(TOKEN_EQUAL) {
+		p.assignment()
+		p.emitByte(OP_SET_INDEX)
+	} else if canAssign && p.matchCompound() {
+		op := compoundOp(p.previous.Type)
+		// copy the list and index for the read
+		p.emitBytes(OP_PICK, 1)
+		p.emitBytes(OP_PICK, 1)
+		p.emitByte(OP_GET_INDEX)
+		p.assignment()
+		p.emitByte(op)
+		p.emitByte(OP_SET_INDEX)
+	} else {
+		start := currentChunk().Count()
+		p.emitByte(OP_GET_INDEX)
+		p.access = access{chunk: currentChunk(), start: start, end: currentChunk().Count(), index: true}
+	}
+}
+
+// dot compiles getting a property. Only error objects have properties in the
+// vm so far.
+func (p *Parser) dot(canAssign bool) {
+	p.consume(TOKEN_IDENTIFIER, "Expect property name after '.'.")
+	name := p.identifierConstant(&p.previous)
+	p.emitBytes(OP_GET_PROPERTY, name)
 }
 
 func (p *Parser) number(canAssign bool) {
-	value, err := strconv.ParseFloat(p.previous.StartAsString(p.scanner.source), 64)
-	if err != nil {
-		// should not happen
-		panic("unable to parse float")
+	value, _, ok := number.Parse(p.previous.StartAsString(p.scanner.source))
+	if !ok {
+		// should not happen: the scanner checks the literal
+		panic("unable to parse number")
 	}
 	p.emitConstant(NumberValue(value))
 }
@@ -463,10 +866,133 @@ func (p *Parser) or_(canAssign bool) {
 	p.patchJump(endJump)
 }
 
+// comma compiles the comma operator, which discards the value of its left
+// operand. It is not part of the book.
+func (p *Parser) comma(canAssign bool) {
+	p.emitByte(OP_POP)
+	p.parsePrecedence(PREC_ASSIGNMENT)
+}
+
+// conditional compiles "condition ? then : else" with jumps like an if
+// statement so only one branch is evaluated. The else branch is parsed at the
+// same precedence so the operator is right associative. It is not part of the
+// book.
+func (p *Parser) conditional(canAssign bool) {
+	thenJump := p.emitJump(OP_JUMP_IF_FALSE)
+	p.emitByte(OP_POP)
+	p.expression()
+	p.consume(TOKEN_COLON, "Expect ':' after then branch of conditional expression.")
+
+	elseJump := p.emitJump(OP_JUMP)
+	p.patchJump(thenJump)
+	p.emitByte(OP_POP)
+	p.parsePrecedence(PREC_CONDITIONAL)
+	p.patchJump(elseJump)
+}
+
 func (p *Parser) string(canAssign bool) {
-	prev := p.previous
-	// copy only the string (avoid copying the quote characters)
-	p.emitConstant(ObjVal(copyString(string(p.scanner.source[prev.Start+1 : prev.Start+prev.Length-1]))))
+	if p.isContinuation(p.previous) {
+		p.error("Expect expression.")
+		return
+	}
+	p.emitConstant(ObjVal(copyString(p.stringValue(p.previous))))
+}
+
+// stringValue returns the value of a string token: its characters without the
+// quotes, or the "}" and "${" around a part of a string with embedded
+// expressions, with the escape sequences decoded. A raw string between
+// backticks is returned as it is.
+func (p *Parser) stringValue(token Token) string {
+	end := token.Start + token.Length - 1
+	if token.Type == TOKEN_INTERPOLATION {
+		end-- // "${" is two characters
+	}
+	chars := p.scanner.source[token.Start+1 : end]
+	if p.scanner.source[token.Start] == '`' {
+		return string(chars)
+	}
+	return unescape(chars)
+}
+
+// unescape decodes the escape sequences in chars, which the scanner has
+// already checked.
+func unescape(chars []rune) string {
+	var value strings.Builder
+	for i := 0; i < len(chars); i++ {
+		if chars[i] != '\\' {
+			value.WriteRune(chars[i])
+			continue
+		}
+		i++
+		switch chars[i] {
+		case 'n':
+			value.WriteRune('\n')
+		case 'r':
+			value.WriteRune('\r')
+		case 't':
+			value.WriteRune('\t')
+		case '0':
+			value.WriteRune(0)
+		case 'u':
+			// "\u{" then the hexadecimal digits up to "}"
+			end := i + 2
+			for chars[end] != '}' {
+				end++
+			}
+			code, _ := strconv.ParseInt(string(chars[i+2:end]), 16, 32)
+			value.WriteRune(rune(code))
+			i = end
+		default:
+			value.WriteRune(chars[i])
+		}
+	}
+	return value.String()
+}
+
+// interpolation compiles a string with embedded expressions the way the
+// tree-walk parser desugars it: the parts are concatenated with each
+// expression converted to a string the way print formats it. It is not part
+// of the book.
+func (p *Parser) interpolation(canAssign bool) {
+	if p.isContinuation(p.previous) {
+		p.error("Expect expression.")
+		return
+	}
+	p.emitConstant(ObjVal(copyString(p.stringValue(p.previous))))
+	for {
+		if p.continuesString() {
+			// "${}" has no expression
+			p.errorAtCurrent("Expect expression.")
+			return
+		}
+		p.expression()
+		p.emitBytes(OP_TO_STRING, OP_ADD)
+		if !p.continuesString() {
+			p.errorAtCurrent("Expect '}' after interpolated expression.")
+			return
+		}
+		p.advance()
+		if part := p.stringValue(p.previous); part != "" {
+			p.emitConstant(ObjVal(copyString(part)))
+			p.emitByte(OP_ADD)
+		}
+		if p.previous.Type == TOKEN_STRING {
+			return
+		}
+	}
+}
+
+// continuesString reports whether the current token is the rest of a string
+// after an embedded expression.
+func (p *Parser) continuesString() bool {
+	return p.isContinuation(p.current)
+}
+
+// isContinuation reports whether token is the rest of a string after an
+// embedded expression, which starts with the "}" closing it. It can't be an
+// operand, like the `}"` in "${1 +}".
+func (p *Parser) isContinuation(token Token) bool {
+	return (token.Type == TOKEN_INTERPOLATION || token.Type == TOKEN_STRING) && p.scanner.source[token.Start] == '}'
 }
 
 func (p *Parser) namedVariable(name Token, canAssign bool) {
@@ -482,10 +1008,94 @@ func (p *Parser) namedVariable(name Token, canAssign bool) {
 	}
 
 	if canAssign && p.match(TOKEN_EQUAL) {
-		p.expression()
+		p.assignment()
+		p.emitBytes(setOp, uint8(arg))
+	} else if canAssign && p.matchCompound() {
+		op := compoundOp(p.previous.Type)
+		p.emitBytes(getOp, uint8(arg))
+		p.assignment()
+		p.emitByte(op)
 		p.emitBytes(setOp, uint8(arg))
 	} else {
+		start := currentChunk().Count()
 		p.emitBytes(getOp, uint8(arg))
+		p.access = access{currentChunk(), start, currentChunk().Count(), getOp, setOp, uint8(arg), false}
+	}
+}
+
+// Compound assignments and increments are not part of the book. A compound
+// assignment is compiled where the "=" of an assignment would be. An
+// increment or decrement compiles its target as a read and then replaces the
+// read with an update.
+
+// matchCompound matches the operator of a compound assignment like "+=".
+func (p *Parser) matchCompound() bool {
+	return p.match(TOKEN_PLUS_EQUAL) || p.match(TOKEN_MINUS_EQUAL) || p.match(TOKEN_STAR_EQUAL) ||
+		p.match(TOKEN_SLASH_EQUAL) || p.match(TOKEN_PERCENT_EQUAL)
+}
+
+// compoundOp returns the instruction of the binary operator that a compound
+// assignment, increment or decrement applies.
+func compoundOp(operator TokenType) uint8 {
+	switch operator {
+	case TOKEN_PLUS_EQUAL, TOKEN_PLUS_PLUS:
+		return OP_ADD
+	case TOKEN_MINUS_EQUAL, TOKEN_MINUS_MINUS:
+		return OP_SUBTRACT
+	case TOKEN_STAR_EQUAL:
+		return OP_MULTIPLY
+	case TOKEN_SLASH_EQUAL:
+		return OP_DIVIDE
+	default:
+		return OP_MODULO
+	}
+}
+
+func (p *Parser) prefixUpdate(canAssign bool) {
+	operator := p.previous
+	p.parsePrecedence(PREC_UNARY)
+	p.update(operator, false)
+}
+
+func (p *Parser) postfixUpdate(canAssign bool) {
+	p.update(p.previous, true)
+}
+
+// update replaces the read of a variable or list element that was just
+// compiled with adding or subtracting one from it. A postfix update leaves the
+// old value on the stack.
+func (p *Parser) update(operator Token, postfix bool) {
+	read := p.access
+	p.access = access{}
+	chunk := currentChunk()
+	if read.chunk != chunk || read.end != chunk.Count() {
+		p.errorAt(operator, "Invalid assignment target.")
+		return
+	}
+	chunk.Code = chunk.Code[:read.start]
+	chunk.Lines = chunk.Lines[:read.start]
+	// the number of values the target's set instruction takes besides the value
+	var operands uint8
+	if read.index {
+		operands = 2
+		p.emitBytes(OP_PICK, 1)
+		p.emitBytes(OP_PICK, 1)
+		p.emitByte(OP_GET_INDEX)
+	} else {
+		p.emitBytes(read.getOp, read.arg)
+	}
+	if postfix {
+		p.emitBytes(OP_TUCK, operands)
+	}
+	p.emitConstant(NumberValue(1))
+	p.emitByte(compoundOp(operator.Type))
+	if read.index {
+		p.emitByte(OP_SET_INDEX)
+	} else {
+		p.emitBytes(read.setOp, read.arg)
+	}
+	if postfix {
+		p.emitByte(OP_POP)
 	}
 }
 
@@ -505,6 +1115,8 @@ func (p *Parser) unary(canAssign bool) {
 		p.emitByte(OP_NOT)
 	case TOKEN_MINUS:
 		p.emitByte(OP_NEGATE)
+	case TOKEN_TILDE:
+		p.emitByte(OP_BIT_NOT)
 	default:
 		return // unreachable
 	}
@@ -514,7 +1126,7 @@ func (p *Parser) parsePrecedence(precedence Precedence) {
 	p.advance()
 	prefixRule := getRule(p.previous.Type).prefix
 	if prefixRule == nil {
-		p.error("Expect expression")
+		p.error("Expect expression.")
 		return
 	}
 
@@ -527,7 +1139,7 @@ func (p *Parser) parsePrecedence(precedence Precedence) {
 		infixRule(canAssign)
 	}
 
-	if canAssign && p.match(TOKEN_EQUAL) {
+	if canAssign && (p.match(TOKEN_EQUAL) || p.matchCompound()) {
 		p.error("Invalid assignment target.")
 	}
 }
@@ -651,10 +1263,10 @@ func (p *Parser) emitBytes(byte1, byte2 uint8) {
 	p.emitByte(byte2)
 }
 
-func (p *Parser) emitLoop(loopStart uint8) {
+func (p *Parser) emitLoop(loopStart int) {
 	p.emitByte(OP_LOOP)
 
-	offset := currentChunk().Count() - int(loopStart) + 2
+	offset := currentChunk().Count() - loopStart + 2
 	if offset > math.MaxUint16 {
 		p.error("Loop body too large.")
 	}
@@ -730,16 +1342,16 @@ func (p *Parser) errorAt(token Token, message string) {
 		return
 	}
 	p.panicMode = true
-	fmt.Fprintf(os.Stderr, "[line %d] Error", token.Line)
 
+	where := ""
 	if token.Type == TOKEN_EOF {
-		fmt.Fprintf(os.Stderr, "at end")
+		where = " at end"
 	} else if token.Type == TOKEN_ERROR {
 		// nothing
 	} else {
 		str := token.StartAsString(p.scanner.source)
-		fmt.Fprintf(os.Stderr, " at '%s'", str)
+		where = fmt.Sprintf(" at '%s'", str)
 	}
-	fmt.Fprintf(os.Stderr, ": %s\n", message)
+	reporter.CompileError(token.Line, where, message)
 	p.hadError = true
 }
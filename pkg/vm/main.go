@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/rhomel/golox/pkg/args"
 	"github.com/rhomel/golox/pkg/util/exit"
@@ -37,10 +38,12 @@ func sampleChunk() {
 	//interpret(chunk)
 }
 
-// Main is the entry point for the VM implementation of golox.
-func Main(enableDisassembler bool, args *args.Args) {
+// Main is the entry point for the VM implementation of golox. modulePath lists
+// the directories imports look in after the importing file's directory.
+func Main(enableDisassembler bool, modulePath []string, args *args.Args) {
 	DebugTraceExecution = enableDisassembler
 	InitVM()
+	vm.ModulePath = modulePath
 
 	if args.Len() == 0 {
 		repl()
@@ -75,12 +78,14 @@ func runFile(file string) {
 	if err != nil {
 		exit.Exitf(74, "error reading file '%s': %v", file, err)
 	}
+	if vm.File, err = filepath.Abs(file); err != nil {
+		vm.File = file
+	}
 	result := interpret(string(b))
 	if result == INTERPRET_COMPILE_ERROR {
-		exit.Exitf(65, "compile error")
+		exit.Exitf(exit.ExitSyntaxError, "")
 	}
 	if result == INTERPRET_RUNTIME_ERROR {
-		exit.Exitf(70, "runtime error")
+		exit.Exitf(exit.ExitRuntimeError, "")
 	}
-
 }
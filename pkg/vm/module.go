@@ -0,0 +1,101 @@
+package vm
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rhomel/golox/pkg/module"
+)
+
+// Modules are not part of the book. An import compiles the module's file the
+// first time it is imported and runs it in a frame of its own with its own
+// globals, which become the properties of the module object.
+
+// importModule returns the module named by path, running it the first time it
+// is imported. It throws an error and returns false if the module can't be
+// imported or throws a value itself. A module that fails isn't cached so
+// importing it again runs it again.
+func importModule(path *ObjectString) (*ObjectModule, bool) {
+	file, ok := module.Find(vm.File, path.String, vm.ModulePath)
+	if !ok {
+		runtimeError("Module '%s' not found.", path.String)
+		return nil, false
+	}
+	if loaded, ok := vm.Modules[file]; ok {
+		return loaded, true
+	}
+	if message, ok := module.Cycle(append(vm.loading, vm.File), file); ok {
+		runtimeError("%s", message)
+		return nil, false
+	}
+	source, err := os.ReadFile(file)
+	if err != nil {
+		runtimeError("Can't read module '%s'.", path.String)
+		return nil, false
+	}
+	function, message := compileModule(string(source))
+	if function == nil {
+		runtimeError("Error in module '%s': %s", path.String, message)
+		return nil, false
+	}
+	globals := &Table{}
+	globals.initTable()
+	if !runModule(file, function, globals) {
+		return nil, false
+	}
+	loaded := newModule(module.Name(file), globals)
+	vm.Modules[file] = loaded
+	return loaded, true
+}
+
+// moduleErrors keeps the first compile error of a module, which the import
+// throws.
+type moduleErrors struct {
+	ErrorReporter
+	first string
+}
+
+func (m *moduleErrors) CompileError(line int, where, message string) {
+	if m.first == "" {
+		m.first = fmt.Sprintf("[line %d] Error%s: %s", line, where, message)
+	}
+}
+
+// compileModule compiles the source of a module. It returns nil and the first
+// error if the module has errors.
+func compileModule(source string) (*ObjectFunction, string) {
+	errors := &moduleErrors{ErrorReporter: reporter}
+	saved := reporter
+	reporter = errors
+	function := compile(source)
+	reporter = saved
+	return function, errors.first
+}
+
+// runModule runs the compiled module in file with globals. It returns false if
+// the module throws a value, which is left to the importer to catch.
+func runModule(file string, function *ObjectFunction, globals *Table) bool {
+	if vm.FrameCount == FRAMES_MAX {
+		runtimeError("Stack overflow.")
+		return false
+	}
+	savedGlobals, savedHandlers, savedFile := vm.Globals, vm.Handlers, vm.File
+	vm.loading = append(vm.loading, vm.File)
+	// the module's try statements are its own; the importer's can only catch
+	// what the import throws
+	vm.Globals, vm.Handlers, vm.File = globals, nil, file
+	base := vm.StackTop
+	push(ObjVal(function))
+	vm.Frames[vm.FrameCount] = CallFrame{
+		Function:   function,
+		Slots:      vm.Stack[base:],
+		SlotsStart: base,
+	}
+	vm.FrameCount++
+	result := runCatching()
+	vm.FrameCount--
+	vm.StackTop = base
+	vm.Globals, vm.Handlers, vm.File = savedGlobals, savedHandlers, savedFile
+	vm.loading = vm.loading[:len(vm.loading)-1]
+	return result == INTERPRET_OK
+}
@@ -33,6 +33,115 @@ func (of *ObjectFunction) GetNext() Obj {
 	return of.next
 }
 
+// ObjectList is a list. Lists are mutable and shared by reference.
+type ObjectList struct {
+	next Obj
+
+	elements []Value
+}
+
+func (ol *ObjectList) Type() ObjType {
+	return ObjList
+}
+func (ol *ObjectList) SetNext(next Obj) {
+	ol.next = next
+}
+func (ol *ObjectList) GetNext() Obj {
+	return ol.next
+}
+
+// ObjectMap is a map from nil, booleans, numbers and strings to values. Like
+// the tree-walk interpreter's maps it remembers the order keys were added in.
+// Values can be map keys because equal strings are the same object.
+type ObjectMap struct {
+	next Obj
+
+	positions map[Value]int // the position of each key in keys and values
+	keys      []Value
+	values    []Value
+}
+
+func (om *ObjectMap) Type() ObjType {
+	return ObjMap
+}
+func (om *ObjectMap) SetNext(next Obj) {
+	om.next = next
+}
+func (om *ObjectMap) GetNext() Obj {
+	return om.next
+}
+
+func (om *ObjectMap) get(key Value) (Value, bool) {
+	i, ok := om.positions[key]
+	if !ok {
+		return Value{}, false
+	}
+	return om.values[i], true
+}
+
+func (om *ObjectMap) set(key, value Value) {
+	if key.IsNumber() && key.Number == 0 {
+		// -0 is the same key as 0 so store it as 0
+		key.Number = 0
+	}
+	if i, ok := om.positions[key]; ok {
+		om.values[i] = value
+		return
+	}
+	om.positions[key] = len(om.keys)
+	om.keys = append(om.keys, key)
+	om.values = append(om.values, value)
+}
+
+// ObjectError is the error object a catch clause receives for a runtime
+// error. Like the tree-walk interpreter's error objects it has a message and
+// a line property.
+type ObjectError struct {
+	next Obj
+
+	message *ObjectString
+	line    int
+}
+
+func (oe *ObjectError) Type() ObjType {
+	return ObjError
+}
+func (oe *ObjectError) SetNext(next Obj) {
+	oe.next = next
+}
+func (oe *ObjectError) GetNext() Obj {
+	return oe.next
+}
+
+// property returns the value of the property called name.
+func (oe *ObjectError) property(name string) (Value, bool) {
+	switch name {
+	case "message":
+		return ObjVal(oe.message), true
+	case "line":
+		return NumberValue(float64(oe.line)), true
+	}
+	return Value{}, false
+}
+
+// ObjectModule is an imported module. Its globals are its properties.
+type ObjectModule struct {
+	next Obj
+
+	name    *ObjectString
+	globals *Table
+}
+
+func (om *ObjectModule) Type() ObjType {
+	return ObjModule
+}
+func (om *ObjectModule) SetNext(next Obj) {
+	om.next = next
+}
+func (om *ObjectModule) GetNext() Obj {
+	return om.next
+}
+
 type ObjectString struct {
 	String string
 	Hash   uint32
@@ -41,6 +150,10 @@ type ObjectString struct {
 
 var _ Obj = (*ObjectString)(nil)
 var _ Obj = (*ObjectFunction)(nil)
+var _ Obj = (*ObjectList)(nil)
+var _ Obj = (*ObjectMap)(nil)
+var _ Obj = (*ObjectError)(nil)
+var _ Obj = (*ObjectModule)(nil)
 
 func copyString(chars string) *ObjectString {
 	hash := hashString(chars)
@@ -55,7 +168,7 @@ func copyString(chars string) *ObjectString {
 }
 
 func printFunction(function *ObjectFunction) {
-	fmt.Printf("<fn %s>", function.name.String)
+	fmt.Fprintf(stdout, "<fn %s>", function.name.String)
 }
 
 func takeString(s string) *ObjectString {
@@ -81,6 +194,30 @@ func newFunction() *ObjectFunction {
 	return fn
 }
 
+func newList(elements []Value) *ObjectList {
+	list := &ObjectList{elements: elements}
+	allocateObject(list)
+	return list
+}
+
+func newError(message string, line int) *ObjectError {
+	err := &ObjectError{message: copyString(message), line: line}
+	allocateObject(err)
+	return err
+}
+
+func newModule(name string, globals *Table) *ObjectModule {
+	module := &ObjectModule{name: copyString(name), globals: globals}
+	allocateObject(module)
+	return module
+}
+
+func newMap() *ObjectMap {
+	m := &ObjectMap{positions: make(map[Value]int)}
+	allocateObject(m)
+	return m
+}
+
 func allocateString(s string, hash uint32) *ObjectString {
 	os := &ObjectString{
 		String: s,
@@ -104,9 +241,71 @@ func hashString(s string) uint32 {
 func printObject(value Value) {
 	switch value.Obj.Type() {
 	case ObjString:
-		fmt.Print(AsGoString(value))
+		fmt.Fprint(stdout, AsGoString(value))
 	case ObjFunction:
 		printFunction(AsFunction(value))
+	case ObjList:
+		printList(AsList(value), make(map[Obj]bool))
+	case ObjMap:
+		printMap(AsMap(value), make(map[Obj]bool))
+	case ObjError:
+		fmt.Fprint(stdout, "Error instance")
+	case ObjModule:
+		fmt.Fprintf(stdout, "<module %s>", AsModule(value).name.String)
+	}
+}
+
+// printList prints the list like the tree-walk interpreter: strings in the
+// list are quoted and a list that contains itself is printed as [...] the
+// second time. seen holds the lists and maps being printed.
+func printList(list *ObjectList, seen map[Obj]bool) {
+	if seen[list] {
+		fmt.Fprint(stdout, "[...]")
+		return
+	}
+	seen[list] = true
+	defer delete(seen, list)
+	fmt.Fprint(stdout, "[")
+	for i, element := range list.elements {
+		if i > 0 {
+			fmt.Fprint(stdout, ", ")
+		}
+		printElement(element, seen)
+	}
+	fmt.Fprint(stdout, "]")
+}
+
+// printMap prints the map like printList.
+func printMap(m *ObjectMap, seen map[Obj]bool) {
+	if seen[m] {
+		fmt.Fprint(stdout, "{...}")
+		return
+	}
+	seen[m] = true
+	defer delete(seen, m)
+	fmt.Fprint(stdout, "{")
+	for i, key := range m.keys {
+		if i > 0 {
+			fmt.Fprint(stdout, ", ")
+		}
+		printElement(key, seen)
+		fmt.Fprint(stdout, ": ")
+		printElement(m.values[i], seen)
+	}
+	fmt.Fprint(stdout, "}")
+}
+
+// printElement prints a value in a list or map.
+func printElement(value Value, seen map[Obj]bool) {
+	switch {
+	case isObjType(value, ObjList):
+		printList(AsList(value), seen)
+	case isObjType(value, ObjMap):
+		printMap(AsMap(value), seen)
+	case IsString(value):
+		fmt.Fprintf(stdout, "\"%s\"", AsGoString(value))
+	default:
+		printValue(value)
 	}
 }
 
@@ -141,6 +340,34 @@ func AsFunction(value Value) *ObjectFunction {
 	return nil
 }
 
+func AsList(value Value) *ObjectList {
+	if o, ok := value.Obj.(*ObjectList); ok {
+		return o
+	}
+	return nil
+}
+
+func AsMap(value Value) *ObjectMap {
+	if o, ok := value.Obj.(*ObjectMap); ok {
+		return o
+	}
+	return nil
+}
+
+func AsError(value Value) *ObjectError {
+	if o, ok := value.Obj.(*ObjectError); ok {
+		return o
+	}
+	return nil
+}
+
+func AsModule(value Value) *ObjectModule {
+	if o, ok := value.Obj.(*ObjectModule); ok {
+		return o
+	}
+	return nil
+}
+
 func AsString(value Value) *ObjectString {
 	if o, ok := value.Obj.(*ObjectString); ok {
 		return o
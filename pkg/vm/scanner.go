@@ -1,6 +1,12 @@
 package vm
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/rhomel/golox/pkg/number"
+)
 
 type TokenType int
 
@@ -10,6 +16,9 @@ const (
 	TOKEN_RIGHT_PAREN
 	TOKEN_LEFT_BRACE
 	TOKEN_RIGHT_BRACE
+	TOKEN_LEFT_BRACKET
+	TOKEN_RIGHT_BRACKET
+	TOKEN_COLON
 	TOKEN_COMMA
 	TOKEN_DOT
 	TOKEN_MINUS
@@ -17,6 +26,11 @@ const (
 	TOKEN_SEMICOLON
 	TOKEN_SLASH
 	TOKEN_STAR
+	TOKEN_PERCENT
+	TOKEN_AMPERSAND
+	TOKEN_PIPE
+	TOKEN_CARET
+	TOKEN_QUESTION
 	// One or two character tokens.
 	TOKEN_BANG
 	TOKEN_BANG_EQUAL
@@ -26,25 +40,45 @@ const (
 	TOKEN_GREATER_EQUAL
 	TOKEN_LESS
 	TOKEN_LESS_EQUAL
+	TOKEN_STAR_STAR
+	TOKEN_TILDE
+	TOKEN_TILDE_SLASH
+	TOKEN_LESS_LESS
+	TOKEN_GREATER_GREATER
+	TOKEN_PLUS_EQUAL
+	TOKEN_MINUS_EQUAL
+	TOKEN_STAR_EQUAL
+	TOKEN_SLASH_EQUAL
+	TOKEN_PERCENT_EQUAL
+	TOKEN_PLUS_PLUS
+	TOKEN_MINUS_MINUS
 	// Literals.
 	TOKEN_IDENTIFIER
 	TOKEN_STRING
+	TOKEN_INTERPOLATION // the part of a string before an embedded expression
 	TOKEN_NUMBER
 	// Keywords.
 	TOKEN_AND
+	TOKEN_BREAK
+	TOKEN_CATCH
 	TOKEN_CLASS
+	TOKEN_CONTINUE
 	TOKEN_ELSE
 	TOKEN_FALSE
+	TOKEN_FINALLY
 	TOKEN_FOR
 	TOKEN_FUN
 	TOKEN_IF
+	TOKEN_IMPORT
 	TOKEN_NIL
 	TOKEN_OR
 	TOKEN_PRINT
 	TOKEN_RETURN
 	TOKEN_SUPER
 	TOKEN_THIS
+	TOKEN_THROW
 	TOKEN_TRUE
+	TOKEN_TRY
 	TOKEN_VAR
 	TOKEN_WHILE
 	TOKEN_ERROR
@@ -76,6 +110,12 @@ func (t Token) String() string {
 		typ = "TOKEN_LEFT_BRACE"
 	case TOKEN_RIGHT_BRACE:
 		typ = "TOKEN_RIGHT_BRACE"
+	case TOKEN_LEFT_BRACKET:
+		typ = "TOKEN_LEFT_BRACKET"
+	case TOKEN_RIGHT_BRACKET:
+		typ = "TOKEN_RIGHT_BRACKET"
+	case TOKEN_COLON:
+		typ = "TOKEN_COLON"
 	case TOKEN_COMMA:
 		typ = "TOKEN_COMMA"
 	case TOKEN_DOT:
@@ -90,6 +130,16 @@ func (t Token) String() string {
 		typ = "TOKEN_SLASH"
 	case TOKEN_STAR:
 		typ = "TOKEN_STAR"
+	case TOKEN_PERCENT:
+		typ = "TOKEN_PERCENT"
+	case TOKEN_AMPERSAND:
+		typ = "TOKEN_AMPERSAND"
+	case TOKEN_PIPE:
+		typ = "TOKEN_PIPE"
+	case TOKEN_CARET:
+		typ = "TOKEN_CARET"
+	case TOKEN_QUESTION:
+		typ = "TOKEN_QUESTION"
 	case TOKEN_BANG:
 		typ = "TOKEN_BANG"
 	case TOKEN_BANG_EQUAL:
@@ -106,26 +156,62 @@ func (t Token) String() string {
 		typ = "TOKEN_LESS"
 	case TOKEN_LESS_EQUAL:
 		typ = "TOKEN_LESS_EQUAL"
+	case TOKEN_STAR_STAR:
+		typ = "TOKEN_STAR_STAR"
+	case TOKEN_TILDE:
+		typ = "TOKEN_TILDE"
+	case TOKEN_TILDE_SLASH:
+		typ = "TOKEN_TILDE_SLASH"
+	case TOKEN_LESS_LESS:
+		typ = "TOKEN_LESS_LESS"
+	case TOKEN_GREATER_GREATER:
+		typ = "TOKEN_GREATER_GREATER"
+	case TOKEN_PLUS_EQUAL:
+		typ = "TOKEN_PLUS_EQUAL"
+	case TOKEN_MINUS_EQUAL:
+		typ = "TOKEN_MINUS_EQUAL"
+	case TOKEN_STAR_EQUAL:
+		typ = "TOKEN_STAR_EQUAL"
+	case TOKEN_SLASH_EQUAL:
+		typ = "TOKEN_SLASH_EQUAL"
+	case TOKEN_PERCENT_EQUAL:
+		typ = "TOKEN_PERCENT_EQUAL"
+	case TOKEN_PLUS_PLUS:
+		typ = "TOKEN_PLUS_PLUS"
+	case TOKEN_MINUS_MINUS:
+		typ = "TOKEN_MINUS_MINUS"
 	case TOKEN_IDENTIFIER:
 		typ = "TOKEN_IDENTIFIER"
 	case TOKEN_STRING:
 		typ = "TOKEN_STRING"
+	case TOKEN_INTERPOLATION:
+		typ = "TOKEN_INTERPOLATION"
 	case TOKEN_NUMBER:
 		typ = "TOKEN_NUMBER"
 	case TOKEN_AND:
 		typ = "TOKEN_AND"
+	case TOKEN_BREAK:
+		typ = "TOKEN_BREAK"
+	case TOKEN_CATCH:
+		typ = "TOKEN_CATCH"
 	case TOKEN_CLASS:
 		typ = "TOKEN_CLASS"
+	case TOKEN_CONTINUE:
+		typ = "TOKEN_CONTINUE"
 	case TOKEN_ELSE:
 		typ = "TOKEN_ELSE"
 	case TOKEN_FALSE:
 		typ = "TOKEN_FALSE"
+	case TOKEN_FINALLY:
+		typ = "TOKEN_FINALLY"
 	case TOKEN_FOR:
 		typ = "TOKEN_FOR"
 	case TOKEN_FUN:
 		typ = "TOKEN_FUN"
 	case TOKEN_IF:
 		typ = "TOKEN_IF"
+	case TOKEN_IMPORT:
+		typ = "TOKEN_IMPORT"
 	case TOKEN_NIL:
 		typ = "TOKEN_NIL"
 	case TOKEN_OR:
@@ -138,8 +224,12 @@ func (t Token) String() string {
 		typ = "TOKEN_SUPER"
 	case TOKEN_THIS:
 		typ = "TOKEN_THIS"
+	case TOKEN_THROW:
+		typ = "TOKEN_THROW"
 	case TOKEN_TRUE:
 		typ = "TOKEN_TRUE"
+	case TOKEN_TRY:
+		typ = "TOKEN_TRY"
 	case TOKEN_VAR:
 		typ = "TOKEN_VAR"
 	case TOKEN_WHILE:
@@ -160,6 +250,10 @@ type Scanner struct {
 	line    int
 
 	source []rune
+
+	// interpolations holds the number of unclosed braces in each embedded
+	// expression being scanned, innermost last. It is not part of the book.
+	interpolations []int
 }
 
 func InitScanner(source string) *Scanner {
@@ -174,7 +268,7 @@ func InitScanner(source string) *Scanner {
 
 func (s *Scanner) isAlpha(c rune) bool {
 	return (c >= 'a' && c <= 'z') ||
-		(c >= 'A' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
 		c == '_'
 }
 
@@ -183,8 +277,11 @@ func (s *Scanner) isDigit(c rune) bool {
 }
 
 func (s *Scanner) ScanToken() Token {
-	s.skipWhitespace()
+	message := s.skipWhitespace()
 	s.start = s.current
+	if message != "" {
+		return s.errorToken(message)
+	}
 
 	if s.isAtEnd() {
 		return s.makeToken(TOKEN_EOF)
@@ -204,23 +301,76 @@ func (s *Scanner) ScanToken() Token {
 	case ')':
 		return s.makeToken(TOKEN_RIGHT_PAREN)
 	case '{':
+		if n := len(s.interpolations); n > 0 {
+			s.interpolations[n-1]++
+		}
 		return s.makeToken(TOKEN_LEFT_BRACE)
 	case '}':
+		if n := len(s.interpolations); n > 0 {
+			if s.interpolations[n-1] == 0 {
+				// the end of an embedded expression continues the string
+				s.interpolations = s.interpolations[:n-1]
+				return s.stringToken()
+			}
+			s.interpolations[n-1]--
+		}
 		return s.makeToken(TOKEN_RIGHT_BRACE)
+	case '[':
+		return s.makeToken(TOKEN_LEFT_BRACKET)
+	case ']':
+		return s.makeToken(TOKEN_RIGHT_BRACKET)
 	case ';':
 		return s.makeToken(TOKEN_SEMICOLON)
+	case ':':
+		return s.makeToken(TOKEN_COLON)
+	case '?':
+		return s.makeToken(TOKEN_QUESTION)
 	case ',':
 		return s.makeToken(TOKEN_COMMA)
 	case '.':
 		return s.makeToken(TOKEN_DOT)
 	case '-':
+		if s.match('-') {
+			return s.makeToken(TOKEN_MINUS_MINUS)
+		} else if s.match('=') {
+			return s.makeToken(TOKEN_MINUS_EQUAL)
+		}
 		return s.makeToken(TOKEN_MINUS)
 	case '+':
+		if s.match('+') {
+			return s.makeToken(TOKEN_PLUS_PLUS)
+		} else if s.match('=') {
+			return s.makeToken(TOKEN_PLUS_EQUAL)
+		}
 		return s.makeToken(TOKEN_PLUS)
 	case '/':
+		if s.match('=') {
+			return s.makeToken(TOKEN_SLASH_EQUAL)
+		}
 		return s.makeToken(TOKEN_SLASH)
 	case '*':
+		if s.match('*') {
+			return s.makeToken(TOKEN_STAR_STAR)
+		} else if s.match('=') {
+			return s.makeToken(TOKEN_STAR_EQUAL)
+		}
 		return s.makeToken(TOKEN_STAR)
+	case '%':
+		if s.match('=') {
+			return s.makeToken(TOKEN_PERCENT_EQUAL)
+		}
+		return s.makeToken(TOKEN_PERCENT)
+	case '&':
+		return s.makeToken(TOKEN_AMPERSAND)
+	case '|':
+		return s.makeToken(TOKEN_PIPE)
+	case '^':
+		return s.makeToken(TOKEN_CARET)
+	case '~':
+		if s.match('/') {
+			return s.makeToken(TOKEN_TILDE_SLASH)
+		}
+		return s.makeToken(TOKEN_TILDE)
 	case '!':
 		if s.match('=') {
 			return s.makeToken(TOKEN_BANG_EQUAL)
@@ -236,17 +386,23 @@ func (s *Scanner) ScanToken() Token {
 	case '<':
 		if s.match('=') {
 			return s.makeToken(TOKEN_LESS_EQUAL)
+		} else if s.match('<') {
+			return s.makeToken(TOKEN_LESS_LESS)
 		} else {
 			return s.makeToken(TOKEN_LESS)
 		}
 	case '>':
 		if s.match('=') {
 			return s.makeToken(TOKEN_GREATER_EQUAL)
+		} else if s.match('>') {
+			return s.makeToken(TOKEN_GREATER_GREATER)
 		} else {
 			return s.makeToken(TOKEN_GREATER)
 		}
 	case '"':
 		return s.stringToken()
+	case '`':
+		return s.rawStringToken()
 	}
 
 	return s.errorToken("Unexpected character.")
@@ -304,7 +460,9 @@ func (s *Scanner) errorToken(message string) Token {
 	}
 }
 
-func (s *Scanner) skipWhitespace() {
+// skipWhitespace skips whitespace and comments. It returns an error message if
+// a block comment is unterminated.
+func (s *Scanner) skipWhitespace() string {
 	for {
 		c := s.peek()
 		switch c {
@@ -326,16 +484,43 @@ func (s *Scanner) skipWhitespace() {
 				for s.peek() != '\n' && !s.isAtEnd() {
 					s.advance()
 				}
+			} else if s.peekNext() == '*' {
+				if !s.blockComment() {
+					return "Unterminated block comment."
+				}
 			} else {
-				return
+				return ""
 			}
 			break
 		default:
-			return
+			return ""
 		}
 	}
 }
 
+// blockComment skips a comment between "/*" and "*/", which can nest. It
+// returns false if the source ends before the comment does. Block comments are
+// not part of the book.
+func (s *Scanner) blockComment() bool {
+	s.advance()
+	s.advance()
+	depth := 1
+	for depth > 0 {
+		if s.isAtEnd() {
+			return false
+		}
+		switch c := s.advance(); {
+		case c == '\n':
+			s.line++
+		case c == '/' && s.match('*'):
+			depth++
+		case c == '*' && s.match('/'):
+			depth--
+		}
+	}
+	return true
+}
+
 func (s *Scanner) checkKeyword(start int, length int, rest string, Type TokenType) TokenType {
 	rrest := []rune(rest)
 	i := s.start + start
@@ -347,7 +532,8 @@ func (s *Scanner) checkKeyword(start int, length int, rest string, Type TokenTyp
 		i++
 		j++
 	}
-	if j != length {
+	if j != length || s.current-s.start != start+length {
+		// a keyword followed by more letters is an identifier
 		return TOKEN_IDENTIFIER
 	}
 	return Type
@@ -357,8 +543,19 @@ func (s *Scanner) identifierType() TokenType {
 	switch s.source[s.start] {
 	case 'a':
 		return s.checkKeyword(1, 2, "nd", TOKEN_AND)
+	case 'b':
+		return s.checkKeyword(1, 4, "reak", TOKEN_BREAK)
 	case 'c':
-		return s.checkKeyword(1, 4, "lass", TOKEN_CLASS)
+		if s.current-s.start > 1 {
+			switch s.source[s.start+1] {
+			case 'a':
+				return s.checkKeyword(2, 3, "tch", TOKEN_CATCH)
+			case 'l':
+				return s.checkKeyword(2, 3, "ass", TOKEN_CLASS)
+			case 'o':
+				return s.checkKeyword(2, 6, "ntinue", TOKEN_CONTINUE)
+			}
+		}
 	case 'e':
 		return s.checkKeyword(1, 3, "lse", TOKEN_ELSE)
 	case 'f':
@@ -366,6 +563,8 @@ func (s *Scanner) identifierType() TokenType {
 			switch s.source[s.start+1] {
 			case 'a':
 				return s.checkKeyword(2, 3, "lse", TOKEN_FALSE)
+			case 'i':
+				return s.checkKeyword(2, 5, "nally", TOKEN_FINALLY)
 			case 'o':
 				return s.checkKeyword(2, 1, "r", TOKEN_FOR)
 			case 'u':
@@ -373,7 +572,14 @@ func (s *Scanner) identifierType() TokenType {
 			}
 		}
 	case 'i':
-		return s.checkKeyword(1, 1, "f", TOKEN_IF)
+		if s.current-s.start > 1 {
+			switch s.source[s.start+1] {
+			case 'f':
+				return s.checkKeyword(2, 0, "", TOKEN_IF)
+			case 'm':
+				return s.checkKeyword(2, 4, "port", TOKEN_IMPORT)
+			}
+		}
 	case 'n':
 		return s.checkKeyword(1, 2, "il", TOKEN_NIL)
 	case 'o':
@@ -388,9 +594,23 @@ func (s *Scanner) identifierType() TokenType {
 		if s.current-s.start > 1 {
 			switch s.source[s.start+1] {
 			case 'h':
-				return s.checkKeyword(2, 2, "is", TOKEN_THIS)
+				if s.current-s.start > 2 {
+					switch s.source[s.start+2] {
+					case 'i':
+						return s.checkKeyword(3, 1, "s", TOKEN_THIS)
+					case 'r':
+						return s.checkKeyword(3, 2, "ow", TOKEN_THROW)
+					}
+				}
 			case 'r':
-				return s.checkKeyword(2, 2, "ue", TOKEN_TRUE)
+				if s.current-s.start > 2 {
+					switch s.source[s.start+2] {
+					case 'u':
+						return s.checkKeyword(3, 1, "e", TOKEN_TRUE)
+					case 'y':
+						return s.checkKeyword(3, 0, "", TOKEN_TRY)
+					}
+				}
 			}
 		}
 	case 'v':
@@ -408,21 +628,78 @@ func (s *Scanner) identifier() Token {
 	return s.makeToken(s.identifierType())
 }
 
+// number scans a number literal like the tree-walk scanner, which allows
+// other bases, exponents and "_" between digits. It is an error token if
+// package number can't parse it.
 func (s *Scanner) number() Token {
-	for s.isDigit(s.peek()) {
-		s.advance()
-	}
-	if s.peek() == '.' && s.isDigit(s.peekNext()) {
-		s.advance() // consume '.'
-		for s.isDigit(s.peek()) {
+	if s.source[s.start] == '0' && number.Base(s.peek()) != 10 {
+		s.advance() // the base letter
+		for s.isAlpha(s.peek()) || s.isDigit(s.peek()) {
 			s.advance()
 		}
+	} else {
+		s.digits()
+		if s.peek() == '.' && s.isDigit(s.peekNext()) {
+			s.advance() // consume '.'
+			s.digits()
+		}
+		if s.exponent() {
+			s.advance() // consume 'e'
+			if s.peek() == '+' || s.peek() == '-' {
+				s.advance()
+			}
+			s.digits()
+		}
+	}
+	if _, message, ok := number.Parse(string(s.source[s.start:s.current])); !ok {
+		return s.errorToken(message)
 	}
 	return s.makeToken(TOKEN_NUMBER)
 }
 
+func (s *Scanner) digits() {
+	for s.isDigit(s.peek()) || s.peek() == '_' {
+		s.advance()
+	}
+}
+
+// exponent reports whether an exponent like "e9" or "E-3" is next.
+func (s *Scanner) exponent() bool {
+	if s.peek() != 'e' && s.peek() != 'E' {
+		return false
+	}
+	next := s.current + 1
+	if next < len(s.source) && (s.source[next] == '+' || s.source[next] == '-') {
+		next++
+	}
+	return next < len(s.source) && s.isDigit(s.source[next])
+}
+
+// stringToken scans a string like the tree-walk scanner: the part before each
+// embedded expression is a TOKEN_INTERPOLATION and the rest of the string
+// after the last one is a TOKEN_STRING starting with "}". The escape sequences
+// are checked here and decoded by the compiler; a part with an invalid one is
+// an error token for the first.
 func (s *Scanner) stringToken() Token {
+	var invalid *Token
 	for s.peek() != '"' && !s.isAtEnd() {
+		if s.peek() == '$' && s.peekNext() == '{' {
+			s.advance()
+			s.advance()
+			s.interpolations = append(s.interpolations, 0)
+			if invalid != nil {
+				return *invalid
+			}
+			return s.makeToken(TOKEN_INTERPOLATION)
+		}
+		if s.peek() == '\\' && s.peekNext() != 0 {
+			s.advance()
+			if message, ok := s.escape(); !ok && invalid == nil {
+				token := s.errorToken(message)
+				invalid = &token
+			}
+			continue
+		}
 		if s.peek() == '\n' {
 			s.line++
 		}
@@ -433,5 +710,69 @@ func (s *Scanner) stringToken() Token {
 		return s.errorToken("Unterminated string.")
 	}
 	s.advance() // closing quote
+	if invalid != nil {
+		return *invalid
+	}
+	return s.makeToken(TOKEN_STRING)
+}
+
+// escape scans the escape sequence after a backslash. It returns the error
+// message if the sequence is invalid.
+func (s *Scanner) escape() (string, bool) {
+	c := s.advance()
+	switch c {
+	case 'n', 'r', 't', '0', '"', '\\', '$':
+		return "", true
+	case 'u':
+		return s.unicodeEscape()
+	}
+	if c == '\n' {
+		s.line++
+	}
+	// show a newline or tab after the backslash the way it would be escaped
+	readable := strconv.Quote(string(c))
+	return fmt.Sprintf("Invalid escape sequence '\\%s'.", readable[1:len(readable)-1]), false
+}
+
+// unicodeEscape scans the code point of a "\u{...}" escape sequence, which has
+// one to six hexadecimal digits.
+func (s *Scanner) unicodeEscape() (string, bool) {
+	const message = "Invalid Unicode escape sequence."
+	if !s.match('{') {
+		return message, false
+	}
+	start := s.current
+	for isHexDigit(s.peek()) {
+		s.advance()
+	}
+	digits := string(s.source[start:s.current])
+	if !s.match('}') || len(digits) == 0 || len(digits) > 6 {
+		return message, false
+	}
+	code, _ := strconv.ParseInt(digits, 16, 32)
+	if !utf8.ValidRune(rune(code)) {
+		return message, false
+	}
+	return "", true
+}
+
+// rawStringToken scans a string between backticks, which can span lines and
+// has no escape sequences or embedded expressions. It is not part of the book.
+func (s *Scanner) rawStringToken() Token {
+	for s.peek() != '`' && !s.isAtEnd() {
+		if s.peek() == '\n' {
+			s.line++
+		}
+		s.advance()
+	}
+
+	if s.isAtEnd() {
+		return s.errorToken("Unterminated string.")
+	}
+	s.advance() // closing backtick
 	return s.makeToken(TOKEN_STRING)
 }
+
+func isHexDigit(c rune) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
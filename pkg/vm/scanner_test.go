@@ -7,6 +7,7 @@ func Test_checkKeyword(t *testing.T) {
 		t.Helper()
 		scanner := InitScanner(source)
 		scanner.start = start
+		scanner.current = start + 3
 		token := scanner.checkKeyword(1, 2, "nd", TOKEN_AND)
 		if want, got := TOKEN_AND, token; want != got {
 			t.Errorf("want token %v, got: %v", want, got)
@@ -23,6 +24,10 @@ func Test_identifierType(t *testing.T) {
 		t.Helper()
 		scanner := InitScanner(source)
 		scanner.start = start
+		scanner.current = start
+		for scanner.current < len(source) && source[scanner.current] != ' ' {
+			scanner.current++
+		}
 		received := scanner.identifierType()
 		if want, got := token, received; want != got {
 			t.Errorf("want token %v, got: %v", want, got)
@@ -32,4 +37,151 @@ func Test_identifierType(t *testing.T) {
 	matchKeyword("and", 0, TOKEN_AND)
 	matchKeyword("and class while if else", 4, TOKEN_CLASS)
 	matchKeyword("and class while if else", 10, TOKEN_WHILE)
+	matchKeyword("break continue", 0, TOKEN_BREAK)
+	matchKeyword("break continue", 6, TOKEN_CONTINUE)
+	matchKeyword("c co cont", 0, TOKEN_IDENTIFIER)
+	matchKeyword("c co cont", 2, TOKEN_IDENTIFIER)
+	matchKeyword("c co cont", 5, TOKEN_IDENTIFIER)
+	matchKeyword("try catch finally throw", 0, TOKEN_TRY)
+	matchKeyword("try catch finally throw", 4, TOKEN_CATCH)
+	matchKeyword("try catch finally throw", 10, TOKEN_FINALLY)
+	matchKeyword("try catch finally throw", 18, TOKEN_THROW)
+	matchKeyword("if import", 0, TOKEN_IF)
+	matchKeyword("if import", 3, TOKEN_IMPORT)
+	matchKeyword("this true", 0, TOKEN_THIS)
+	matchKeyword("this true", 5, TOKEN_TRUE)
+	// a keyword followed by more letters is an identifier
+	matchKeyword("classy tryst", 0, TOKEN_IDENTIFIER)
+	matchKeyword("classy tryst", 7, TOKEN_IDENTIFIER)
+}
+
+// scannedToken is the type and lexeme of a token that scanAll expects.
+type scannedToken struct {
+	typ    TokenType
+	lexeme string
+}
+
+// scanAll scans source and checks that it yields the tokens in want.
+func scanAll(t *testing.T, source string, want []scannedToken) {
+	t.Helper()
+	scanner := InitScanner(source)
+	for _, w := range want {
+		token := scanner.ScanToken()
+		lexeme := string(scanner.source[token.Start : token.Start+token.Length])
+		if token.Type != w.typ || lexeme != w.lexeme {
+			t.Errorf("want %v %q, got %v %q", w.typ, w.lexeme, token.Type, lexeme)
+		}
+	}
+}
+
+func Test_interpolation(t *testing.T) {
+	scanAll(t, `"a${b + "c${d}"}e" {}`, []scannedToken{
+		{TOKEN_INTERPOLATION, `"a${`},
+		{TOKEN_IDENTIFIER, "b"},
+		{TOKEN_PLUS, "+"},
+		{TOKEN_INTERPOLATION, `"c${`},
+		{TOKEN_IDENTIFIER, "d"},
+		{TOKEN_STRING, `}"`},
+		{TOKEN_STRING, `}e"`},
+		{TOKEN_LEFT_BRACE, "{"},
+		{TOKEN_RIGHT_BRACE, "}"},
+		{TOKEN_EOF, ""},
+	})
+}
+
+func Test_escapes(t *testing.T) {
+	source := "\"a\\\"b\" \"\\q\" \"\\u{41\" `raw\\q\n` \"ok\""
+	scanner := InitScanner(source)
+	want := []struct {
+		typ    TokenType
+		lexeme string
+		err    string
+		line   int
+	}{
+		{TOKEN_STRING, `"a\"b"`, "", 1},
+		{TOKEN_ERROR, "", `Invalid escape sequence '\q'.`, 1},
+		{TOKEN_ERROR, "", "Invalid Unicode escape sequence.", 1},
+		{TOKEN_STRING, "`raw\\q\n`", "", 2},
+		{TOKEN_STRING, `"ok"`, "", 2},
+		{TOKEN_EOF, "", "", 2},
+	}
+	for _, w := range want {
+		token := scanner.ScanToken()
+		lexeme := string(scanner.source[token.Start : token.Start+token.Length])
+		if token.Type != w.typ || lexeme != w.lexeme || token.Error != w.err || token.Line != w.line {
+			t.Errorf("want %v %q %q line %d, got %v %q %q line %d", w.typ, w.lexeme, w.err, w.line, token.Type, lexeme, token.Error, token.Line)
+		}
+	}
+}
+
+func Test_unescape(t *testing.T) {
+	tests := map[string]string{
+		`plain`:              "plain",
+		`\n\r\t\0`:           "\n\r\t\x00",
+		`\"\\\$`:             `"\$`,
+		`caf\u{e9}\u{1F600}`: "café😀",
+	}
+	for escaped, want := range tests {
+		if got := unescape([]rune(escaped)); got != want {
+			t.Errorf("unescape(%q) = %q, want %q", escaped, got, want)
+		}
+	}
+}
+
+func Test_numbers(t *testing.T) {
+	scanAll(t, "0x1F 1_000 2.5e-3 1e x 0b12 ** * ~/ ~ << <= >> % & | ^", []scannedToken{
+		{TOKEN_NUMBER, "0x1F"},
+		{TOKEN_NUMBER, "1_000"},
+		{TOKEN_NUMBER, "2.5e-3"},
+		// "e" without digits isn't an exponent
+		{TOKEN_NUMBER, "1"},
+		{TOKEN_IDENTIFIER, "e"},
+		{TOKEN_IDENTIFIER, "x"},
+		{TOKEN_ERROR, ""},
+		{TOKEN_STAR_STAR, "**"},
+		{TOKEN_STAR, "*"},
+		{TOKEN_TILDE_SLASH, "~/"},
+		{TOKEN_TILDE, "~"},
+		{TOKEN_LESS_LESS, "<<"},
+		{TOKEN_LESS_EQUAL, "<="},
+		{TOKEN_GREATER_GREATER, ">>"},
+		{TOKEN_PERCENT, "%"},
+		{TOKEN_AMPERSAND, "&"},
+		{TOKEN_PIPE, "|"},
+		{TOKEN_CARET, "^"},
+		{TOKEN_EOF, ""},
+	})
+}
+
+func Test_compoundOperators(t *testing.T) {
+	scanAll(t, "a+++=b---=c*=**/=%=//", []scannedToken{
+		{TOKEN_IDENTIFIER, "a"},
+		{TOKEN_PLUS_PLUS, "++"},
+		{TOKEN_PLUS_EQUAL, "+="},
+		{TOKEN_IDENTIFIER, "b"},
+		{TOKEN_MINUS_MINUS, "--"},
+		{TOKEN_MINUS_EQUAL, "-="},
+		{TOKEN_IDENTIFIER, "c"},
+		{TOKEN_STAR_EQUAL, "*="},
+		{TOKEN_STAR_STAR, "**"},
+		{TOKEN_SLASH_EQUAL, "/="},
+		{TOKEN_PERCENT_EQUAL, "%="},
+		// a comment, not "/="
+		{TOKEN_EOF, ""},
+	})
+}
+
+func Test_blockComments(t *testing.T) {
+	scanner := InitScanner("/* a /* nested */\ncomment */ 1 /* unterminated /* */")
+	token := scanner.ScanToken()
+	if token.Type != TOKEN_NUMBER || token.Line != 2 {
+		t.Errorf("want number on line 2, got %v on line %d", token.Type, token.Line)
+	}
+	token = scanner.ScanToken()
+	if token.Type != TOKEN_ERROR || token.Error != "Unterminated block comment." {
+		t.Errorf("want unterminated comment error, got %v %q", token.Type, token.Error)
+	}
+	if token = scanner.ScanToken(); token.Type != TOKEN_EOF {
+		t.Errorf("want EOF, got %v", token.Type)
+	}
 }
@@ -0,0 +1,155 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Like clox the vm, compiler and parser are package globals. A Session owns
+// its own VM and swaps it into the globals for the duration of each call so
+// several sessions can exist in one process. Calls are serialized across all
+// sessions.
+var sessionMu sync.Mutex
+
+type Session struct {
+	vm       *VM
+	stdout   io.Writer
+	reporter ErrorReporter
+}
+
+// NewSession returns a VM with its own globals that prints to stdout and
+// reports errors to reporter.
+func NewSession(stdout io.Writer, reporter ErrorReporter) *Session {
+	s := &Session{stdout: stdout, reporter: reporter}
+	s.with(func() {
+		InitVM()
+		s.vm = vm
+	})
+	return s
+}
+
+func (s *Session) with(fn func()) {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	savedVM, savedStdout, savedReporter := vm, stdout, reporter
+	defer func() {
+		vm, stdout, reporter = savedVM, savedStdout, savedReporter
+	}()
+	vm, stdout, reporter = s.vm, s.stdout, s.reporter
+	fn()
+}
+
+// Interpret compiles and runs source.
+func (s *Session) Interpret(source string) (result InterpretResult) {
+	return s.InterpretFile("", source)
+}
+
+// InterpretFile compiles and runs source read from file. Modules it imports
+// are found relative to file, or the current directory if file is empty.
+func (s *Session) InterpretFile(file, source string) (result InterpretResult) {
+	s.with(func() {
+		vm.File = file
+		result = interpret(source)
+	})
+	return
+}
+
+// SetModulePath sets the directories imports look in when a module isn't
+// found next to the importing file.
+func (s *Session) SetModulePath(dirs []string) {
+	s.with(func() {
+		vm.ModulePath = dirs
+	})
+}
+
+// Evaluate compiles and runs a single expression and returns its value.
+func (s *Session) Evaluate(expression string) (value interface{}, result InterpretResult) {
+	s.with(func() {
+		function := compileExpression(expression)
+		if function == nil {
+			result = INTERPRET_COMPILE_ERROR
+			return
+		}
+		result = interpretFunction(function)
+		if result == INTERPRET_OK {
+			value = toGo(peek(0))
+		}
+		resetStack()
+	})
+	return
+}
+
+// GetGlobal returns the value of a global variable.
+func (s *Session) GetGlobal(name string) (value interface{}, ok bool) {
+	s.with(func() {
+		var v Value
+		if ok = vm.Globals.Get(copyString(name), &v); ok {
+			value = toGo(v)
+		}
+	})
+	return
+}
+
+// SetGlobal defines or redefines a global variable. value must be nil, a
+// bool, a float64, a string or a Value.
+func (s *Session) SetGlobal(name string, value interface{}) (err error) {
+	s.with(func() {
+		var v Value
+		if v, err = fromGo(value); err == nil {
+			vm.Globals.Set(copyString(name), v)
+		}
+	})
+	return
+}
+
+// toGo converts strings and primitive values to their Go equivalent and lists
+// to []interface{}. Other values are returned as a Value.
+func toGo(value Value) interface{} {
+	return toGoWithLists(value, make(map[*ObjectList][]interface{}))
+}
+
+// toGoWithLists is toGo for a value that may be in one of the lists being
+// converted, so a list that contains itself converts to a slice that contains
+// itself.
+func toGoWithLists(value Value, lists map[*ObjectList][]interface{}) interface{} {
+	if isObjType(value, ObjList) {
+		list := AsList(value)
+		if converted, ok := lists[list]; ok {
+			return converted
+		}
+		converted := make([]interface{}, len(list.elements))
+		lists[list] = converted
+		for i, element := range list.elements {
+			converted[i] = toGoWithLists(element, lists)
+		}
+		return converted
+	}
+	switch {
+	case value.IsNil():
+		return nil
+	case value.IsBool():
+		return value.AsBool()
+	case value.IsNumber():
+		return value.AsNumber()
+	case IsString(value):
+		return AsGoString(value)
+	}
+	return value
+}
+
+func fromGo(value interface{}) (Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return NilValue(), nil
+	case bool:
+		return BooleanValue(v), nil
+	case float64:
+		return NumberValue(v), nil
+	case string:
+		return ObjVal(copyString(v)), nil
+	case Value:
+		return v, nil
+	}
+	return Value{}, fmt.Errorf("unsupported value type %T", value)
+}
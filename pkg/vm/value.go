@@ -17,6 +17,10 @@ type ObjType int
 const (
 	ObjString ObjType = iota
 	ObjFunction
+	ObjList
+	ObjMap
+	ObjError
+	ObjModule
 )
 
 type Value struct {
@@ -93,9 +97,9 @@ func ValuesEqual(a, b Value) bool {
 	case ValNumber:
 		return a.AsNumber() == b.AsNumber()
 	case ValObj:
-		aString := AsString(a)
-		bString := AsString(b)
-		return aString == bString
+		// strings are interned so equal strings are the same object, and
+		// lists are only equal to themselves
+		return a.Obj == b.Obj
 	default:
 		return false // unreachable
 	}
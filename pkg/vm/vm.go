@@ -2,12 +2,46 @@ package vm
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rhomel/golox/pkg/number"
 )
 
 var vm *VM
-var DebugTraceExecution bool = true
+
+// interrupted is set by Interrupt and accessed atomically
+var interrupted int32
+
+// stdout is where print statements write to
+var stdout io.Writer = os.Stdout
+
+// reporter receives compile and runtime errors
+var reporter ErrorReporter = stderrReporter{}
+
+// ErrorReporter receives the errors found while compiling and running a
+// program. where is " at end", " at 'lexeme'" or empty for scanner errors.
+type ErrorReporter interface {
+	CompileError(line int, where, message string)
+	RuntimeError(line int, message string)
+}
+
+// stderrReporter prints errors in the same format as clox.
+type stderrReporter struct{}
+
+func (stderrReporter) CompileError(line int, where, message string) {
+	fmt.Fprintf(os.Stderr, "[line %d] Error%s: %s\n", line, where, message)
+}
+
+func (stderrReporter) RuntimeError(line int, message string) {
+	fmt.Fprintf(os.Stderr, "%s\n[line %d] in script\n", message, line)
+}
+
+var DebugTraceExecution bool = false
 
 const UINT8_COUNT = math.MaxUint8 + 1
 const FRAMES_MAX = 64
@@ -29,20 +63,88 @@ type VM struct {
 	Strings  *Table
 	Globals  *Table
 	Objects  Obj
+
+	Handlers   []Handler // the try statements being executed, innermost last
+	thrown     Value     // the value being thrown
+	thrownLine int       // the line the value was thrown on
+
+	File       string                   // the file of the program or module being run
+	ModulePath []string                 // where imports are found if not next to the importing file
+	Modules    map[string]*ObjectModule // the modules imported so far by file
+	loading    []string                 // the files of the modules importing the one being run
+}
+
+// Handler is a try statement being executed. It is not part of the book.
+type Handler struct {
+	Ip       int // where to continue when a value is thrown
+	StackTop int // the stack height to unwind to
 }
 
 func resetStack() {
 	vm.StackTop = 0 // this line does not match the book's example (24.3.3)
 	vm.FrameCount = 0
+	vm.Handlers = vm.Handlers[:0]
 }
 
+// runtimeError throws an error object with the message. Unlike the book it
+// doesn't report the error because a try statement may catch it; run reports
+// it if nothing does.
 func runtimeError(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, format, args...)
 	// 24.3.3: different from the book because of pointer math
-	frame := vm.Frames[vm.FrameCount-1]
+	frame := &vm.Frames[vm.FrameCount-1]
 	instruction := frame.Ip - 1
 	line := frame.Function.chunk.Lines[instruction]
-	fmt.Fprintf(os.Stderr, "[line %d] in script\n", line)
+	throw(ObjVal(newError(fmt.Sprintf(format, args...), line)), line)
+}
+
+// throw records the value being thrown. The instruction that throws it then
+// returns INTERPRET_RUNTIME_ERROR to unwind the stack.
+func throw(value Value, line int) {
+	vm.thrown = value
+	vm.thrownLine = line
+}
+
+// catch unwinds the stack to the innermost try statement and continues at its
+// handler with the thrown value and its line on top of the stack. It returns
+// false if there is no try statement or the program was interrupted, since an
+// interrupt can't be caught.
+func catch() bool {
+	if len(vm.Handlers) == 0 || atomic.LoadInt32(&interrupted) != 0 {
+		return false
+	}
+	handler := vm.Handlers[len(vm.Handlers)-1]
+	vm.Handlers = vm.Handlers[:len(vm.Handlers)-1]
+	vm.StackTop = handler.StackTop
+	push(vm.thrown)
+	push(NumberValue(float64(vm.thrownLine)))
+	vm.Frames[vm.FrameCount-1].Ip = handler.Ip
+	return true
+}
+
+// thrownMessage is the message an uncaught value is reported with: the
+// message of an error object or else the value as print would show it.
+func thrownMessage(value Value) string {
+	if err := AsError(value); err != nil {
+		return err.message.String
+	}
+	return formatValue(value)
+}
+
+// formatValue returns value formatted the way print shows it.
+func formatValue(value Value) string {
+	var b strings.Builder
+	saved := stdout
+	stdout = &b
+	printValue(value)
+	stdout = saved
+	return b.String()
+}
+
+// Interrupt stops the running program with an "Interrupted." runtime error the
+// next time it jumps back to the start of a loop. It is safe to call from
+// another goroutine.
+func Interrupt() {
+	atomic.StoreInt32(&interrupted, 1)
 }
 
 func push(value Value) {
@@ -63,6 +165,109 @@ func isFalsey(value Value) bool {
 	return value.IsNil() || (value.IsBool() && !value.AsBool())
 }
 
+// getIndex replaces the object and index on top of the stack with
+// object[index]. It reports a runtime error if the object can't be indexed.
+func getIndex() bool {
+	object, index := peek(1), peek(0)
+	var value Value
+	switch {
+	case isObjType(object, ObjList):
+		list := AsList(object)
+		i, ok := listIndex(list, index)
+		if !ok {
+			return false
+		}
+		value = list.elements[i]
+	case isObjType(object, ObjMap):
+		if !checkKey(index) {
+			return false
+		}
+		var ok bool
+		if value, ok = AsMap(object).get(index); !ok {
+			runtimeError("Key %s is not in the map.", formatKey(index))
+			return false
+		}
+	default:
+		runtimeError("Only lists and maps can be indexed.")
+		return false
+	}
+	pop()
+	pop()
+	push(value)
+	return true
+}
+
+// setIndex sets object[index] to the value on top of the stack and replaces
+// the object, index and value with the value.
+func setIndex() bool {
+	object, index, value := peek(2), peek(1), peek(0)
+	switch {
+	case isObjType(object, ObjList):
+		list := AsList(object)
+		i, ok := listIndex(list, index)
+		if !ok {
+			return false
+		}
+		list.elements[i] = value
+	case isObjType(object, ObjMap):
+		if !checkKey(index) {
+			return false
+		}
+		AsMap(object).set(index, value)
+	default:
+		runtimeError("Only lists and maps can be indexed.")
+		return false
+	}
+	pop()
+	pop()
+	pop()
+	push(value)
+	return true
+}
+
+// listIndex checks that index is a position in list. It reports a runtime
+// error if not.
+func listIndex(list *ObjectList, index Value) (int, bool) {
+	n := index.AsNumber()
+	if !index.IsNumber() || n != math.Trunc(n) {
+		runtimeError("List index must be an integer.")
+		return 0, false
+	}
+	if n < 0 || n >= float64(len(list.elements)) {
+		runtimeError("List index %s is out of bounds for a list of length %d.", strconv.FormatFloat(n, 'f', -1, 64), len(list.elements))
+		return 0, false
+	}
+	return int(n), true
+}
+
+// checkKey checks that key can be a map key. It reports a runtime error if
+// not.
+func checkKey(key Value) bool {
+	switch {
+	case key.IsNumber() && math.IsNaN(key.AsNumber()):
+		// NaN is not == to anything so it could never be found again
+		runtimeError("NaN can't be a map key.")
+		return false
+	case key.IsNil(), key.IsBool(), key.IsNumber(), IsString(key):
+		return true
+	}
+	runtimeError("Map keys must be nil, booleans, numbers or strings.")
+	return false
+}
+
+// formatKey formats a map key for an error message.
+func formatKey(key Value) string {
+	switch {
+	case IsString(key):
+		return "\"" + AsGoString(key) + "\""
+	case key.IsNumber():
+		return strconv.FormatFloat(key.AsNumber(), 'f', -1, 64)
+	case key.IsBool():
+		return strconv.FormatBool(key.AsBool())
+	}
+	return "nil"
+}
+
 func concatenate() {
 	b := AsString(pop())
 	a := AsString(pop())
@@ -73,6 +278,7 @@ func InitVM() {
 	vm = &VM{
 		Globals: &Table{},
 		Strings: &Table{},
+		Modules: make(map[string]*ObjectModule),
 	}
 	vm.Globals.initTable()
 	vm.Strings.initTable()
@@ -97,7 +303,13 @@ func interpret(source string) InterpretResult {
 	if function == nil {
 		return INTERPRET_COMPILE_ERROR
 	}
+	return interpretFunction(function)
+}
 
+func interpretFunction(function *ObjectFunction) InterpretResult {
+	// a previous run may have returned with values left on the stack
+	resetStack()
+	atomic.StoreInt32(&interrupted, 0)
 	push(ObjVal(function))
 	frame := CallFrame{
 		Function: function,
@@ -110,8 +322,32 @@ func interpret(source string) InterpretResult {
 	return run()
 }
 
+// run runs the current frame, catching the values thrown in try statements.
+// An uncaught value is reported as a runtime error.
 func run() InterpretResult {
-	frame := vm.Frames[vm.FrameCount-1]
+	result := runCatching()
+	if result == INTERPRET_RUNTIME_ERROR {
+		reporter.RuntimeError(vm.thrownLine, thrownMessage(vm.thrown))
+		resetStack()
+	}
+	return result
+}
+
+// runCatching runs the current frame, continuing at the handler of the
+// innermost try statement when a value is thrown. It returns
+// INTERPRET_RUNTIME_ERROR if nothing catches the value.
+func runCatching() InterpretResult {
+	for {
+		result := execute()
+		if result != INTERPRET_RUNTIME_ERROR || !catch() {
+			return result
+		}
+	}
+}
+
+// execute runs the current frame until it returns or throws a value.
+func execute() InterpretResult {
+	frame := &vm.Frames[vm.FrameCount-1]
 	READ_BYTE := func() uint8 {
 		instruction := frame.Function.chunk.Code[frame.Ip]
 		frame.Ip++
@@ -141,6 +377,23 @@ func run() InterpretResult {
 		a := pop().AsNumber()
 		return a, b, INTERPRET_OK
 	}
+	// INTEGER_OP is BINARY_OP for the bitwise operators, whose operands must
+	// be integers. It is not part of the book.
+	INTEGER_OP := func() (int64, int64, InterpretResult) {
+		if !peek(0).IsNumber() || !peek(1).IsNumber() {
+			runtimeError("Operands must be numbers.")
+			return 0, 0, INTERPRET_RUNTIME_ERROR
+		}
+		a, aOk := number.Integer(peek(1).AsNumber())
+		b, bOk := number.Integer(peek(0).AsNumber())
+		if !aOk || !bOk {
+			runtimeError("Operands must be integers.")
+			return 0, 0, INTERPRET_RUNTIME_ERROR
+		}
+		pop()
+		pop()
+		return a, b, INTERPRET_OK
+	}
 
 	for {
 		if DebugTraceExecution {
@@ -156,6 +409,12 @@ func run() InterpretResult {
 			// separate and therefore our frame ip is also for the function chunk?
 			frame.Function.chunk.DisassembleInstruction(frame.Ip)
 		}
+		// no instruction grows the stack by more than one value so checking
+		// once per instruction keeps push in bounds
+		if vm.StackTop >= STACK_MAX {
+			runtimeError("Stack overflow.")
+			return INTERPRET_RUNTIME_ERROR
+		}
 		var instruction uint8 = READ_BYTE()
 		switch instruction {
 		case OP_CONSTANT:
@@ -169,6 +428,18 @@ func run() InterpretResult {
 			push(BooleanValue(false))
 		case OP_POP:
 			pop()
+		case OP_PICK:
+			// push a copy of the value the operand slots below the top
+			distance := READ_BYTE()
+			push(peek(int(distance)))
+		case OP_TUCK:
+			// insert a copy of the top value below the operand values under it
+			depth := int(READ_BYTE())
+			top := peek(0)
+			start := vm.StackTop - 1 - depth
+			copy(vm.Stack[start+1:vm.StackTop+1], vm.Stack[start:vm.StackTop])
+			vm.Stack[start] = top
+			vm.StackTop++
 		case OP_GET_LOCAL:
 			slot := READ_BYTE()
 			push(frame.Slots[slot])
@@ -204,12 +475,24 @@ func run() InterpretResult {
 				return i
 			}
 			push(BooleanValue(a > b))
+		case OP_GREATER_EQUAL:
+			a, b, i := BINARY_OP()
+			if i != INTERPRET_OK {
+				return i
+			}
+			push(BooleanValue(a >= b))
 		case OP_LESS:
 			a, b, i := BINARY_OP()
 			if i != INTERPRET_OK {
 				return i
 			}
 			push(BooleanValue(a < b))
+		case OP_LESS_EQUAL:
+			a, b, i := BINARY_OP()
+			if i != INTERPRET_OK {
+				return i
+			}
+			push(BooleanValue(a <= b))
 		case OP_ADD:
 			if IsString(peek(0)) && IsString(peek(1)) {
 				concatenate()
@@ -241,6 +524,56 @@ func run() InterpretResult {
 				return i
 			}
 			push(NumberValue(a / b))
+		case OP_MODULO:
+			a, b, i := BINARY_OP()
+			if i != INTERPRET_OK {
+				return i
+			}
+			push(NumberValue(math.Mod(a, b)))
+		case OP_POWER:
+			a, b, i := BINARY_OP()
+			if i != INTERPRET_OK {
+				return i
+			}
+			push(NumberValue(math.Pow(a, b)))
+		case OP_INT_DIVIDE:
+			a, b, i := BINARY_OP()
+			if i != INTERPRET_OK {
+				return i
+			}
+			push(NumberValue(math.Trunc(a / b)))
+		case OP_BIT_AND:
+			a, b, i := INTEGER_OP()
+			if i != INTERPRET_OK {
+				return i
+			}
+			push(NumberValue(float64(a & b)))
+		case OP_BIT_OR:
+			a, b, i := INTEGER_OP()
+			if i != INTERPRET_OK {
+				return i
+			}
+			push(NumberValue(float64(a | b)))
+		case OP_BIT_XOR:
+			a, b, i := INTEGER_OP()
+			if i != INTERPRET_OK {
+				return i
+			}
+			push(NumberValue(float64(a ^ b)))
+		case OP_SHIFT_LEFT, OP_SHIFT_RIGHT:
+			a, b, i := INTEGER_OP()
+			if i != INTERPRET_OK {
+				return i
+			}
+			if b < 0 {
+				runtimeError("Shift count can't be negative.")
+				return INTERPRET_RUNTIME_ERROR
+			}
+			if instruction == OP_SHIFT_LEFT {
+				push(NumberValue(float64(a << b)))
+			} else {
+				push(NumberValue(float64(a >> b)))
+			}
 		case OP_NOT:
 			push(BooleanValue(isFalsey(pop())))
 		case OP_NEGATE:
@@ -249,9 +582,17 @@ func run() InterpretResult {
 				return INTERPRET_RUNTIME_ERROR
 			}
 			push(NumberValue(-pop().AsNumber()))
+		case OP_BIT_NOT:
+			a, ok := number.Integer(peek(0).AsNumber())
+			if !peek(0).IsNumber() || !ok {
+				runtimeError("Operand must be an integer.")
+				return INTERPRET_RUNTIME_ERROR
+			}
+			pop()
+			push(NumberValue(float64(^a)))
 		case OP_PRINT:
 			printValue(pop())
-			fmt.Println()
+			fmt.Fprintln(stdout)
 		case OP_JUMP:
 			var offset uint16 = READ_SHORT()
 			frame.Ip += int(offset)
@@ -262,7 +603,91 @@ func run() InterpretResult {
 			}
 		case OP_LOOP:
 			var offset uint16 = READ_SHORT()
+			// check before jumping so the error is reported on the loop's line
+			if atomic.LoadInt32(&interrupted) != 0 {
+				runtimeError("Interrupted.")
+				return INTERPRET_RUNTIME_ERROR
+			}
 			frame.Ip = frame.Ip - int(offset)
+		case OP_BUILD_LIST:
+			count := int(READ_BYTE())
+			elements := make([]Value, count)
+			copy(elements, vm.Stack[vm.StackTop-count:vm.StackTop])
+			vm.StackTop -= count
+			push(ObjVal(newList(elements)))
+		case OP_BUILD_MAP:
+			count := int(READ_BYTE())
+			entries := vm.Stack[vm.StackTop-2*count : vm.StackTop]
+			m := newMap()
+			for i := 0; i < len(entries); i += 2 {
+				if !checkKey(entries[i]) {
+					return INTERPRET_RUNTIME_ERROR
+				}
+				m.set(entries[i], entries[i+1])
+			}
+			vm.StackTop -= 2 * count
+			push(ObjVal(m))
+		case OP_GET_INDEX:
+			if !getIndex() {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_SET_INDEX:
+			if !setIndex() {
+				return INTERPRET_RUNTIME_ERROR
+			}
+		case OP_GET_PROPERTY:
+			name := READ_STRING()
+			var value Value
+			var ok bool
+			switch {
+			case isObjType(peek(0), ObjError):
+				value, ok = AsError(peek(0)).property(name.String)
+			case isObjType(peek(0), ObjModule):
+				ok = AsModule(peek(0)).globals.Get(name, &value)
+			default:
+				runtimeError("Only instances have properties.")
+				return INTERPRET_RUNTIME_ERROR
+			}
+			if !ok {
+				runtimeError("Undefined property '%s'.", name.String)
+				return INTERPRET_RUNTIME_ERROR
+			}
+			pop()
+			push(value)
+		case OP_THROW:
+			throw(pop(), frame.Function.chunk.Lines[frame.Ip-1])
+			return INTERPRET_RUNTIME_ERROR
+		case OP_TRY:
+			depth := int(READ_BYTE())
+			var offset uint16 = READ_SHORT()
+			vm.Handlers = append(vm.Handlers, Handler{frame.Ip + int(offset), frame.SlotsStart + depth})
+		case OP_END_TRY:
+			vm.Handlers = vm.Handlers[:len(vm.Handlers)-1]
+		case OP_END_FINALLY:
+			// the finally clause was entered with a value and a kind on the
+			// stack, see Parser.tryStatement
+			kind := pop()
+			value := pop()
+			switch {
+			case kind.IsNil():
+				// the try statement completed normally
+			case kind.AsNumber() > 0:
+				// rethrow the value, kind is the line it was thrown on
+				throw(value, int(kind.AsNumber()))
+				return INTERPRET_RUNTIME_ERROR
+			default:
+				// exit -kind-1: skip the jump for normal completion and the
+				// jumps of the exits before it
+				frame.Ip += 3 * int(-kind.AsNumber())
+			}
+		case OP_TO_STRING:
+			push(ObjVal(takeString(formatValue(pop()))))
+		case OP_IMPORT:
+			module, ok := importModule(READ_STRING())
+			if !ok {
+				return INTERPRET_RUNTIME_ERROR
+			}
+			push(ObjVal(module))
 		case OP_RETURN:
 			return INTERPRET_OK
 		default:
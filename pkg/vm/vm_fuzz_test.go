@@ -0,0 +1,22 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rhomel/golox/pkg/util/fuzz"
+)
+
+func FuzzInterpret(f *testing.F) {
+	fuzz.AddSamples(f, "../../samples")
+	// deeply nested operands used to push past the end of the value stack
+	f.Add("{var a = 1; print " + strings.Repeat("a+(", STACK_MAX) + "a" + strings.Repeat(")", STACK_MAX) + ";}")
+	f.Add("while (true) {}")
+	f.Fuzz(func(t *testing.T, source string) {
+		fuzz.Silence(t)
+		DebugTraceExecution = false
+		InitVM()
+		defer FreeVM()
+		fuzz.RunBounded(t, func() { interpret(source) }, Interrupt)
+	})
+}